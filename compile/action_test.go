@@ -0,0 +1,244 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/parse"
+	"github.com/sdcio/yang-parser/schema"
+	"github.com/sdcio/yang-parser/testutils"
+)
+
+type ActionChecker struct {
+	Input  NodeChecker
+	Output NodeChecker
+}
+
+func (expected ActionChecker) check(t *testing.T, actual schema.Action) {
+	expected.Input.check(t, actual.Input())
+	expected.Output.check(t, actual.Output())
+}
+
+func getActionSchemaNode(
+	t *testing.T,
+	schema_text *bytes.Buffer,
+	path []string,
+	name string,
+) schema.Action {
+	st, err := testutils.GetConfigSchema(schema_text.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error when parsing action schema: %s", err)
+	}
+
+	parent := st.Descendant(path)
+	if parent == nil {
+		t.Fatalf("Unable to find action parent %v", path)
+	}
+	if actual := parent.Actions()[name]; actual != nil {
+		return actual
+	}
+
+	t.Errorf("Unable to find action %s under %v", name, path)
+	return nil
+}
+
+func TestActionInContainer(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`container server {
+			leaf name {
+				type string;
+			}
+			action reset {
+				input {
+					leaf delay {
+						type uint32;
+					}
+				}
+				output {
+					leaf result {
+						type string;
+					}
+				}
+			}
+		}`))
+
+	expected := ActionChecker{
+		Input: NewTreeChecker("Input", []NodeChecker{
+			NewLeafChecker("delay", CheckType("uint32")),
+		}),
+		Output: NewTreeChecker("Output", []NodeChecker{
+			NewLeafChecker("result", CheckType("string")),
+		}),
+	}
+
+	if actual := getActionSchemaNode(t, schema_text,
+		[]string{"server"}, "reset"); actual != nil {
+		expected.check(t, actual)
+	}
+}
+
+func TestActionInListWithImplicitOutput(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`list interface {
+			key name;
+			leaf name {
+				type string;
+			}
+			action clear-counters {
+				input {
+					leaf all {
+						type empty;
+					}
+				}
+			}
+		}`))
+
+	expected := ActionChecker{
+		Input: NewTreeChecker("Input", []NodeChecker{
+			NewLeafChecker("all", CheckType("empty")),
+		}),
+		Output: NewTreeChecker("Output", []NodeChecker{}),
+	}
+
+	if actual := getActionSchemaNode(t, schema_text,
+		[]string{"interface"}, "clear-counters"); actual != nil {
+		expected.check(t, actual)
+	}
+}
+
+func TestActionFromGroupingAndAugment(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`grouping resettable {
+			action reset;
+		}
+		container server {
+			uses resettable;
+		}
+		augment /server/reset/input {
+			leaf delay {
+				type uint32;
+			}
+		}`))
+
+	expected := ActionChecker{
+		Input: NewTreeChecker("Input", []NodeChecker{
+			NewLeafChecker("delay", CheckType("uint32")),
+		}),
+		Output: NewTreeChecker("Output", []NodeChecker{}),
+	}
+
+	if actual := getActionSchemaNode(t, schema_text,
+		[]string{"server"}, "reset"); actual != nil {
+		expected.check(t, actual)
+	}
+}
+
+func TestActionDisabledByFeature(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`feature resets;
+		container server {
+			action reset {
+				if-feature resets;
+			}
+		}`))
+
+	st, err := testutils.GetConfigSchema(schema_text.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error when parsing action schema: %s", err)
+	}
+	if _, ok := st.Child("server").Actions()["reset"]; ok {
+		t.Errorf("Action with disabled feature should not be compiled")
+	}
+}
+
+func TestActionNotAllowedInRpc(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`rpc ping {
+			input {
+				container options {
+					action reset;
+				}
+			}
+		}`))
+
+	_, err := testutils.GetConfigSchema(schema_text.Bytes())
+	assertErrorContains(t, err, "action not allowed within rpc ping")
+}
+
+func TestActionNotAllowedInNotification(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`notification event {
+			container data {
+				action reset;
+			}
+		}`))
+
+	_, err := testutils.GetConfigSchema(schema_text.Bytes())
+	assertErrorContains(t, err, "action not allowed within notification event")
+}
+
+// The parser requires every list to have a key, so the key statement is
+// removed from the parse tree before compiling.
+func compileWithoutListKey(t *testing.T, schema_snippet, list string) error {
+	tree, err := parse.Parse("schema", fmt.Sprintf(
+		SchemaTemplate, schema_snippet), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error when parsing schema: %s", err)
+	}
+
+	var removeKey func(n parse.Node)
+	removeKey = func(n parse.Node) {
+		for _, ch := range n.Children() {
+			if ch.Type() == parse.NodeList && ch.Name() == list {
+				ch.ReplaceChild(ch.ChildByType(parse.NodeKey))
+			}
+			removeKey(ch)
+		}
+	}
+	removeKey(tree.Root)
+
+	_, err = compile.CompileParseTrees(nil,
+		map[string]*parse.Tree{tree.Root.Name(): tree},
+		nil, false, compile.IsConfig)
+	return err
+}
+
+func TestActionNotAllowedInKeylessList(t *testing.T) {
+	err := compileWithoutListKey(t,
+		`container state {
+			config false;
+			list entries {
+				key name;
+				leaf name {
+					type string;
+				}
+				container stats {
+					action clear;
+				}
+			}
+		}`, "entries")
+	assertErrorContains(t, err,
+		"action not allowed within list entries without a key")
+}
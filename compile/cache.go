@@ -0,0 +1,222 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sdcio/yang-parser/schema"
+)
+
+// featureSettings is met by the checkers in this package, which can list
+// every feature they know of.
+type featureSettings interface {
+	settings() map[string]bool
+}
+
+func (f *featuresMap) settings() map[string]bool {
+	return f.features
+}
+
+// Later checkers override earlier ones, as in Status()
+func (f *checkers) settings() map[string]bool {
+	all := make(map[string]bool)
+	for _, chkr := range f.checkers {
+		if chkr == nil {
+			continue
+		}
+		s, ok := chkr.(featureSettings)
+		if !ok {
+			return nil
+		}
+		for feature, enabled := range s.settings() {
+			all[feature] = enabled
+		}
+	}
+	return all
+}
+
+func hashString(h hash.Hash, s string) {
+	h.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	h.Write([]byte(s))
+}
+
+// Decorations added by the compiler are told apart from those added by
+// extensions by the first byte of their data.
+const (
+	decorationKey byte = iota
+	decorationExtension
+)
+
+type modelSetCodec struct {
+	extensions schema.DecorationCodec
+}
+
+// ModelSetCodec returns the schema.DecorationCodec to encode and decode
+// model sets compiled with the given extensions. The compiler decorates
+// list keys itself, and extensions that decorate the schema must also
+// implement schema.DecorationCodec.
+func ModelSetCodec(extensions Extensions) schema.DecorationCodec {
+	codec, _ := extensions.(schema.DecorationCodec)
+	return &modelSetCodec{extensions: codec}
+}
+
+func (c *modelSetCodec) Undecorate(
+	v interface{},
+) (interface{}, []byte, error) {
+	if k, ok := v.(*key); ok {
+		return k.Leaf, []byte{decorationKey}, nil
+	}
+	if c.extensions == nil {
+		return nil, nil, fmt.Errorf("cannot encode %T without a DecorationCodec", v)
+	}
+	inner, data, err := c.extensions.Undecorate(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return inner, append([]byte{decorationExtension}, data...), nil
+}
+
+func (c *modelSetCodec) Decorate(
+	inner interface{},
+	data []byte,
+) (interface{}, error) {
+	switch {
+	case len(data) == 0:
+	case data[0] == decorationKey:
+		if leaf, ok := inner.(schema.Leaf); ok {
+			return &key{leaf}, nil
+		}
+	case data[0] == decorationExtension && c.extensions != nil:
+		return c.extensions.Decorate(inner, data[1:])
+	}
+	return nil, fmt.Errorf("cannot decorate %T", inner)
+}
+
+// CacheKey returns the name under which CompileDirCached stores the model
+// set compiled for cfg: a hash of the files found through cfg, the
+// features enabled, and the format of the cache. Neither the cfg Filter
+// nor the extensions are covered, so a cache directory should only be used
+// with one filter and one set of extensions.
+//
+// Features must be given by the checkers of this package, as a checker can
+// otherwise not list the features it enables.
+func CacheKey(cfg *Config) (string, error) {
+	h := sha256.New()
+	hashString(h, fmt.Sprintf("v%d", schema.ModelSetFormatVersion))
+
+	mods, err := cfg.repository().Modules()
+	if err != nil {
+		return "", err
+	}
+	for _, mod := range mods {
+		text, err := mod.Text()
+		if err != nil {
+			return "", err
+		}
+		hashString(h, mod.File)
+		hashString(h, string(text))
+	}
+
+	s, ok := cfg.features().(featureSettings)
+	var settings map[string]bool
+	if ok {
+		settings = s.settings()
+	}
+	if settings == nil {
+		return "", fmt.Errorf("cannot list features of %T", cfg.Features)
+	}
+	features := make([]string, 0, len(settings))
+	for feature := range settings {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	for _, feature := range features {
+		hashString(h, fmt.Sprintf("%s=%t", feature, settings[feature]))
+	}
+	hashString(h, fmt.Sprintf("skip-unknown=%t", cfg.SkipUnknown))
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CompileDirCached is CompileDir, keeping the compiled model set in
+// cacheDir to load next time instead of compiling again. The cache file is
+// named by CacheKey(), so changing the modules or features compiles them
+// again. A cache file that cannot be loaded, for instance written by a
+// different version, is replaced. The cache is only an optimisation, so
+// the compiled model set is returned even if it cannot be written.
+//
+// See ModelSetCodec for extensions that decorate the schema.
+func CompileDirCached(
+	extensions Extensions,
+	cfg *Config,
+	cacheDir string,
+) (schema.ModelSet, error) {
+
+	key, err := CacheKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	codec := ModelSetCodec(extensions)
+
+	file := filepath.Join(cacheDir, key)
+	if f, err := os.Open(file); err == nil {
+		ms, err := schema.DecodeModelSet(f, codec)
+		f.Close()
+		if err == nil {
+			return ms, nil
+		}
+	}
+
+	ms, err := CompileDir(extensions, cfg)
+	if err != nil {
+		return nil, err
+	}
+	writeModelSetCache(cacheDir, file, ms, codec)
+	return ms, nil
+}
+
+// Write to a temporary file first, so that another compiler never loads a
+// partly written cache.
+func writeModelSetCache(
+	dir, file string,
+	ms schema.ModelSet,
+	codec schema.DecorationCodec,
+) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = schema.EncodeModelSet(tmp, ms, codec)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,603 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/parse"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+const cachedModule = `
+module cached {
+	namespace "urn:cached";
+	prefix cached;
+
+	feature extra;
+
+	identity base-id;
+	identity derived-id {
+		base base-id;
+	}
+
+	typedef percent {
+		type uint8 {
+			range "0..100";
+		}
+		default 50;
+	}
+
+	container top {
+		description "The top";
+		leaf name {
+			type string {
+				length "1..16";
+				pattern "[a-z]+" {
+					error-message "lower case only";
+				}
+				pattern "x.*" {
+					modifier invert-match;
+				}
+			}
+		}
+		leaf ratio {
+			type decimal64 {
+				fraction-digits 2;
+				range "-1.5..1.5";
+			}
+		}
+		leaf level {
+			type percent;
+		}
+		leaf colour {
+			type enumeration {
+				enum red;
+				enum green {
+					value 7;
+				}
+			}
+		}
+		leaf flags {
+			type bits {
+				bit up;
+				bit down {
+					position 4;
+				}
+			}
+		}
+		leaf kind {
+			type identityref {
+				base base-id;
+			}
+		}
+		leaf either {
+			type union {
+				type int16;
+				type boolean;
+			}
+		}
+		leaf extra {
+			if-feature extra;
+			type empty;
+		}
+		list entry {
+			key "id";
+			unique "value";
+			min-elements 1;
+			max-elements 8;
+			ordered-by user;
+			leaf id {
+				type uint32;
+			}
+			leaf value {
+				type string;
+			}
+		}
+		leaf ref {
+			type leafref {
+				path "../entry/id";
+			}
+			must "../entry[id = current()]/value != 'none'" {
+				error-message "bad ref";
+			}
+		}
+		leaf-list tags {
+			type string;
+			default "a";
+			default "b";
+		}
+		choice pick {
+			default one;
+			case one {
+				leaf first {
+					type instance-identifier;
+				}
+			}
+			leaf second {
+				when "../name = 'two'";
+				type string;
+			}
+		}
+		anydata blob;
+	}
+	rpc reset {
+		input {
+			leaf force {
+				type boolean;
+			}
+		}
+		output {
+			leaf done {
+				type string;
+			}
+		}
+	}
+	notification changed {
+		leaf what {
+			type string;
+		}
+	}
+}`
+
+func cachedConfig() *compile.Config {
+	return &compile.Config{
+		Repository: compile.MapRepository(map[string][]byte{
+			"cached.yang": []byte(cachedModule),
+		}),
+		Features: compile.FeaturesFromNames(true, "cached:extra"),
+	}
+}
+
+// dumper writes out every value reachable from a model set, including
+// unexported fields, so that decoded model sets may be compared with the
+// originals. A pointer seen before is written as a reference, as the
+// model set has cycles.
+type dumper struct {
+	buf  bytes.Buffer
+	seen map[uintptr]int
+}
+
+func dumpModelSet(ms schema.ModelSet) string {
+	d := &dumper{seen: make(map[uintptr]int)}
+	d.dump(reflect.ValueOf(ms), 0)
+	return d.buf.String()
+}
+
+func (d *dumper) printf(depth int, format string, args ...interface{}) {
+	d.buf.WriteString(strings.Repeat(" ", depth))
+	fmt.Fprintf(&d.buf, format, args...)
+	d.buf.WriteString("\n")
+}
+
+func (d *dumper) dump(v reflect.Value, depth int) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			d.printf(depth, "nil")
+			return
+		}
+		d.dump(v.Elem(), depth)
+	case reflect.Ptr:
+		if v.IsNil() {
+			d.printf(depth, "nil")
+			return
+		}
+		if id, ok := d.seen[v.Pointer()]; ok {
+			d.printf(depth, "ref %d", id)
+			return
+		}
+		d.seen[v.Pointer()] = len(d.seen) + 1
+		d.printf(depth, "%s", v.Type())
+		d.dump(v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			d.printf(depth, "%s:", v.Type().Field(i).Name)
+			d.dump(v.Field(i), depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		d.printf(depth, "%s len %d", v.Type(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			d.dump(v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		d.printf(depth, "%s len %d", v.Type(), v.Len())
+		for _, k := range keys {
+			d.printf(depth+1, "%s:", k)
+			d.dump(v.MapIndex(k), depth+2)
+		}
+	case reflect.Func:
+		d.printf(depth, "func %t", v.IsNil())
+	default:
+		d.printf(depth, "%v", v)
+	}
+}
+
+func roundTrip(
+	t *testing.T,
+	ms schema.ModelSet,
+	codec schema.DecorationCodec,
+) schema.ModelSet {
+	var buf bytes.Buffer
+	if err := schema.EncodeModelSet(&buf, ms, codec); err != nil {
+		t.Fatalf("Unexpected encoding error: %s", err)
+	}
+	decoded, err := schema.DecodeModelSet(&buf, codec)
+	if err != nil {
+		t.Fatalf("Unexpected decoding error: %s", err)
+	}
+	return decoded
+}
+
+func TestModelSetEncoding(t *testing.T) {
+	ms, err := compile.CompileDir(nil, cachedConfig())
+	if err != nil {
+		t.Fatalf("Unexpected compilation error: %s", err)
+	}
+	decoded := roundTrip(t, ms, compile.ModelSetCodec(nil))
+
+	if expected, actual := dumpModelSet(ms), dumpModelSet(decoded); expected != actual {
+		t.Errorf("Decoded model set differs\nExpected:\n%s\nActual:\n%s",
+			expected, actual)
+	}
+
+	name := decoded.Child("top").Child("name").Type()
+	if err := name.Validate(nil, []string{"top", "name"}, "abc"); err != nil {
+		t.Errorf("Unexpected validation error: %s", err)
+	}
+	if err := name.Validate(nil, []string{"top", "name"}, "xyz"); err == nil {
+		t.Errorf("Unexpected validation success")
+	}
+}
+
+func TestModelSetFormatVersion(t *testing.T) {
+	ms, err := compile.CompileDir(nil, cachedConfig())
+	if err != nil {
+		t.Fatalf("Unexpected compilation error: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := schema.EncodeModelSet(
+		&buf, ms, compile.ModelSetCodec(nil)); err != nil {
+		t.Fatalf("Unexpected encoding error: %s", err)
+	}
+
+	// The version follows the "YANGMS" magic
+	data := buf.Bytes()
+	data[len("YANGMS")]++
+	_, err = schema.DecodeModelSet(
+		bytes.NewReader(data), compile.ModelSetCodec(nil))
+	if !errors.Is(err, schema.ErrModelSetFormat) {
+		t.Fatalf("Expected format version error, got %v", err)
+	}
+	expected := fmt.Sprintf("found %d, expected %d",
+		schema.ModelSetFormatVersion+1, schema.ModelSetFormatVersion)
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error containing %q, got %q", expected, err)
+	}
+}
+
+func TestCompileDirCached(t *testing.T) {
+	dir := t.TempDir()
+	cfg := cachedConfig()
+	key, err := compile.CacheKey(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	ms, err := compile.CompileDirCached(nil, cfg, dir)
+	if err != nil {
+		t.Fatalf("Unexpected compilation error: %s", err)
+	}
+	if ms.Child("top").Child("extra") == nil {
+		t.Errorf("Feature extra not enabled")
+	}
+	if _, err := os.Stat(filepath.Join(dir, key)); err != nil {
+		t.Fatalf("Cache not written: %s", err)
+	}
+
+	// Replace the cache, to see that it is used rather than compiling
+	other, err := compile.CompileDir(nil, &compile.Config{
+		Repository: compile.MapRepository(map[string][]byte{
+			"other.yang": []byte(
+				`module other { namespace "urn:other"; prefix other; }`),
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected compilation error: %s", err)
+	}
+	f, err := os.Create(filepath.Join(dir, key))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = schema.EncodeModelSet(f, other, compile.ModelSetCodec(nil))
+	f.Close()
+	if err != nil {
+		t.Fatalf("Unexpected encoding error: %s", err)
+	}
+	ms, err = compile.CompileDirCached(nil, cfg, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, ok := ms.Modules()["other"]; !ok {
+		t.Errorf("Cached model set not used")
+	}
+
+	// A cache that can't be loaded is replaced
+	if err := os.WriteFile(filepath.Join(dir, key), []byte("junk"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ms, err = compile.CompileDirCached(nil, cfg, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, ok := ms.Modules()["cached"]; !ok {
+		t.Errorf("Model set not compiled again")
+	}
+	if _, err := schema.DecodeModelSet(
+		bytes.NewReader(mustReadFile(t, filepath.Join(dir, key))),
+		compile.ModelSetCodec(nil)); err != nil {
+		t.Errorf("Cache not replaced: %s", err)
+	}
+}
+
+func TestCompileDirCachedUnwritable(t *testing.T) {
+	// A directory can't be created beneath a file
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ms, err := compile.CompileDirCached(nil, cachedConfig(), filepath.Join(file, "cache"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, ok := ms.Modules()["cached"]; !ok {
+		t.Errorf("Model set not compiled")
+	}
+}
+
+func mustReadFile(t *testing.T, file string) []byte {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return data
+}
+
+func TestCacheKey(t *testing.T) {
+	key := func(cfg *compile.Config) string {
+		k, err := compile.CacheKey(cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		return k
+	}
+
+	cfg := cachedConfig()
+	if key(cfg) != key(cachedConfig()) {
+		t.Errorf("Key differs for the same configuration")
+	}
+
+	noFeatures := cachedConfig()
+	noFeatures.Features = nil
+	if key(cfg) == key(noFeatures) {
+		t.Errorf("Key same with different features")
+	}
+
+	changed := cachedConfig()
+	changed.Repository = compile.MapRepository(map[string][]byte{
+		"cached.yang": []byte(cachedModule + "\n"),
+	})
+	if key(cfg) == key(changed) {
+		t.Errorf("Key same with different module text")
+	}
+
+	unknown := cachedConfig()
+	unknown.Features = unlistedFeatures{}
+	if _, err := compile.CacheKey(unknown); err == nil {
+		t.Errorf("Expected error for features that can't be listed")
+	}
+}
+
+type unlistedFeatures struct{}
+
+func (unlistedFeatures) Status(string) compile.FeatureStatus {
+	return compile.ENABLED
+}
+
+// describedContainer decorates containers with their description, to
+// check that decorations survive encoding.
+type describedContainer struct {
+	schema.Container
+	desc string
+}
+
+type describingExtensions struct{}
+
+func (describingExtensions) Undecorate(
+	v interface{},
+) (interface{}, []byte, error) {
+	c, ok := v.(*describedContainer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected %T", v)
+	}
+	return c.Container, []byte(c.desc), nil
+}
+
+func (describingExtensions) Decorate(
+	inner interface{},
+	data []byte,
+) (interface{}, error) {
+	return &describedContainer{inner.(schema.Container), string(data)}, nil
+}
+
+func (describingExtensions) NodeCardinality(
+	parse.NodeType,
+) map[parse.NodeType]parse.Cardinality {
+	return nil
+}
+
+func (describingExtensions) ExtendModelSet(
+	m schema.ModelSet,
+) (schema.ModelSet, error) {
+	return m, nil
+}
+
+func (describingExtensions) ExtendModel(
+	_ parse.Node, m schema.Model, _ schema.Tree,
+) (schema.Model, error) {
+	return m, nil
+}
+
+func (describingExtensions) ExtendRpc(
+	_ parse.Node, r schema.Rpc,
+) (schema.Rpc, error) {
+	return r, nil
+}
+
+func (describingExtensions) ExtendAction(
+	_ parse.Node, a schema.Action,
+) (schema.Action, error) {
+	return a, nil
+}
+
+func (describingExtensions) ExtendNotification(
+	_ parse.Node, n schema.Notification,
+) (schema.Notification, error) {
+	return n, nil
+}
+
+func (describingExtensions) ExtendTree(
+	_ parse.Node, t schema.Tree,
+) (schema.Tree, error) {
+	return t, nil
+}
+
+func (describingExtensions) ExtendContainer(
+	p parse.Node, c schema.Container,
+) (schema.Container, error) {
+	return &describedContainer{c, p.Desc()}, nil
+}
+
+func (describingExtensions) ExtendList(
+	_ parse.Node, l schema.List,
+) (schema.List, error) {
+	return l, nil
+}
+
+func (describingExtensions) ExtendLeaf(
+	_ parse.Node, l schema.Leaf,
+) (schema.Leaf, error) {
+	return l, nil
+}
+
+func (describingExtensions) ExtendLeafList(
+	_ parse.Node, l schema.LeafList,
+) (schema.LeafList, error) {
+	return l, nil
+}
+
+func (describingExtensions) ExtendAnydata(
+	_ parse.Node, a schema.Anydata,
+) (schema.Anydata, error) {
+	return a, nil
+}
+
+func (describingExtensions) ExtendAnyxml(
+	_ parse.Node, a schema.Anyxml,
+) (schema.Anyxml, error) {
+	return a, nil
+}
+
+func (describingExtensions) ExtendChoice(
+	_ parse.Node, c schema.Choice,
+) (schema.Choice, error) {
+	return c, nil
+}
+
+func (describingExtensions) ExtendCase(
+	_ parse.Node, c schema.Case,
+) (schema.Case, error) {
+	return c, nil
+}
+
+func (describingExtensions) ExtendType(
+	_ parse.Node, _ schema.Type, t schema.Type,
+) (schema.Type, error) {
+	return t, nil
+}
+
+func (describingExtensions) ExtendMust(
+	_ parse.Node, _ parse.Node,
+) (string, error) {
+	return "", nil
+}
+
+func (describingExtensions) ExtendOpdCommand(
+	_ parse.Node, c schema.OpdCommand,
+) (schema.OpdCommand, error) {
+	return c, nil
+}
+
+func (describingExtensions) ExtendOpdOption(
+	_ parse.Node, o schema.OpdOption,
+) (schema.OpdOption, error) {
+	return o, nil
+}
+
+func (describingExtensions) ExtendOpdArgument(
+	_ parse.Node, a schema.OpdArgument,
+) (schema.OpdArgument, error) {
+	return a, nil
+}
+
+func TestModelSetEncodingDecorations(t *testing.T) {
+	ext := describingExtensions{}
+	ms, err := compile.CompileDir(ext, cachedConfig())
+	if err != nil {
+		t.Fatalf("Unexpected compilation error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := schema.EncodeModelSet(
+		&buf, ms, compile.ModelSetCodec(nil)); err == nil {
+		t.Errorf("Expected error encoding decorations without a codec")
+	}
+
+	decoded := roundTrip(t, ms, compile.ModelSetCodec(ext))
+	if expected, actual := dumpModelSet(ms), dumpModelSet(decoded); expected != actual {
+		t.Errorf("Decoded model set differs\nExpected:\n%s\nActual:\n%s",
+			expected, actual)
+	}
+	top, ok := decoded.Child("top").(*describedContainer)
+	if !ok {
+		t.Fatalf("Expected decorated container, got %T", decoded.Child("top"))
+	}
+	if top.desc != "The top" {
+		t.Errorf("Unexpected decoration %q", top.desc)
+	}
+}
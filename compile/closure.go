@@ -0,0 +1,236 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/sdcio/yang-parser/parse"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+// A closure collects the modules needed to compile a set of root modules,
+// reading and parsing each file only when it is found to be needed.
+type closure struct {
+	repo           ModuleRepository
+	extCard        parse.NodeCardinality
+	modules        map[string]*parse.Tree
+	files          map[string]*parse.Tree
+	requested      map[string]bool
+	stringInterner *parse.StringInterner
+	argInterner    *parse.ArgInterner
+}
+
+// ParseModuleClosure parses the named modules and the modules and
+// submodules they import or include, directly or indirectly. A name may be
+// given as name@revision to select a revision. With augmenting set, modules
+// that augment or deviate one of the named modules are added too, along
+// with their imports and includes. Finding these means reading every file
+// in the repository, but only those that may match are parsed.
+//
+// An imported module missing from the repository is left for the compiler
+// to report, so that the error shows where it is imported.
+func ParseModuleClosure(
+	extCard parse.NodeCardinality,
+	repo ModuleRepository,
+	augmenting bool,
+	roots ...string,
+) (map[string]*parse.Tree, error) {
+
+	cl := &closure{
+		repo:           repo,
+		extCard:        extCard,
+		modules:        make(map[string]*parse.Tree),
+		files:          make(map[string]*parse.Tree),
+		requested:      make(map[string]bool),
+		stringInterner: parse.NewStringInterner(),
+		argInterner:    parse.NewArgInterner(),
+	}
+
+	rootNames := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		name, rev := root, ""
+		if idx := strings.Index(root, "@"); idx != -1 {
+			name, rev = root[:idx], root[idx+1:]
+		}
+		rootNames[name] = true
+		src, err := repo.Find(name, rev)
+		if err != nil {
+			return nil, err
+		}
+		if err := cl.add(src, name, rev); err != nil {
+			return nil, err
+		}
+	}
+
+	if augmenting {
+		if err := cl.addAugmenting(rootNames); err != nil {
+			return nil, err
+		}
+	}
+	return cl.modules, nil
+}
+
+func (cl *closure) parse(src ModuleSource) (*parse.Tree, error) {
+	return parseModuleSource(src, cl.repo, cl.extCard, cl.stringInterner, cl.argInterner)
+}
+
+// add parses the file found for the named module or submodule, unless
+// already added.
+func (cl *closure) add(src ModuleSource, name, rev string) error {
+	cl.requested[parse.RevisionKey(name, rev)] = true
+
+	if t, ok := cl.files[src.File]; ok {
+		return checkClosureRevision(t, name, rev)
+	}
+	t, err := cl.parse(src)
+	if err != nil {
+		return err
+	}
+	return cl.addTree(src, t, name, rev)
+}
+
+func checkClosureRevision(t *parse.Tree, name, rev string) error {
+	if rev != "" && t.Root.Revision() != rev {
+		return fmt.Errorf("revision %s of module %s not found", rev, name)
+	}
+	return nil
+}
+
+// addTree adds a parsed module or submodule, then what it imports and
+// includes.
+func (cl *closure) addTree(src ModuleSource, t *parse.Tree, name, rev string) error {
+	if err := checkClosureRevision(t, name, rev); err != nil {
+		return err
+	}
+	cl.files[src.File] = t
+	if err := addModuleTree(cl.modules, t); err != nil {
+		return err
+	}
+
+	for _, ch := range t.Root.Children() {
+		switch ch.Type() {
+		case parse.NodeImport, parse.NodeInclude, parse.NodeBelongsTo:
+		default:
+			continue
+		}
+		name, rev := ch.Name(), ch.RevisionDate()
+		if cl.requested[parse.RevisionKey(name, rev)] {
+			continue
+		}
+		src, err := cl.repo.Find(name, rev)
+		if err != nil {
+			cl.requested[parse.RevisionKey(name, rev)] = true
+			continue
+		}
+		if err := cl.add(src, name, rev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cl *closure) addAugmenting(roots map[string]bool) error {
+	sources, err := cl.repo.Modules()
+	if err != nil {
+		return err
+	}
+	for _, src := range sources {
+		if _, ok := cl.files[src.File]; ok {
+			continue
+		}
+		text, err := src.Text()
+		if err != nil {
+			return err
+		}
+		if !mayAugment(text, roots) {
+			continue
+		}
+		t, err := cl.parse(src)
+		if err != nil {
+			return err
+		}
+		if !augmentsOrDeviates(t.Root, roots) {
+			continue
+		}
+		if err := cl.addTree(src, t, t.Root.Name(), ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A quick check of the text, to avoid parsing files that cannot augment
+// or deviate any of the roots.
+func mayAugment(text []byte, roots map[string]bool) bool {
+	if !bytes.Contains(text, []byte("augment")) &&
+		!bytes.Contains(text, []byte("deviation")) {
+		return false
+	}
+	for root := range roots {
+		if bytes.Contains(text, []byte(root)) {
+			return true
+		}
+	}
+	return false
+}
+
+func augmentsOrDeviates(mod parse.Node, roots map[string]bool) bool {
+	imported := make(map[string]string)
+	for _, i := range mod.ChildrenByType(parse.NodeImport) {
+		imported[i.Prefix()] = i.Name()
+	}
+
+	targets := mod.ChildrenByType(parse.NodeAugment)
+	targets = append(targets, mod.ChildrenByType(parse.NodeDeviation)...)
+	for _, target := range targets {
+		if _, ok := target.Argument().(*parse.AbsoluteSchemaArg); !ok {
+			continue
+		}
+		path := target.ArgSchema()
+		if len(path) > 0 && roots[imported[path[0].Space]] {
+			return true
+		}
+	}
+	return false
+}
+
+// CompileModuleClosure compiles the named modules, found through the
+// YangDir, YangLocations and Repository of cfg, together with only the
+// modules they need. See ParseModuleClosure.
+func CompileModuleClosure(
+	extensions Extensions,
+	cfg *Config,
+	roots ...string,
+) (schema.ModelSet, error) {
+
+	var extCard parse.NodeCardinality
+	if extensions != nil {
+		extCard = extensions.NodeCardinality
+	}
+	mods, err := ParseModuleClosure(extCard, cfg.repository(),
+		cfg.AugmentingModules, roots...)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, submodules := parse.GetModulesAndSubmodules(mods)
+	ms, _, err := compileInternal(extensions, modules, submodules,
+		cfg.features(), cfg.SkipUnknown, dontGenWarnings, cfg.Filter,
+		cfg.UserFnCheckFn, cfg.CollectErrors)
+	return ms, err
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+)
+
+const augmentingModule = `
+module augmenting {
+	namespace "urn:augmenting";
+	prefix augmenting;
+	import remote {
+		prefix r;
+	}
+	augment /r:new {
+		leaf added {
+			type string;
+		}
+	}
+}`
+
+func closureRepository() compile.ModuleRepository {
+	return compile.MapRepository(map[string][]byte{
+		"remote@2020-01-01.yang": []byte(remoteRevOld),
+		"remote@2021-06-01.yang": []byte(remoteRevNew),
+		"local.yang": []byte(fmt.Sprintf(
+			localImportTemplate, "", "new")),
+		"augmenting.yang": []byte(augmentingModule),
+		"unrelated.yang":  []byte("module unrelated { this is not yang"),
+	})
+}
+
+func closureModules(
+	t *testing.T,
+	augmenting bool,
+	roots ...string,
+) []string {
+	mods, err := compile.ParseModuleClosure(
+		nil, closureRepository(), augmenting, roots...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	names := make([]string, 0, len(mods))
+	for name, mod := range mods {
+		names = append(names, name+"@"+mod.Root.Revision())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestParseModuleClosure(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		augmenting bool
+		roots      []string
+		expected   []string
+	}{
+		{"imports", false, []string{"local"},
+			[]string{"local@", "remote@2021-06-01"}},
+		{"revision", false, []string{"remote@2020-01-01"},
+			[]string{"remote@2020-01-01"}},
+		{"no augmenting", false, []string{"remote"},
+			[]string{"remote@2021-06-01"}},
+		{"augmenting", true, []string{"remote"},
+			[]string{"augmenting@", "local@", "remote@2021-06-01"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			actual := closureModules(t, test.augmenting, test.roots...)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseModuleClosureMissingRoot(t *testing.T) {
+	_, err := compile.ParseModuleClosure(
+		nil, closureRepository(), false, "missing")
+	assertErrorContains(t, err, "module missing not found")
+
+	_, err = compile.ParseModuleClosure(
+		nil, closureRepository(), false, "remote@2019-01-01")
+	assertErrorContains(t, err,
+		"revision 2019-01-01 of module remote not found")
+}
+
+func TestCompileModuleClosure(t *testing.T) {
+	ms, err := compile.CompileModuleClosure(nil, &compile.Config{
+		Repository:        closureRepository(),
+		Filter:            compile.IsConfig,
+		AugmentingModules: true,
+	}, "remote")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, name := range []string{"remote", "local", "augmenting"} {
+		if _, ok := ms.Modules()[name]; !ok {
+			t.Errorf("Module %s not compiled", name)
+		}
+	}
+	if _, ok := ms.Modules()["unrelated"]; ok {
+		t.Errorf("Unrelated module compiled")
+	}
+	if ms.Child("new").Child("added") == nil {
+		t.Errorf("Augment of remote not applied")
+	}
+}
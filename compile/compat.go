@@ -0,0 +1,719 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file compares two revisions of a set of modules, and classifies
+// the changes using the update rules of RFC 7950 section 11. As the
+// compiled schemas are compared, changes to groupings and typedefs are
+// found where they are used, and a change that does not alter the schema,
+// such as moving nodes into a grouping, is not reported.
+
+package compile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sdcio/yang-parser/parse"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+// A CompatibilityClass says how a change between revisions affects
+// clients of the old revision.
+type CompatibilityClass int
+
+const (
+	// Compatible changes are allowed by RFC 7950 section 11
+	Compatible CompatibilityClass = iota
+
+	// StatusChange marks a definition deprecated or obsolete, which is
+	// allowed, but clients will need to stop using it.
+	StatusChange
+
+	// NonCompatible changes may break clients of the old revision
+	NonCompatible
+)
+
+func (c CompatibilityClass) String() string {
+	switch c {
+	case Compatible:
+		return "compatible"
+	case StatusChange:
+		return "status-change"
+	case NonCompatible:
+		return "non-compatible"
+	}
+	return fmt.Sprintf("CompatibilityClass(%d)", int(c))
+}
+
+// A CompatibilityChange is a difference found between the revisions
+type CompatibilityChange struct {
+	Class CompatibilityClass
+
+	// Schema path of the node changed. As for JSON (RFC 7951), names are
+	// prefixed by their module where it differs from their parent's, and
+	// choices and cases are included. For a module added or removed, the
+	// path is the module name.
+	Path string
+
+	// Where the node is defined, as file:line:col, in the new revision,
+	// or in the old revision if it was removed. Empty if not known.
+	Location string
+
+	Message string
+}
+
+func (c CompatibilityChange) String() string {
+	s := fmt.Sprintf("%s: %s: %s", c.Class, c.Path, c.Message)
+	if c.Location != "" {
+		s = c.Location + ": " + s
+	}
+	return s
+}
+
+// CheckCompatibility compiles the old and new module sets and compares
+// them as CompareCompatibility does, giving the location of each change.
+func CheckCompatibility(
+	extensions Extensions,
+	oldCfg, newCfg *Config,
+) ([]CompatibilityChange, error) {
+	oldMs, err, oldMods, _ := CompileDirKeepMods(extensions, oldCfg)
+	if err != nil {
+		return nil, fmt.Errorf("old revision: %s", err)
+	}
+	newMs, err, newMods, _ := CompileDirKeepMods(extensions, newCfg)
+	if err != nil {
+		return nil, fmt.Errorf("new revision: %s", err)
+	}
+
+	c := &compatChecker{oldMods: oldMods, newMods: newMods}
+	c.modelSets(oldMs, newMs)
+	return c.changes, nil
+}
+
+// CompareCompatibility returns the changes from old to new, in order of
+// schema path. Locations are not known, so are left empty.
+func CompareCompatibility(old, new schema.ModelSet) []CompatibilityChange {
+	c := &compatChecker{}
+	c.modelSets(old, new)
+	return c.changes
+}
+
+type compatChecker struct {
+	changes []CompatibilityChange
+
+	// Expanded parse trees, for locations
+	oldMods, newMods map[string]*parse.Module
+}
+
+func (c *compatChecker) report(
+	class CompatibilityClass,
+	p schema.ComparePath,
+	removed bool,
+	format string,
+	args ...interface{},
+) {
+	mods := c.newMods
+	if removed {
+		mods = c.oldMods
+	}
+	var location string
+	if n := findSchemaStatement(mods, p.Path); n != nil {
+		file, line, col := n.Location()
+		location = fmt.Sprintf("%s:%d:%d", file, line, col)
+	}
+	c.changes = append(c.changes, CompatibilityChange{
+		Class:    class,
+		Path:     p.Path.String(),
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// findSchemaStatement finds the statement defining the node at path in
+// the expanded parse trees, in which groupings have been copied to where
+// they are used and augments to their targets.
+func findSchemaStatement(mods map[string]*parse.Module, path schema.SchemaPath) parse.Node {
+	if len(path) == 0 {
+		return nil
+	}
+	mod, ok := mods[path[0].Module]
+	if !ok {
+		return nil
+	}
+	n := mod.GetModule()
+	for _, e := range path {
+		var found parse.Node
+		for _, ch := range n.Children() {
+			switch t := ch.Type(); {
+			case t.IsDataNode(), t == parse.NodeRpc, t == parse.NodeAction,
+				t == parse.NodeNotification, t == parse.NodeInput,
+				t == parse.NodeOutput:
+				if ch.Name() == e.Name && ch.Type() != parse.NodeUses {
+					found = ch
+				}
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		n = found
+	}
+	return n
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make(map[string]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return sortedKeys(keys)
+}
+
+func (c *compatChecker) modelSets(old, new schema.ModelSet) {
+	for _, name := range unionKeys(old.Modules(), new.Modules()) {
+		om, inOld := old.Modules()[name]
+		nm, inNew := new.Modules()[name]
+		switch {
+		case !inNew:
+			c.changes = append(c.changes, CompatibilityChange{
+				Class: NonCompatible, Path: name, Message: "module removed"})
+			continue
+		case !inOld:
+			c.changes = append(c.changes, CompatibilityChange{
+				Class: Compatible, Path: name, Message: "module added"})
+			continue
+		}
+
+		schema.CompareNodes(c, schema.ComparePath{}, om, nm)
+		schema.CompareRpcs(c, name, om.Rpcs(), nm.Rpcs())
+		schema.CompareNotifications(c, name, om.Notifications(), nm.Notifications())
+	}
+}
+
+func (c *compatChecker) Removed(p schema.ComparePath, kind string, _ schema.Node) {
+	c.report(NonCompatible, p, true, "%s removed", kind)
+}
+
+func (c *compatChecker) Added(p schema.ComparePath, kind string, n schema.Node) {
+	if n != nil && isMandatoryNode(n) {
+		c.report(NonCompatible, p, false, "mandatory %s added", kind)
+		return
+	}
+	c.report(Compatible, p, false, "%s added", kind)
+}
+
+// isMandatoryNode says whether n is a mandatory node, as defined in RFC
+// 7950 section 3, which cannot be added to an existing schema.
+func isMandatoryNode(n schema.Node) bool {
+	switch v := n.(type) {
+	case schema.Leaf, schema.Choice, schema.Anydata, schema.Anyxml:
+		return v.Mandatory()
+	case schema.List:
+		return v.Limit().Min > 0
+	case schema.LeafList:
+		return v.Limit().Min > 0
+	case schema.Container:
+		if v.Presence() {
+			return false
+		}
+		for _, ch := range schema.SchemaChildren(v) {
+			if isMandatoryNode(ch) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func statusName(s schema.Status) string {
+	return strings.ToLower(s.String())
+}
+
+// isListKey says whether n is a key of the list parent.
+func isListKey(parent, n schema.Node) bool {
+	if l, ok := parent.(schema.List); ok {
+		for _, key := range l.Keys() {
+			if key == n.Name() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Compare checks the changes to a node found in both schemas.
+func (c *compatChecker) Compare(p schema.ComparePath, o, n schema.Node) bool {
+	if schema.NodeKind(o) != schema.NodeKind(n) {
+		c.report(NonCompatible, p, false, "changed from %s to %s",
+			schema.NodeKind(o), schema.NodeKind(n))
+		return false
+	}
+
+	// Config and status are inherited, so are only reported where they
+	// change differently from the parent.
+	if !p.Operation && o.Config() != n.Config() && (p.OldParent == nil ||
+		p.OldParent.Config() != o.Config() || p.NewParent.Config() != n.Config()) {
+		switch {
+		case !n.Config():
+			c.report(NonCompatible, p, false, "config changed to false")
+		case isListKey(p.NewParent, n) || isMandatoryNode(n):
+			c.report(NonCompatible, p, false,
+				"config changed to true for mandatory node")
+		default:
+			c.report(Compatible, p, false, "config changed to true")
+		}
+	}
+	if o.Status() != n.Status() && (p.OldParent == nil ||
+		p.OldParent.Status() != o.Status() || p.NewParent.Status() != n.Status()) {
+		class := StatusChange
+		if n.Status() < o.Status() {
+			class = NonCompatible
+		}
+		c.report(class, p, false, "status changed from %s to %s",
+			statusName(o.Status()), statusName(n.Status()))
+	}
+	if o.Description() != n.Description() {
+		c.report(Compatible, p, false, "description changed")
+	}
+	c.conditions(p, "must", mustExprs(o.Musts()), mustExprs(n.Musts()))
+	c.conditions(p, "when", whenExprs(o.Whens()), whenExprs(n.Whens()))
+
+	switch nv := n.(type) {
+	case schema.Container:
+		ov := o.(schema.Container)
+		if ov.Presence() != nv.Presence() {
+			c.report(NonCompatible, p, false, "presence changed to %t",
+				nv.Presence())
+		}
+	case schema.List:
+		ov := o.(schema.List)
+		if ok, nk := strings.Join(ov.Keys(), " "), strings.Join(nv.Keys(), " "); ok != nk {
+			c.report(NonCompatible, p, false,
+				"key changed from %q to %q", ok, nk)
+		}
+		c.uniques(p, ov, nv)
+		c.limits(p, ov.Limit(), nv.Limit())
+		c.orderedBy(p, ov.OrdBy(), nv.OrdBy())
+	case schema.Leaf:
+		ov := o.(schema.Leaf)
+		c.types(p, "", ov.Type(), nv.Type())
+		c.units(p, ov.Units(), nv.Units())
+		od, oHas := ov.Default()
+		nd, nHas := nv.Default()
+		c.defaults(p, od, oHas, nd, nHas)
+		c.mandatory(p, ov.Mandatory(), nv.Mandatory())
+	case schema.LeafList:
+		ov := o.(schema.LeafList)
+		c.types(p, "", ov.Type(), nv.Type())
+		c.units(p, ov.Units(), nv.Units())
+		od, nd := strings.Join(ov.Defaults(), " "), strings.Join(nv.Defaults(), " ")
+		c.defaults(p, od, od != "", nd, nd != "")
+		c.limits(p, ov.Limit(), nv.Limit())
+		c.orderedBy(p, ov.OrdBy(), nv.OrdBy())
+	case schema.Choice:
+		ov := o.(schema.Choice)
+		od, nd := ov.DefaultCase(), nv.DefaultCase()
+		c.defaults(p, od, od != "", nd, nd != "")
+		c.mandatory(p, ov.Mandatory(), nv.Mandatory())
+	case schema.Anydata, schema.Anyxml:
+		c.mandatory(p, o.Mandatory(), n.Mandatory())
+	}
+	return true
+}
+
+func mustExprs(musts []schema.MustContext) map[string]bool {
+	exprs := make(map[string]bool)
+	for _, m := range musts {
+		exprs[m.Mach.GetExpr()] = true
+	}
+	return exprs
+}
+
+func whenExprs(whens []schema.WhenContext) map[string]bool {
+	exprs := make(map[string]bool)
+	for _, w := range whens {
+		exprs[w.Mach.GetExpr()] = true
+	}
+	return exprs
+}
+
+// Removing a condition is allowed, but whether a changed condition is
+// more relaxed is not known, so it is taken as added.
+func (c *compatChecker) conditions(p schema.ComparePath, keyword string, old, new map[string]bool) {
+	for _, expr := range unionKeys(old, new) {
+		switch {
+		case !new[expr]:
+			c.report(Compatible, p, false, "%s %q removed", keyword, expr)
+		case !old[expr]:
+			c.report(NonCompatible, p, false, "%s %q added", keyword, expr)
+		}
+	}
+}
+
+func (c *compatChecker) uniques(p schema.ComparePath, old, new schema.List) {
+	uniqueStrings := func(l schema.List) map[string]bool {
+		uniques := make(map[string]bool)
+		for _, unique := range l.Uniques() {
+			var paths []string
+			for _, path := range unique {
+				var elems []string
+				for _, name := range path {
+					elems = append(elems, name.Local)
+				}
+				paths = append(paths, strings.Join(elems, "/"))
+			}
+			uniques[strings.Join(paths, " ")] = true
+		}
+		return uniques
+	}
+	c.conditions(p, "unique", uniqueStrings(old), uniqueStrings(new))
+}
+
+func (c *compatChecker) limits(p schema.ComparePath, old, new schema.Limit) {
+	switch {
+	case new.Min > old.Min:
+		c.report(NonCompatible, p, false,
+			"min-elements increased from %d to %d", old.Min, new.Min)
+	case new.Min < old.Min:
+		c.report(Compatible, p, false,
+			"min-elements decreased from %d to %d", old.Min, new.Min)
+	}
+	limitString := func(max uint) string {
+		if max == ^uint(0) {
+			return "unbounded"
+		}
+		return fmt.Sprint(max)
+	}
+	switch {
+	case new.Max < old.Max:
+		c.report(NonCompatible, p, false,
+			"max-elements decreased from %s to %s",
+			limitString(old.Max), limitString(new.Max))
+	case new.Max > old.Max:
+		c.report(Compatible, p, false,
+			"max-elements increased from %s to %s",
+			limitString(old.Max), limitString(new.Max))
+	}
+}
+
+func (c *compatChecker) orderedBy(p schema.ComparePath, old, new string) {
+	if old != new {
+		c.report(NonCompatible, p, false,
+			"ordered-by changed from %s to %s", old, new)
+	}
+}
+
+func (c *compatChecker) units(p schema.ComparePath, old, new string) {
+	switch {
+	case old == new:
+	case old == "":
+		c.report(Compatible, p, false, "units %q added", new)
+	default:
+		c.report(NonCompatible, p, false,
+			"units changed from %q to %q", old, new)
+	}
+}
+
+func (c *compatChecker) defaults(p schema.ComparePath, old string, oldHas bool, new string, newHas bool) {
+	switch {
+	case oldHas == newHas && old == new:
+	case !oldHas:
+		c.report(Compatible, p, false, "default %q added", new)
+	case !newHas:
+		c.report(NonCompatible, p, false, "default %q removed", old)
+	default:
+		c.report(NonCompatible, p, false,
+			"default changed from %q to %q", old, new)
+	}
+}
+
+func (c *compatChecker) mandatory(p schema.ComparePath, old, new bool) {
+	switch {
+	case !old && new:
+		c.report(NonCompatible, p, false, "mandatory changed to true")
+	case old && !new:
+		c.report(Compatible, p, false, "mandatory changed to false")
+	}
+}
+
+// compatInterval is a range of values. Integer ranges are exact, as are
+// decimal64 ranges, as the boundaries are compared as given.
+type compatInterval[T int64 | uint64 | float64] struct {
+	start, end T
+}
+
+// covers says whether every interval of inner is within one of outer
+func covers[T int64 | uint64 | float64](outer, inner []compatInterval[T]) bool {
+	for _, in := range inner {
+		within := false
+		for _, out := range outer {
+			if in.start >= out.start && in.end <= out.end {
+				within = true
+				break
+			}
+		}
+		if !within {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *compatChecker) intervals(
+	p schema.ComparePath,
+	prefix, keyword, old, new string,
+	expanded, narrowed bool,
+) {
+	switch {
+	case expanded && narrowed:
+		c.report(NonCompatible, p, false,
+			"%s%s changed from %q to %q", prefix, keyword, old, new)
+	case narrowed:
+		c.report(NonCompatible, p, false,
+			"%s%s narrowed from %q to %q", prefix, keyword, old, new)
+	case expanded:
+		c.report(Compatible, p, false,
+			"%s%s expanded from %q to %q", prefix, keyword, old, new)
+	}
+}
+
+func compareIntervals[T int64 | uint64 | float64](
+	c *compatChecker,
+	p schema.ComparePath,
+	prefix, keyword string,
+	old, new []compatInterval[T],
+	format func([]compatInterval[T]) string,
+) {
+	c.intervals(p, prefix, keyword, format(old), format(new),
+		!covers(old, new), !covers(new, old))
+}
+
+func formatIntervals[T int64 | uint64 | float64](intervals []compatInterval[T]) string {
+	var parts []string
+	for _, i := range intervals {
+		if i.start == i.end {
+			parts = append(parts, fmt.Sprint(i.start))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%v..%v", i.start, i.end))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// types compares the types of a leaf or leaf-list. Members of a union are
+// described by prefix.
+func (c *compatChecker) types(p schema.ComparePath, prefix string, old, new schema.Type) {
+	oldName, newName := builtinTypeName(old), builtinTypeName(new)
+	if oldName != newName {
+		c.report(NonCompatible, p, false, "%stype changed from %s to %s",
+			prefix, oldName, newName)
+		return
+	}
+
+	switch nv := new.(type) {
+	case schema.Integer:
+		ov := old.(schema.Integer)
+		var o, n []compatInterval[int64]
+		for _, rb := range ov.Rbs() {
+			o = append(o, compatInterval[int64]{rb.Start, rb.End})
+		}
+		for _, rb := range nv.Rbs() {
+			n = append(n, compatInterval[int64]{rb.Start, rb.End})
+		}
+		compareIntervals(c, p, prefix, "range", o, n, formatIntervals[int64])
+	case schema.Uinteger:
+		ov := old.(schema.Uinteger)
+		var o, n []compatInterval[uint64]
+		for _, rb := range ov.Rbs() {
+			o = append(o, compatInterval[uint64]{rb.Start, rb.End})
+		}
+		for _, rb := range nv.Rbs() {
+			n = append(n, compatInterval[uint64]{rb.Start, rb.End})
+		}
+		compareIntervals(c, p, prefix, "range", o, n, formatIntervals[uint64])
+	case schema.Decimal64:
+		ov := old.(schema.Decimal64)
+		if ov.Fd() != nv.Fd() {
+			c.report(NonCompatible, p, false,
+				"%sfraction-digits changed from %d to %d",
+				prefix, ov.Fd(), nv.Fd())
+			return
+		}
+		var o, n []compatInterval[float64]
+		for _, rb := range ov.Rbs() {
+			o = append(o, compatInterval[float64]{rb.Start, rb.End})
+		}
+		for _, rb := range nv.Rbs() {
+			n = append(n, compatInterval[float64]{rb.Start, rb.End})
+		}
+		compareIntervals(c, p, prefix, "range", o, n, formatIntervals[float64])
+	case schema.String:
+		ov := old.(schema.String)
+		c.lengths(p, prefix, ov.Len(), nv.Len())
+		c.patterns(p, prefix, ov.Pats(), nv.Pats())
+	case schema.Binary:
+		c.lengths(p, prefix, old.(schema.Binary).Length(), nv.Length())
+	case schema.Enumeration:
+		ov := old.(schema.Enumeration)
+		o := make(map[string]*schema.Enum)
+		for _, e := range ov.Enums() {
+			o[e.Val] = e
+		}
+		n := make(map[string]*schema.Enum)
+		for _, e := range nv.Enums() {
+			n[e.Val] = e
+		}
+		for _, name := range unionKeys(o, n) {
+			switch oe, ne := o[name], n[name]; {
+			case ne == nil:
+				c.report(NonCompatible, p, false, "%senum %s removed", prefix, name)
+			case oe == nil:
+				c.report(Compatible, p, false, "%senum %s added", prefix, name)
+			case oe.Value != ne.Value:
+				c.report(NonCompatible, p, false,
+					"%senum %s value changed from %d to %d",
+					prefix, name, oe.Value, ne.Value)
+			default:
+				c.valueStatus(p, prefix+"enum "+name, oe.Status(), ne.Status())
+			}
+		}
+	case schema.Bits:
+		ov := old.(schema.Bits)
+		o := make(map[string]*schema.Bit)
+		for _, b := range ov.Bits() {
+			o[b.Name] = b
+		}
+		n := make(map[string]*schema.Bit)
+		for _, b := range nv.Bits() {
+			n[b.Name] = b
+		}
+		for _, name := range unionKeys(o, n) {
+			switch ob, nb := o[name], n[name]; {
+			case nb == nil:
+				c.report(NonCompatible, p, false, "%sbit %s removed", prefix, name)
+			case ob == nil:
+				c.report(Compatible, p, false, "%sbit %s added", prefix, name)
+			case ob.Pos != nb.Pos:
+				c.report(NonCompatible, p, false,
+					"%sbit %s position changed from %d to %d",
+					prefix, name, ob.Pos, nb.Pos)
+			default:
+				c.valueStatus(p, prefix+"bit "+name, ob.Status(), nb.Status())
+			}
+		}
+	case schema.Identityref:
+		ov := old.(schema.Identityref)
+		o := make(map[string]bool)
+		for _, id := range ov.Identities() {
+			o[identityKey(id)] = true
+		}
+		n := make(map[string]bool)
+		for _, id := range nv.Identities() {
+			n[identityKey(id)] = true
+		}
+		for _, id := range unionKeys(o, n) {
+			switch {
+			case !n[id]:
+				c.report(NonCompatible, p, false,
+					"%sidentity %s no longer allowed", prefix, id)
+			case !o[id]:
+				c.report(Compatible, p, false,
+					"%sidentity %s allowed", prefix, id)
+			}
+		}
+	case schema.Union:
+		ov := old.(schema.Union)
+		if len(ov.Typs()) != len(nv.Typs()) {
+			c.report(NonCompatible, p, false,
+				"%sunion changed from %d to %d member types",
+				prefix, len(ov.Typs()), len(nv.Typs()))
+			return
+		}
+		for i := range nv.Typs() {
+			c.types(p, fmt.Sprintf("%smember %d: ", prefix, i+1),
+				ov.Typs()[i], nv.Typs()[i])
+		}
+	case schema.Leafref:
+		op, np := old.(schema.Leafref).Mach().GetExpr(), nv.Mach().GetExpr()
+		if op != np {
+			c.report(NonCompatible, p, false,
+				"%spath changed from %q to %q", prefix, op, np)
+		}
+	case schema.InstanceId:
+		switch or, nr := old.(schema.InstanceId).Require(), nv.Require(); {
+		case !or && nr:
+			c.report(NonCompatible, p, false,
+				"%srequire-instance changed to true", prefix)
+		case or && !nr:
+			c.report(Compatible, p, false,
+				"%srequire-instance changed to false", prefix)
+		}
+	}
+}
+
+func (c *compatChecker) valueStatus(p schema.ComparePath, what string, old, new schema.Status) {
+	switch {
+	case new > old:
+		c.report(StatusChange, p, false, "%s status changed from %s to %s",
+			what, statusName(old), statusName(new))
+	case new < old:
+		c.report(NonCompatible, p, false, "%s status changed from %s to %s",
+			what, statusName(old), statusName(new))
+	}
+}
+
+func (c *compatChecker) lengths(p schema.ComparePath, prefix string, old, new *schema.Length) {
+	intervals := func(l *schema.Length) []compatInterval[uint64] {
+		if l == nil {
+			return []compatInterval[uint64]{{0, ^uint64(0)}}
+		}
+		var is []compatInterval[uint64]
+		for _, lb := range l.Lbs {
+			is = append(is, compatInterval[uint64]{lb.Start, lb.End})
+		}
+		return is
+	}
+	compareIntervals(c, p, prefix, "length", intervals(old), intervals(new),
+		formatIntervals[uint64])
+}
+
+// A value must match every pattern, so adding one narrows the type, and
+// removing one expands it.
+func (c *compatChecker) patterns(p schema.ComparePath, prefix string, old, new [][]schema.Pattern) {
+	patternSet := func(pats [][]schema.Pattern) map[string]bool {
+		set := make(map[string]bool)
+		for _, ps := range pats {
+			for _, pat := range ps {
+				if pat.InvertMatch {
+					set["not "+pat.Pattern] = true
+				} else {
+					set[pat.Pattern] = true
+				}
+			}
+		}
+		return set
+	}
+	o, n := patternSet(old), patternSet(new)
+	for _, pat := range unionKeys(o, n) {
+		switch {
+		case !n[pat]:
+			c.report(Compatible, p, false, "%spattern %q removed", prefix, pat)
+		case !o[pat]:
+			c.report(NonCompatible, p, false, "%spattern %q added", prefix, pat)
+		}
+	}
+}
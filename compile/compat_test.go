@@ -0,0 +1,206 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+)
+
+const compatOldModule = `module compat {
+	namespace "urn:compat";
+	prefix c;
+
+	grouping named {
+		leaf name {
+			type string {
+				length "1..32";
+			}
+		}
+	}
+
+	container top {
+		uses named;
+		leaf level {
+			type uint8 {
+				range "0..100";
+			}
+		}
+		leaf size {
+			type int32 {
+				range "1..10";
+			}
+		}
+		leaf colour {
+			type enumeration {
+				enum red;
+				enum green;
+			}
+		}
+		leaf gone {
+			type string;
+		}
+		leaf old-style {
+			type string;
+		}
+		list item {
+			key "id";
+			leaf id {
+				type string;
+			}
+			leaf value {
+				type string;
+			}
+		}
+		container state {
+			leaf counter {
+				type uint32;
+			}
+		}
+	}
+
+	rpc reset;
+}`
+
+const compatNewModule = `module compat {
+	namespace "urn:compat";
+	prefix c;
+
+	grouping named {
+		leaf name {
+			type string {
+				length "1..16";
+			}
+		}
+	}
+
+	container top {
+		uses named;
+		leaf level {
+			type uint8 {
+				range "0..200";
+			}
+		}
+		leaf size {
+			type int32 {
+				range "1..10";
+			}
+			mandatory true;
+		}
+		leaf colour {
+			type enumeration {
+				enum red;
+				enum green;
+				enum blue;
+			}
+		}
+		leaf old-style {
+			type string;
+			status deprecated;
+		}
+		leaf extra {
+			type string;
+		}
+		leaf required {
+			type string;
+			mandatory true;
+		}
+		list item {
+			key "id value";
+			leaf id {
+				type string;
+			}
+			leaf value {
+				type string;
+			}
+		}
+		container state {
+			config false;
+			leaf counter {
+				type uint32;
+			}
+		}
+	}
+}`
+
+func checkCompatibility(t *testing.T, old, new string) []compile.CompatibilityChange {
+	t.Helper()
+	config := func(module string) *compile.Config {
+		return &compile.Config{
+			Repository: compile.MapRepository(map[string][]byte{
+				"compat.yang": []byte(module),
+			}),
+			Features: compile.FeaturesFromNames(true),
+		}
+	}
+	changes, err := compile.CheckCompatibility(nil, config(old), config(new))
+	if err != nil {
+		t.Fatalf("Unexpected CheckCompatibility error: %s", err)
+	}
+	return changes
+}
+
+// Locations are in the new revision, except for removed nodes. The
+// changed grouping is reported where it is used.
+func TestCheckCompatibility(t *testing.T) {
+	expected := []string{
+		"compat.yang:26:2: compatible: /compat:top/colour: enum blue added",
+		"compat.yang:37:2: compatible: /compat:top/extra: leaf added",
+		"compat.yang:31:2: non-compatible: /compat:top/gone: leaf removed",
+		"compat.yang:44:2: non-compatible: /compat:top/item: " +
+			"key changed from \"id\" to \"id value\"",
+		"compat.yang:15:2: compatible: /compat:top/level: " +
+			"range expanded from \"0..100\" to \"0..200\"",
+		"compat.yang:6:2: non-compatible: /compat:top/name: " +
+			"length narrowed from \"1..32\" to \"1..16\"",
+		"compat.yang:33:2: status-change: /compat:top/old-style: " +
+			"status changed from current to deprecated",
+		"compat.yang:40:2: non-compatible: /compat:top/required: mandatory leaf added",
+		"compat.yang:20:2: non-compatible: /compat:top/size: mandatory changed to true",
+		"compat.yang:53:2: non-compatible: /compat:top/state: config changed to false",
+		"compat.yang:53:1: non-compatible: /compat:reset: rpc removed",
+	}
+
+	changes := checkCompatibility(t, compatOldModule, compatNewModule)
+	var actual []string
+	for _, change := range changes {
+		actual = append(actual, change.String())
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected changes\nexpected:\n%s\nactual:\n%s",
+			strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestCheckCompatibilityUnchanged(t *testing.T) {
+	if changes := checkCompatibility(t, compatOldModule, compatOldModule); len(changes) != 0 {
+		t.Errorf("Unexpected changes: %v", changes)
+	}
+}
+
+func TestCheckCompatibilityCompileError(t *testing.T) {
+	config := &compile.Config{
+		Repository: compile.MapRepository(map[string][]byte{
+			"compat.yang": []byte("module compat {"),
+		}),
+		Features: compile.FeaturesFromNames(true),
+	}
+	_, err := compile.CheckCompatibility(nil, config, config)
+	if err == nil || !strings.HasPrefix(err.Error(), "old revision: ") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -26,9 +26,8 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log/syslog"
+	"math"
 	"os"
 	"runtime"
 	"strings"
@@ -56,10 +55,17 @@ const emptyDefault = ""
 type Config struct {
 	YangDir       string
 	YangLocations YangLocator
-	CapsLocation  string
-	Features      FeaturesChecker
-	SkipUnknown   bool
-	Filter        SchemaFilter
+	Repository    ModuleRepository
+	// CompileModuleClosure also compiles modules that augment or deviate
+	// the modules asked for.
+	AugmentingModules bool
+	CapsLocation      string
+	Features          FeaturesChecker
+	SkipUnknown       bool
+	Filter            SchemaFilter
+	// Report every independent error found, as CompileErrors, rather than
+	// stopping at the first.
+	CollectErrors bool
 	// Used for the likes of yangc to inject names of valid custom functions
 	// that otherwise would not be visible to the compiler.  Only used during
 	// path evaluation.
@@ -76,6 +82,12 @@ func (c *Config) yangLocations() YangLocator {
 	return YangLocations(YangDirs(c.YangDir), c.YangLocations)
 }
 
+// Files found through YangDir and YangLocations come before those in the
+// Repository.
+func (c *Config) repository() ModuleRepository {
+	return Repositories(c.yangLocations(), c.Repository)
+}
+
 type SchemaType int
 
 const (
@@ -150,6 +162,11 @@ type Compiler struct {
 	// evaluation for configd:must statements when using tools that are run
 	// without custom function plugins present (eg yangc / DRAM).
 	userFnChecker xpath.UserCustomFunctionCheckerFn
+	// Carry on past errors where possible, recording each in errs.
+	collectErrors bool
+	errs          CompileErrors
+	// The uses statement each node copied from a grouping came from.
+	usesSites map[parse.Node]parse.Node
 }
 
 const (
@@ -222,6 +239,7 @@ func NewCompiler(
 	c.filter = filter
 	c.deviations = make(map[string]map[string]struct{})
 	c.featuresChecker = features
+	c.usesSites = make(map[parse.Node]parse.Node)
 
 	if dlog, err := syslog.NewLogger(syslog.LOG_DEBUG, 0); err == nil {
 		xpath.SetDebugLogger(dlog)
@@ -236,6 +254,11 @@ func (c *Compiler) addCustomFnChecker(
 	return c
 }
 
+func (c *Compiler) collectAllErrors(collect bool) *Compiler {
+	c.collectErrors = collect
+	return c
+}
+
 func (c *Compiler) addDeviation(target, source string) {
 	if t, ok := c.deviations[target]; ok {
 		if _, ok := t[source]; !ok {
@@ -277,12 +300,15 @@ func (c *Compiler) recover(errp *error) {
 			panic(e)
 		}
 		*errp = e.(error)
+		if c.collectErrors {
+			c.addError(asCompileError(*errp))
+			*errp = c.collectedErrors()
+		}
 	}
 }
 
 func (c *Compiler) error(n parse.Node, err error) {
-	s, _ := n.ErrorContext()
-	panic(fmt.Errorf("%s: %s", s, err))
+	panic(c.newCompileError(n, err))
 }
 
 func (c *Compiler) saveWarning(n parse.Node, warn xutils.Warning) {
@@ -427,10 +453,20 @@ func (comp *Compiler) validateRangeBoundaries(
 // It is an implicit reference to the local module when the optional
 // [prefix:] is absent
 func (c *Compiler) getModuleAndReference(m, n parse.Node, targetType parse.NodeType) (parse.Node, parse.Node) {
+	return c.getModuleAndNamedReference(m, n, targetType, n.Argument().String())
+}
+
+// As getModuleAndReference, but for a reference that is only part of
+// the argument of n, such as a feature within an if-feature expression
+func (c *Compiler) getModuleAndNamedReference(
+	m, n parse.Node,
+	targetType parse.NodeType,
+	ref string,
+) (parse.Node, parse.Node) {
 	// Assume an implicit local module reference until
 	// we learn otherwise.
 	targetModule := m
-	name := n.Argument().String()
+	name := ref
 	nameparts := strings.Split(name, ":")
 	if len(nameparts) > 2 {
 		// Can't have more than one ':'
@@ -452,7 +488,7 @@ func (c *Compiler) getModuleAndReference(m, n parse.Node, targetType parse.NodeT
 	if reference == nil {
 		if !c.skipUnknown {
 			// Feature not found in specified module
-			c.error(n, fmt.Errorf("%s not valid: %s", targetType.String(), n.Argument().String()))
+			c.error(n, fmt.Errorf("%s not valid: %s", targetType.String(), ref))
 			return nil, nil
 		}
 		var nc parse.NodeCardinality
@@ -488,11 +524,23 @@ func (c *Compiler) isFeatureValid(m parse.Node, n parse.Node, featTree map[strin
 	}
 	featTree[featName] = true
 
-	// Verify each feature that this feature references via an if-feature
+	// Verify each feature that this feature references via an if-feature,
+	// then evaluate the if-feature expression using the verified values
 	for _, ifFeat := range n.ChildrenByType(parse.NodeIfFeature) {
-		mod, feature := c.getModuleAndReference(m, ifFeat, parse.NodeFeature)
-		c.assertReferenceStatus(n, feature, schema.Current)
-		enabled = c.isFeatureValid(mod, feature, featTree) && enabled
+		expr := ifFeat.ArgIfFeature()
+		valid := make(map[xml.Name]bool)
+		for _, ref := range expr.Features() {
+			if _, ok := valid[ref]; ok {
+				continue
+			}
+			mod, feature := c.getModuleAndNamedReference(
+				m, ifFeat, parse.NodeFeature, featureRefString(ref))
+			c.assertReferenceStatus(n, feature, schema.Current)
+			valid[ref] = c.isFeatureValid(mod, feature, featTree)
+		}
+		enabled = expr.Eval(func(ref xml.Name) bool {
+			return valid[ref]
+		}) && enabled
 	}
 
 	// update the verified features
@@ -551,6 +599,9 @@ func (c *Compiler) identityCheckCyclicRef(name string, ids map[string]parse.Node
 		c.identityCheckCyclicRef(nm, ids, assigned)
 	}
 
+	// An identity with multiple bases may be reached by more than one
+	// path, so only identities on the current path count as a cycle.
+	delete(assigned, name)
 }
 
 func (c *Compiler) checkIdentities() error {
@@ -619,14 +670,18 @@ func (c *Compiler) ExpandModules() (err error) {
 		mod.GetSubmodules()[mn] = subm.GetModule()
 	}
 
+	c.checkRevisions()
+
 	//Process includes
 	for _, module := range c.modules {
 		r := module.GetModule()
-		c.VerifyModuleIncludes(r, module.GetSubmodules())
-		for _, s := range module.GetSubmodules() {
-			c.ProcessSubmoduleIncludes(s, module.GetSubmodules())
-		}
-		c.ProcessModuleIncludes(r, module.GetSubmodules())
+		c.collect(func() {
+			c.VerifyModuleIncludes(r, module.GetSubmodules())
+			for _, s := range module.GetSubmodules() {
+				c.ProcessSubmoduleIncludes(s, module.GetSubmodules())
+			}
+			c.ProcessModuleIncludes(r, module.GetSubmodules())
+		})
 	}
 
 	//Process imports
@@ -656,13 +711,17 @@ func (c *Compiler) ExpandModules() (err error) {
 
 	// Check for cycles in all groupings before applying
 	for _, module := range c.modules {
-		if err := c.validateModuleGroupings(module.GetModule()); err != nil {
-			c.error(module.GetModule(), err)
-		}
-		for _, sm := range module.GetSubmodules() {
-			if err := c.validateModuleGroupings(sm); err != nil {
-				c.error(sm, err)
+		c.collect(func() {
+			if err := c.validateModuleGroupings(module.GetModule()); err != nil {
+				c.error(module.GetModule(), err)
 			}
+		})
+		for _, sm := range module.GetSubmodules() {
+			c.collect(func() {
+				if err := c.validateModuleGroupings(sm); err != nil {
+					c.error(sm, err)
+				}
+			})
 		}
 	}
 
@@ -670,7 +729,7 @@ func (c *Compiler) ExpandModules() (err error) {
 	for _, name := range c.modnames {
 		module, ok := c.modules[name]
 		if ok {
-			c.expandModule(module)
+			c.collect(func() { c.expandModule(module) })
 		} else if !c.skipUnknown {
 			i := c.findMissingImportStatement(name)
 			c.error(i, fmt.Errorf("module not found"))
@@ -699,8 +758,9 @@ func (c *Compiler) BuildModules() (modules map[string]schema.Model, err error) {
 	for _, name := range c.modnames {
 		module, ok := c.modules[name]
 		if ok {
-			newModule := c.BuildModule(module, module.GetModule())
-			modules[name] = newModule
+			c.collect(func() {
+				modules[name] = c.BuildModule(module, module.GetModule())
+			})
 		} else if !c.skipUnknown {
 			panic(fmt.Errorf("required module %s was not found", name))
 		}
@@ -708,6 +768,51 @@ func (c *Compiler) BuildModules() (modules map[string]schema.Model, err error) {
 	return modules, nil
 }
 
+// Imports and includes with a revision-date require that exact revision.
+// When no revision-date is given any revision may be used, and the latest
+// available revision has already been selected.
+func (c *Compiler) checkRevisions() {
+	check := func(n parse.Node) {
+		for _, i := range n.ChildrenByType(parse.NodeImport) {
+			if mod, ok := c.modules[i.Name()]; ok {
+				c.collect(func() { c.checkRevision(i, "module", mod) })
+			}
+		}
+		for _, i := range n.ChildrenByType(parse.NodeInclude) {
+			if smod, ok := c.submodules[i.Name()]; ok {
+				c.collect(func() { c.checkRevision(i, "submodule", smod) })
+			}
+		}
+	}
+	for _, module := range c.modules {
+		check(module.GetModule())
+	}
+	for _, submodule := range c.submodules {
+		check(submodule.GetModule())
+	}
+}
+
+func (c *Compiler) checkRevision(stmt parse.Node, kind string, found *parse.Module) {
+	want := stmt.RevisionDate()
+	inUse := found.GetModule().Revision()
+	if want == "" || want == inUse {
+		return
+	}
+	if conflict := found.RevisionConflict(); conflict != "" {
+		c.error(stmt, fmt.Errorf("%s %s: %s", kind, stmt.Name(), conflict))
+	}
+	for _, rev := range found.Revisions() {
+		if rev == want {
+			c.error(stmt, fmt.Errorf(
+				"revision %s of %s %s conflicts with revision %s required elsewhere",
+				want, kind, stmt.Name(), inUse))
+		}
+	}
+	c.error(stmt, fmt.Errorf(
+		"revision %s of %s %s not found, available revisions: %s",
+		want, kind, stmt.Name(), strings.Join(found.Revisions(), ", ")))
+}
+
 func (c *Compiler) VerifyModuleIncludes(m parse.Node, submodules map[string]parse.Node) {
 	g := tsort.New()
 	for _, i := range m.ChildrenByType(parse.NodeInclude) {
@@ -801,25 +906,72 @@ func (c *Compiler) buildSchemaTree(m parse.Node, n parse.Node) schema.Tree {
 	return c.extendTree(n, tree)
 }
 
+// buildActions compiles the action statements defined directly under
+// a container or list node, keyed by action name.
+func (c *Compiler) buildActions(
+	features inheritedFeatures,
+	m parse.Node,
+	n parse.Node,
+) map[string]schema.Action {
+	actions := make(map[string]schema.Action)
+	for _, a := range n.ChildrenByType(parse.NodeAction) {
+		if c.IgnoreNode(a, features.status) {
+			continue
+		}
+		input := a.ChildByType(parse.NodeInput)
+		inputTree := c.buildSchemaTree(m, input)
+
+		output := a.ChildByType(parse.NodeOutput)
+		outputTree := c.buildSchemaTree(m, output)
+
+		action := schema.NewAction(inputTree, outputTree)
+		actions[a.Name()] = c.extendAction(a, action)
+	}
+	return actions
+}
+
+// buildNotifications compiles the notification statements defined
+// directly under a container or list node, keyed by notification name.
+func (c *Compiler) buildNotifications(
+	features inheritedFeatures,
+	m parse.Node,
+	n parse.Node,
+) map[string]schema.Notification {
+	notifications := make(map[string]schema.Notification)
+	for _, nt := range n.ChildrenByType(parse.NodeNotification) {
+		if c.IgnoreNode(nt, features.status) {
+			continue
+		}
+		notificationTree := c.buildSchemaTree(m, nt)
+		notification := schema.NewNotification(notificationTree)
+		notifications[nt.Name()] = c.extendNotification(nt, notification)
+	}
+	return notifications
+}
+
 func (c *Compiler) BuildModule(module *parse.Module, m parse.Node) schema.Model {
 	c.CheckChildren(m, m)
 	rpcs := make(map[string]schema.Rpc)
 	for _, r := range m.ChildrenByType(parse.NodeRpc) {
-		input := r.ChildByType(parse.NodeInput)
-		inputTree := c.buildSchemaTree(m, input)
+		c.collect(func() {
+			input := r.ChildByType(parse.NodeInput)
+			inputTree := c.buildSchemaTree(m, input)
 
-		output := r.ChildByType(parse.NodeOutput)
-		outputTree := c.buildSchemaTree(m, output)
+			output := r.ChildByType(parse.NodeOutput)
+			outputTree := c.buildSchemaTree(m, output)
 
-		rpc := schema.NewRpc(inputTree, outputTree)
-		rpcs[r.Name()] = c.extendRpc(r, rpc)
+			rpc := schema.NewRpc(inputTree, outputTree)
+			rpcs[r.Name()] = c.extendRpc(r, rpc)
+		})
 	}
 
 	notifications := make(map[string]schema.Notification)
 	for _, n := range m.ChildrenByType(parse.NodeNotification) {
-		notificationTree := c.buildSchemaTree(m, n)
-		notification := schema.NewNotification(notificationTree)
-		notifications[n.Name()] = c.extendNotification(n, notification)
+		c.collect(func() {
+			notificationTree := c.buildSchemaTree(m, n)
+			notification := schema.NewNotification(notificationTree)
+			notifications[n.Name()] = c.extendNotification(n, notification)
+		})
 	}
 
 	inherited := inheritedFeatures{config: true, status: schema.Current}
@@ -860,6 +1012,15 @@ func (c *Compiler) IgnoreNode(node parse.Node, parentStatus schema.Status) bool
 	return false
 }
 
+// The if-feature expressions of a node, for the schema to show
+func ifFeatures(n parse.Node) []string {
+	var exprs []string
+	for _, ifn := range n.ChildrenByType(parse.NodeIfFeature) {
+		exprs = append(exprs, ifn.Argument().String())
+	}
+	return exprs
+}
+
 func parseStatus(statusStatement parse.Node) schema.Status {
 
 	statusString := statusStatement.ArgStatus()
@@ -953,7 +1114,8 @@ func (c *Compiler) buildChildren(inherited inheritedFeatures, m parse.Node, body
 		if c.IgnoreNode(dataDef, inherited.status) {
 			continue
 		}
-		ch := c.BuildNode(inherited, m, dataDef, false)
+		var ch []schema.Node
+		c.collect(func() { ch = c.BuildNode(inherited, m, dataDef, false) })
 		for _, sn := range ch {
 			if c.filter != nil && !c.filter(sn) {
 				continue
@@ -994,7 +1156,8 @@ func (c *Compiler) buildListChildren(
 				}
 			}
 		}
-		ch := c.BuildNode(inherited, m, dataDef, isKey)
+		var ch []schema.Node
+		c.collect(func() { ch = c.BuildNode(inherited, m, dataDef, isKey) })
 		for _, sn := range ch {
 			if c.filter != nil && !c.filter(sn) {
 				continue
@@ -1024,6 +1187,10 @@ func (c *Compiler) BuildNode(
 		retNodes = []schema.Node{c.BuildLeafList(features, m, n)}
 	case parse.NodeLeaf:
 		retNodes = []schema.Node{c.BuildLeaf(features, m, n, isKey)}
+	case parse.NodeAnydata:
+		retNodes = []schema.Node{c.BuildAnydata(features, m, n)}
+	case parse.NodeAnyxml:
+		retNodes = []schema.Node{c.BuildAnyxml(features, m, n)}
 	case parse.NodeChoice:
 		retNodes = []schema.Node{c.BuildChoice(features, m, n)}
 	case parse.NodeCase:
@@ -1043,14 +1210,53 @@ func (c *Compiler) BuildNode(
 }
 
 func (c *Compiler) CheckChildren(m parse.Node, n parse.Node) {
+	for _, ch := range n.Children() {
+		c.collect(func() { c.checkChild(m, ch) })
+	}
+}
+
+func (c *Compiler) checkChild(m parse.Node, ch parse.Node) {
+	switch ch.Type() {
+	case parse.NodeList:
+		c.CheckUniqueConstraint(m, ch)
+		if len(ch.Keys()) == 0 {
+			c.checkNoKeylessListOperations(ch, ch)
+		}
+	case parse.NodeUnknown:
+		c.CheckUnknown(m, ch)
+	case parse.NodeRpc, parse.NodeAction, parse.NodeNotification:
+		c.checkNoNestedOperations(ch, ch)
+	}
+	c.CheckChildren(m, ch)
+}
+
+// RFC 7950 7.15 and 7.16: an action or notification must not be defined
+// within an rpc, action or notification.
+func (c *Compiler) checkNoNestedOperations(op parse.Node, n parse.Node) {
 	for _, ch := range n.Children() {
 		switch ch.Type() {
-		case parse.NodeList:
-			c.CheckUniqueConstraint(m, ch)
-		case parse.NodeUnknown:
-			c.CheckUnknown(m, ch)
+		case parse.NodeGrouping:
+			continue
+		case parse.NodeAction, parse.NodeNotification:
+			c.error(ch, fmt.Errorf("%s not allowed within %s %s",
+				ch.Type(), op.Type(), op.Name()))
 		}
-		c.CheckChildren(m, ch)
+		c.checkNoNestedOperations(op, ch)
+	}
+}
+
+// RFC 7950 7.15 and 7.16: an action or notification must not have a list
+// ancestor without a key.
+func (c *Compiler) checkNoKeylessListOperations(list parse.Node, n parse.Node) {
+	for _, ch := range n.Children() {
+		switch ch.Type() {
+		case parse.NodeGrouping:
+			continue
+		case parse.NodeAction, parse.NodeNotification:
+			c.error(ch, fmt.Errorf("%s not allowed within list %s without a key",
+				ch.Type(), list.Name()))
+		}
+		c.checkNoKeylessListOperations(list, ch)
 	}
 }
 
@@ -1132,14 +1338,25 @@ func (c *Compiler) CheckUnknown(m parse.Node, n parse.Node) {
 // referenced feature is enabled
 // A referenced feature takes the form [prefix:]feature-name
 // If no prefix is present, it is an implicit reference to the
-// local module.
+// local module. References may be combined into a boolean
+// expression using not, and, or and parentheses.
 func (c *Compiler) CheckIfFeature(n parse.Node, parentStatus schema.Status) bool {
 
-	mod, feature := c.getModuleAndReference(n.Root(), n, parse.NodeFeature)
+	return n.ArgIfFeature().Eval(func(ref xml.Name) bool {
+		mod, feature := c.getModuleAndNamedReference(
+			n.Root(), n, parse.NodeFeature, featureRefString(ref))
 
-	c.assertReferenceStatus(n, feature, parentStatus)
+		c.assertReferenceStatus(n, feature, parentStatus)
 
-	return c.verifiedFeatureEnabled(mod.Name() + ":" + feature.Name())
+		return c.verifiedFeatureEnabled(mod.Name() + ":" + feature.Name())
+	})
+}
+
+func featureRefString(ref xml.Name) string {
+	if ref.Space == "" {
+		return ref.Local
+	}
+	return ref.Space + ":" + ref.Local
 }
 
 // Takes a parse.Node ErrorContext for a must / when node and extracts
@@ -1335,7 +1552,10 @@ func (c *Compiler) BuildContainer(features inheritedFeatures, m parse.Node, n pa
 		features.status,
 		c.BuildWhens(n),
 		c.BuildMusts(n),
+		c.buildActions(features, m, n),
+		c.buildNotifications(features, m, n),
 		c.buildChildren(features, m, n.ChildrenByType(parse.NodeDataDef)),
+		schema.WithIfFeatures(ifFeatures(n)),
 	)
 
 	if err != nil {
@@ -1376,7 +1596,10 @@ func (c *Compiler) BuildList(features inheritedFeatures, m parse.Node, n parse.N
 		allUniques(n),
 		c.BuildWhens(n),
 		c.BuildMusts(n),
+		c.buildActions(features, m, n),
+		c.buildNotifications(features, m, n),
 		children,
+		schema.WithIfFeatures(ifFeatures(n)),
 	)
 
 	if err != nil {
@@ -1442,9 +1665,9 @@ func (c *Compiler) BuildLeafList(features inheritedFeatures, m parse.Node, n par
 		n.GetNodeSubmoduleName(),
 		n.Desc(),
 		n.Ref(),
-		n.Def(),
 		n.OrdBy(),
 		n.Units(),
+		c.getLeafListDefaults(n, typ, features.config),
 		n.Min(),
 		n.Max(),
 		typ,
@@ -1452,12 +1675,87 @@ func (c *Compiler) BuildLeafList(features inheritedFeatures, m parse.Node, n par
 		features.status,
 		c.BuildWhens(n),
 		c.BuildMusts(n),
+		schema.WithIfFeatures(ifFeatures(n)),
 	)
 
 	c.filterDisabledExtensions(n)
 	return c.extendLeafList(n, l)
 }
 
+// RFC 7950 7.7.2: the defaults of a leaf-list are its default statements,
+// or if there are none, the default of its type, but only when
+// min-elements is 0.
+func (c *Compiler) getLeafListDefaults(
+	n parse.Node,
+	typ schema.Type,
+	config bool,
+) []string {
+	defs := n.Defs()
+	if len(defs) == 0 {
+		if def, hasDef := typ.Default(); hasDef && n.Min() == 0 {
+			return []string{def}
+		}
+		return nil
+	}
+
+	if n.Min() > 0 {
+		c.error(n, errors.New(
+			"leaf-list cannot have default and min-elements greater than 0"))
+	}
+	seen := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		if err := typ.Validate(nil, []string{}, def); err != nil {
+			c.error(n, fmt.Errorf("Invalid default '%s' for %s: %s\n",
+				def, typ.Name(), err))
+		}
+		if config && seen[def] {
+			c.error(n, fmt.Errorf("Duplicate default '%s'", def))
+		}
+		seen[def] = true
+	}
+	return defs
+}
+
+func (c *Compiler) BuildAnydata(features inheritedFeatures, m parse.Node, n parse.Node) schema.Node {
+	a := schema.NewAnydata(
+		n.Name(),
+		n.GetNodeNamespace(m, c.modules),
+		n.GetNodeModulename(m),
+		n.GetNodeSubmoduleName(),
+		n.Desc(),
+		n.Ref(),
+		n.Mandatory(),
+		features.config,
+		features.status,
+		c.BuildWhens(n),
+		c.BuildMusts(n),
+		schema.WithIfFeatures(ifFeatures(n)),
+	)
+
+	c.filterDisabledExtensions(n)
+	return c.extendAnydata(n, a)
+}
+
+func (c *Compiler) BuildAnyxml(features inheritedFeatures, m parse.Node, n parse.Node) schema.Node {
+	a := schema.NewAnyxml(
+		n.Name(),
+		n.GetNodeNamespace(m, c.modules),
+		n.GetNodeModulename(m),
+		n.GetNodeSubmoduleName(),
+		n.Desc(),
+		n.Ref(),
+		n.Mandatory(),
+		features.config,
+		features.status,
+		c.BuildWhens(n),
+		c.BuildMusts(n),
+		schema.WithIfFeatures(ifFeatures(n)),
+	)
+
+	c.filterDisabledExtensions(n)
+	return c.extendAnyxml(n, a)
+}
+
 func (c *Compiler) BuildOpdCommand(features inheritedFeatures, m parse.Node, n parse.Node) schema.Node {
 	com, err := schema.NewOpdCommand(
 		n.Name(),
@@ -1615,6 +1913,7 @@ func (comp *Compiler) BuildLeaf(
 		features.status,
 		comp.BuildWhens(node),
 		comp.BuildMusts(node),
+		schema.WithIfFeatures(ifFeatures(node)),
 	)
 
 	if isKey {
@@ -1662,6 +1961,7 @@ func (c *Compiler) BuildChoice(features inheritedFeatures, m parse.Node, n parse
 		features.status,
 		c.BuildWhens(n),
 		children,
+		schema.WithIfFeatures(ifFeatures(n)),
 	)
 
 	if err != nil {
@@ -1691,6 +1991,7 @@ func (c *Compiler) BuildCase(features inheritedFeatures, m parse.Node, n parse.N
 		features.status,
 		c.BuildWhens(n),
 		children,
+		schema.WithIfFeatures(ifFeatures(n)),
 	)
 
 	if err != nil {
@@ -1771,7 +2072,7 @@ func (c *Compiler) getEnums(base schema.Enumeration, node parse.Node) []*schema.
 	num_enums := node.ChildrenByType(parse.NodeEnum)
 	if base != nil {
 		if len(num_enums) > 0 {
-			c.error(node, errors.New("cannot restrict predefined enumeration"))
+			return c.restrictEnums(base, num_enums)
 		}
 		return base.Enums()
 	}
@@ -1780,16 +2081,103 @@ func (c *Compiler) getEnums(base schema.Enumeration, node parse.Node) []*schema.
 		c.error(node, errors.New("enumeration requires at least one enum"))
 	}
 
+	// Values not explicitly assigned are one greater than the highest
+	// value assigned so far, starting at zero (RFC 7950 section 9.6.4.2).
+	seen := make(map[string]struct{}, len(num_enums))
+	used := make(map[int]string, len(num_enums))
+	highest, assigned := 0, false
 	enums := make([]*schema.Enum, 0, len(num_enums))
-	for _, en := range node.ChildrenByType(parse.NodeEnum) {
-		enum := schema.NewEnum(en.ArgString(), en.Desc(), en.Ref(),
-			c.getStatus(en, schema.Current), en.Value())
+	for _, en := range num_enums {
+		name := en.ArgString()
+		if _, ok := seen[name]; ok {
+			c.error(en, fmt.Errorf("duplicate enum %s", name))
+		}
+		seen[name] = struct{}{}
+
+		var value int
+		switch {
+		case en.ChildByType(parse.NodeValue) != nil:
+			value = en.Value()
+		case !assigned:
+			value = 0
+		case highest == math.MaxInt32:
+			c.error(en, fmt.Errorf(
+				"enum %s requires an explicit value", name))
+		default:
+			value = highest + 1
+		}
+		if other, ok := used[value]; ok {
+			c.error(en, fmt.Errorf(
+				"enum %s value %d already used by enum %s",
+				name, value, other))
+		}
+		used[value] = name
+		if !assigned || value > highest {
+			highest, assigned = value, true
+		}
+
+		// A disabled enum still takes part in assigning values, so
+		// the values of the others don't depend on the features
+		if c.IgnoreNode(en, schema.Current) {
+			continue
+		}
+		enum := schema.NewEnum(name, en.Desc(), en.Ref(),
+			c.getStatus(en, schema.Current), value)
 		enums = append(enums, enum)
 	}
 
 	return enums
 }
 
+// restrictEnums builds the enums of an enumeration derived from base. Each
+// enum must already exist in base, and keeps the value assigned there.
+func (c *Compiler) restrictEnums(
+	base schema.Enumeration,
+	enumNodes []parse.Node,
+) []*schema.Enum {
+
+	baseEnums := make(map[string]*schema.Enum, len(base.Enums()))
+	for _, en := range base.Enums() {
+		baseEnums[en.Val] = en
+	}
+
+	seen := make(map[string]struct{}, len(enumNodes))
+	enums := make([]*schema.Enum, 0, len(enumNodes))
+	for _, en := range enumNodes {
+		name := en.ArgString()
+		baseEnum, ok := baseEnums[name]
+		if !ok {
+			c.error(en, fmt.Errorf(
+				"enum %s is not defined in the base enumeration", name))
+		}
+		if _, ok := seen[name]; ok {
+			c.error(en, fmt.Errorf("duplicate enum %s", name))
+		}
+		seen[name] = struct{}{}
+		if en.ChildByType(parse.NodeValue) != nil &&
+			en.Value() != baseEnum.Value {
+			c.error(en, fmt.Errorf(
+				"enum %s value %d does not match base value %d",
+				name, en.Value(), baseEnum.Value))
+		}
+		if c.IgnoreNode(en, baseEnum.Status()) {
+			continue
+		}
+
+		desc, ref := en.Desc(), en.Ref()
+		if desc == "" {
+			desc = baseEnum.Desc
+		}
+		if ref == "" {
+			ref = baseEnum.Ref
+		}
+		enums = append(enums, schema.NewEnum(name, desc, ref,
+			c.getStatus(en, baseEnum.Status()), baseEnum.Value))
+	}
+
+	return enums
+}
+
 func (comp *Compiler) makeEnumeration(
 	name xml.Name,
 	node parse.Node,
@@ -1806,47 +2194,98 @@ func (comp *Compiler) makeEnumeration(
 	return schema.NewEnumeration(name, enums, def, hasDef)
 }
 
-func (c *Compiler) identityValues(cfgNode, node parse.Node, ident parse.Node, rt []*schema.Identity) []*schema.Identity {
+func (c *Compiler) identityBases(id parse.Node) []string {
+	var bases []string
+	for _, b := range id.ChildrenByType(parse.NodeBase) {
+		mod, base := c.getModuleAndReference(id.Root(), b, parse.NodeIdentity)
+		bases = append(bases, mod.Name()+":"+base.Name())
+	}
+	return bases
+}
+
+// Collect all identities derived, directly or indirectly, from ident.
+// With multiple inheritance the same identity may be found through
+// several bases, but is only returned once.
+func (c *Compiler) identityValues(
+	cfgNode, node parse.Node,
+	ident parse.Node,
+	rt []*schema.Identity,
+	seen map[string]bool,
+) []*schema.Identity {
 	strp := cfgNode.GetNodeModulename(cfgNode.Root()) + ":"
 
 	for _, id := range ident.ChildrenByType(parse.NodeIdentity) {
 		nm := id.Root().Name() + ":" + id.Name()
+		if seen[nm] {
+			continue
+		}
+		seen[nm] = true
+		// Identities derived from a disabled identity are not reached
+		if c.IgnoreNode(id, schema.Current) {
+			continue
+		}
 		rname := strings.TrimPrefix(nm, strp)
 		i := schema.NewIdentity(id.GetNodeModulename(id.Root()),
 			id.GetNodeNamespace(id.Root(), c.modules),
 			rname, id.Desc(), id.Ref(),
-			c.getStatus(id, schema.Current), id.Name())
+			c.getStatus(id, schema.Current), id.Name(),
+			c.identityBases(id))
 		rt = append(rt, i)
 		n, _ := c.identities[nm]
-		rt = c.identityValues(cfgNode, node, n, rt)
+		rt = c.identityValues(cfgNode, node, n, rt, seen)
 	}
 	return rt
 }
 
+// Only identities derived from every one of the bases are valid
+// (RFC 7950 7.18.2)
+func intersectIdentities(a, b []*schema.Identity) []*schema.Identity {
+	inB := make(map[string]bool, len(b))
+	for _, id := range b {
+		inB[id.Module+":"+id.Value] = true
+	}
+	out := make([]*schema.Identity, 0, len(a))
+	for _, id := range a {
+		if inB[id.Module+":"+id.Value] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
 func (c *Compiler) getIdentities(cfgNode parse.Node, i schema.Identityref, node parse.Node, parentStatus schema.Status) []*schema.Identity {
 
-	baseStmnt := node.ChildByType(parse.NodeBase)
+	baseStmnts := node.ChildrenByType(parse.NodeBase)
 	if i != nil {
-		if baseStmnt != nil {
+		if len(baseStmnts) != 0 {
 			c.error(node, errors.New("cannot restrict predefined identityref"))
 		}
 		return i.Identities()
 	}
 
-	if baseStmnt == nil {
+	if len(baseStmnts) == 0 {
 		c.error(node, errors.New("cannot use identityref without a base"))
 	}
 
 	mod := node.Root()
-	tm, ident := c.getModuleAndReference(mod, baseStmnt, parse.NodeIdentity)
+	var ids []*schema.Identity
+	for index, baseStmnt := range baseStmnts {
+		tm, ident := c.getModuleAndReference(mod, baseStmnt, parse.NodeIdentity)
 
-	idid, _ := c.identities[tm.Name()+":"+ident.Name()]
+		idid, _ := c.identities[tm.Name()+":"+ident.Name()]
 
-	idents := make([]*schema.Identity, 0, 0)
+		idents := make([]*schema.Identity, 0, 0)
 
-	c.assertReferenceStatus(node, idid, parentStatus)
-	node.AddChildren(ident)
-	ids := c.identityValues(cfgNode, node, idid, idents)
+		c.assertReferenceStatus(node, idid, parentStatus)
+		node.AddChildren(ident)
+		derived := c.identityValues(cfgNode, node, idid, idents,
+			make(map[string]bool))
+		if index == 0 {
+			ids = derived
+		} else {
+			ids = intersectIdentities(ids, derived)
+		}
+	}
 	return ids
 }
 
@@ -2066,10 +2505,11 @@ func (c *Compiler) getPatterns(base schema.String, n parse.Node) [][]schema.Patt
 	ps := make([]schema.Pattern, 0, len(patterns))
 	for _, p := range patterns {
 		ps = append(ps, schema.Pattern{
-			Pattern: p.Argument().String(),
-			Regexp:  p.ArgPattern(),
-			Msg:     p.Msg(),
-			AppTag:  p.AppTag()})
+			Pattern:     p.Argument().String(),
+			Regexp:      p.ArgPattern(),
+			Msg:         p.Msg(),
+			AppTag:      p.AppTag(),
+			InvertMatch: p.InvertMatch()})
 	}
 
 	return append(pats, ps)
@@ -2213,11 +2653,118 @@ func (comp *Compiler) makeString(
 
 func (c *Compiler) makeBits(n parse.Node, b schema.Bits) schema.Type {
 	c.validateRestrictions(n, b, SchemaBits)
-	//TODO(jhs): Implement bits
-	if b == nil {
-		return schema.NewBits(nil)
+	//TODO(jhs): Implement bits validation
+	return schema.NewBits(c.getBits(b, n))
+}
+
+func (c *Compiler) getBits(base schema.Bits, node parse.Node) []*schema.Bit {
+
+	bitNodes := node.ChildrenByType(parse.NodeBit)
+	if base != nil {
+		if len(bitNodes) > 0 {
+			return c.restrictBits(base, bitNodes)
+		}
+		return base.Bits()
+	}
+
+	if len(bitNodes) == 0 {
+		c.error(node, errors.New("bits requires at least one bit"))
+	}
+
+	// Positions not explicitly assigned are one greater than the highest
+	// position assigned so far, starting at zero (RFC 7950 section 9.7.4.2).
+	seen := make(map[string]struct{}, len(bitNodes))
+	used := make(map[uint32]string, len(bitNodes))
+	var highest uint32
+	assigned := false
+	bits := make([]*schema.Bit, 0, len(bitNodes))
+	for _, bn := range bitNodes {
+		name := bn.Name()
+		if _, ok := seen[name]; ok {
+			c.error(bn, fmt.Errorf("duplicate bit %s", name))
+		}
+		seen[name] = struct{}{}
+
+		var pos uint32
+		switch p := bn.ChildByType(parse.NodePosition); {
+		case p != nil:
+			pos = uint32(p.ArgUint())
+		case !assigned:
+			pos = 0
+		case highest == math.MaxUint32:
+			c.error(bn, fmt.Errorf(
+				"bit %s requires an explicit position", name))
+		default:
+			pos = highest + 1
+		}
+		if other, ok := used[pos]; ok {
+			c.error(bn, fmt.Errorf(
+				"bit %s position %d already used by bit %s",
+				name, pos, other))
+		}
+		used[pos] = name
+		if !assigned || pos > highest {
+			highest, assigned = pos, true
+		}
+
+		// As with enums, a disabled bit still takes its position
+		if c.IgnoreNode(bn, schema.Current) {
+			continue
+		}
+		bits = append(bits, schema.NewBit(name, bn.Desc(), bn.Ref(),
+			c.getStatus(bn, schema.Current), pos))
+	}
+
+	return bits
+}
+
+// restrictBits builds the bits of a bits type derived from base. Each bit
+// must already exist in base, and keeps the position assigned there.
+func (c *Compiler) restrictBits(
+	base schema.Bits,
+	bitNodes []parse.Node,
+) []*schema.Bit {
+
+	baseBits := make(map[string]*schema.Bit, len(base.Bits()))
+	for _, b := range base.Bits() {
+		baseBits[b.Name] = b
+	}
+
+	seen := make(map[string]struct{}, len(bitNodes))
+	bits := make([]*schema.Bit, 0, len(bitNodes))
+	for _, bn := range bitNodes {
+		name := bn.Name()
+		baseBit, ok := baseBits[name]
+		if !ok {
+			c.error(bn, fmt.Errorf(
+				"bit %s is not defined in the base bits type", name))
+		}
+		if _, ok := seen[name]; ok {
+			c.error(bn, fmt.Errorf("duplicate bit %s", name))
+		}
+		seen[name] = struct{}{}
+		if p := bn.ChildByType(parse.NodePosition); p != nil &&
+			uint32(p.ArgUint()) != baseBit.Pos {
+			c.error(bn, fmt.Errorf(
+				"bit %s position %d does not match base position %d",
+				name, p.ArgUint(), baseBit.Pos))
+		}
+		if c.IgnoreNode(bn, baseBit.Status()) {
+			continue
+		}
+
+		desc, ref := bn.Desc(), bn.Ref()
+		if desc == "" {
+			desc = baseBit.Desc
+		}
+		if ref == "" {
+			ref = baseBit.Ref
+		}
+		bits = append(bits, schema.NewBit(name, desc, ref,
+			c.getStatus(bn, baseBit.Status()), baseBit.Pos))
 	}
-	return b
+
+	return bits
 }
 
 func (c *Compiler) validateRestrictions(n parse.Node, typ schema.Type, schemaType SchemaType) {
@@ -2292,6 +2839,8 @@ func (c *Compiler) refineType(cfgNode, n parse.Node, tname xml.Name, typ schema.
 		typ = c.makeEmpty(tname, n, t, def, hasDef)
 	case schema.Enumeration:
 		typ = c.makeEnumeration(tname, n, t, def, hasDef)
+	case schema.Bits:
+		typ = c.makeBits(n, t)
 	case schema.InstanceId:
 		typ = c.makeInstanceId(tname, n, t, def, hasDef)
 	case schema.Integer:
@@ -2432,7 +2981,7 @@ func YangModulesFromDir(dir string) ([]string, error) {
 	}
 	fnames := make([]string, 0)
 	for _, name := range names {
-		if !strings.HasSuffix(name, ".yang") {
+		if _, ok := moduleFileName(name); !ok {
 			continue
 		}
 		fname := dir + "/" + name
@@ -2446,34 +2995,52 @@ func ParseModuleDir(dir string, extCard parse.NodeCardinality) (map[string]*pars
 }
 
 func ParseModules(extCard parse.NodeCardinality, list ...string) (map[string]*parse.Tree, error) {
+	return ParseYang(extCard, YangFiles(list...))
+}
+
+// ParseYang parses every file in the repository.
+func ParseYang(extCard parse.NodeCardinality, repo ModuleRepository) (map[string]*parse.Tree, error) {
+	sources, err := repo.Modules()
+	if err != nil {
+		return nil, err
+	}
+
 	modules := make(map[string]*parse.Tree)
 	stringInterner := parse.NewStringInterner()
 	argInterner := parse.NewArgInterner()
-	for _, fname := range list {
-		text, err := ioutil.ReadFile(fname)
-		if err != nil && err != io.EOF {
-			return nil, err
-		}
-		t, err := parse.ParseWithInterners(
-			fname, string(text), extCard, stringInterner, argInterner)
+	for _, src := range sources {
+		t, err := parseModuleSource(src, repo, extCard, stringInterner, argInterner)
 		if err != nil {
 			return nil, err
 		}
-		mod := t.Root.Argument().String()
-		if n, ok := modules[mod]; ok {
-			return nil, errors.New("module " + mod + " is already defined by file " + n.ParseName)
+		if err := addModuleTree(modules, t); err != nil {
+			return nil, err
 		}
-		modules[mod] = t
 	}
 	return modules, nil
 }
 
-func ParseYang(extCard parse.NodeCardinality, locator YangLocator) (map[string]*parse.Tree, error) {
-	yangfiles, err := locator()
-	if err != nil {
-		return nil, err
+// The latest revision of a module is keyed by the module name, and any
+// other revisions by parse.RevisionKey().
+func addModuleTree(modules map[string]*parse.Tree, t *parse.Tree) error {
+	mod := t.Root.Argument().String()
+	rev := t.Root.Revision()
+	if n, ok := modules[mod]; ok && n.Root.Revision() < rev {
+		modules[parse.RevisionKey(mod, n.Root.Revision())] = n
+		delete(modules, mod)
 	}
-	return ParseModules(extCard, yangfiles...)
+	key := mod
+	if n, ok := modules[mod]; ok {
+		if n.Root.Revision() == rev {
+			return errors.New("module " + mod + " is already defined by file " + n.ParseName)
+		}
+		key = parse.RevisionKey(mod, rev)
+	}
+	if n, ok := modules[key]; ok {
+		return errors.New("module " + mod + " is already defined by file " + n.ParseName)
+	}
+	modules[key] = t
+	return nil
 }
 
 func getMods(extensions Extensions, cfg *Config,
@@ -2483,8 +3050,7 @@ func getMods(extensions Extensions, cfg *Config,
 	if extensions != nil {
 		ext_card = extensions.NodeCardinality
 	}
-	return ParseYang(ext_card,
-		YangLocations(YangDirs(cfg.YangDir), cfg.YangLocations))
+	return ParseYang(ext_card, cfg.repository())
 }
 
 type YangLocator func() ([]string, error)
@@ -2543,7 +3109,7 @@ func CompileDir(extensions Extensions, cfg *Config,
 		modules, submodules := parse.GetModulesAndSubmodules(mods)
 		ms, _, err := compileInternal(extensions, modules, submodules,
 			cfg.features(), cfg.SkipUnknown, dontGenWarnings, cfg.Filter,
-			cfg.UserFnCheckFn)
+			cfg.UserFnCheckFn, cfg.CollectErrors)
 		return ms, err
 	} else {
 		return nil, err
@@ -2557,7 +3123,7 @@ func CompileDirWithWarnings(extensions Extensions, cfg *Config,
 		modules, submodules := parse.GetModulesAndSubmodules(mods)
 		return compileInternal(extensions, modules, submodules,
 			cfg.features(), cfg.SkipUnknown, genWarnings, cfg.Filter,
-			cfg.UserFnCheckFn)
+			cfg.UserFnCheckFn, cfg.CollectErrors)
 	} else {
 		return nil, nil, err
 	}
@@ -2570,7 +3136,7 @@ func CompileDirKeepMods(extensions Extensions, cfg *Config,
 		modules, submodules := parse.GetModulesAndSubmodules(mods)
 		st, _, retErr := compileInternal(extensions, modules, submodules,
 			cfg.features(), cfg.SkipUnknown,
-			dontGenWarnings, cfg.Filter, cfg.UserFnCheckFn)
+			dontGenWarnings, cfg.Filter, cfg.UserFnCheckFn, cfg.CollectErrors)
 
 		return st, retErr, modules, submodules
 	} else {
@@ -2590,7 +3156,7 @@ func CompileModules(
 
 	ms, _, err := compileInternal(extensions, modules, submodules,
 		FeaturesFromLocations(true, features), skipUnknown, dontGenWarnings,
-		filter, nil)
+		filter, nil, false)
 	return ms, err
 }
 
@@ -2606,7 +3172,7 @@ func CompileModulesWithWarnings(
 
 	return compileInternal(extensions, modules, submodules,
 		FeaturesFromLocations(true, features), skipUnknown, genWarnings,
-		filter, nil)
+		filter, nil, false)
 }
 
 func CompileModulesWithWarningsAndCustomFunctions(
@@ -2622,7 +3188,7 @@ func CompileModulesWithWarningsAndCustomFunctions(
 
 	return compileInternal(extensions, modules, submodules,
 		FeaturesFromLocations(true, features), skipUnknown, genWarnings,
-		filter, userFnChecker)
+		filter, userFnChecker, false)
 }
 
 func CompileParseTrees(
@@ -2636,7 +3202,7 @@ func CompileParseTrees(
 	modules, submodules := parse.GetModulesAndSubmodules(mods)
 
 	ms, _, err := compileInternal(extensions, modules, submodules,
-		features, skipUnknown, dontGenWarnings, filter, nil)
+		features, skipUnknown, dontGenWarnings, filter, nil, false)
 	return ms, err
 }
 
@@ -2665,11 +3231,13 @@ func compileInternal(
 	generateWarnings bool,
 	filter SchemaFilter,
 	userFnChecker xpath.UserCustomFunctionCheckerFn,
+	collectErrors bool,
 ) (schema.ModelSet, []xutils.Warning, error) {
 
 	c := NewCompiler(extensions, modules, submodules, features,
 		skipUnknown, generateWarnings, filter).
-		addCustomFnChecker(userFnChecker)
+		addCustomFnChecker(userFnChecker).
+		collectAllErrors(collectErrors)
 
 	err := c.ExpandModules()
 	if err != nil {
@@ -2680,6 +3248,9 @@ func compileInternal(
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := c.collectedErrors(); err != nil {
+		return nil, nil, err
+	}
 
 	ms, err := schema.NewModelSet(moduleSchemas,
 		convertSubmodules(moduleSchemas, submodules))
@@ -25,9 +25,11 @@
 package compile_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/danos/mgmterror/errtest"
+	"github.com/sdcio/yang-parser/data/datanode"
 	"github.com/sdcio/yang-parser/schema"
 	"github.com/sdcio/yang-parser/testutils"
 )
@@ -1252,3 +1254,105 @@ func TestInstanceIdentifierDefault(t *testing.T) {
 func TestLeafRefDefault(t *testing.T) {
 	t.Skipf("TBD: Default Test for leafref.")
 }
+
+func TestLeafListDefaultFail(t *testing.T) {
+	var LeafListDefaultFailTests = []testutils.TestCase{
+		{
+			Description: "Leaf-list: default not valid for type",
+			Template:    ContainerTemplate,
+			Schema: `leaf-list testleaflist {
+				type uint8;
+				default 1;
+				default 300;
+			}`,
+			ExpResult: false,
+			ExpErrMsg: "Invalid default '300'",
+		},
+		{
+			Description: "Leaf-list: default with min-elements",
+			Template:    ContainerTemplate,
+			Schema: `leaf-list testleaflist {
+				type uint8;
+				min-elements 1;
+				default 1;
+			}`,
+			ExpResult: false,
+			ExpErrMsg: "leaf-list cannot have default and min-elements greater than 0",
+		},
+		{
+			Description: "Leaf-list: duplicate config default",
+			Template:    ContainerTemplate,
+			Schema: `leaf-list testleaflist {
+				type uint8;
+				default 1;
+				default 1;
+			}`,
+			ExpResult: false,
+			ExpErrMsg: "Duplicate default '1'",
+		},
+	}
+	runTestCases(t, LeafListDefaultFailTests)
+}
+
+func TestLeafListDefaults(t *testing.T) {
+	st := buildSchema(t, `
+	typedef port {
+		type uint16;
+		default 22;
+	}
+	container testContainer {
+		leaf-list ports {
+			type port;
+			default 80;
+			default 443;
+			default 8080;
+		}
+		leaf-list ssh-ports {
+			type port;
+		}
+		leaf-list no-default-ports {
+			type port;
+			min-elements 1;
+		}
+	}`)
+
+	cont := st.Child("testContainer")
+	for name, exp := range map[string][]string{
+		"ports":            {"80", "443", "8080"},
+		"ssh-ports":        {"22"},
+		"no-default-ports": nil,
+	} {
+		actual := cont.Child(name).(schema.LeafList).Defaults()
+		if !reflect.DeepEqual(actual, exp) {
+			t.Errorf("%s: expected defaults %v, got %v", name, exp, actual)
+		}
+	}
+
+	dn := schema.AddDefaults(cont,
+		datanode.CreateDataNode("testContainer", nil, nil))
+	values := make(map[string][]string)
+	for _, ch := range dn.YangDataChildren() {
+		values[ch.YangDataName()] = ch.YangDataValues()
+	}
+	if !reflect.DeepEqual(values["ports"], []string{"80", "443", "8080"}) {
+		t.Errorf("Unexpected ports defaults: %v", values["ports"])
+	}
+	if !reflect.DeepEqual(values["ssh-ports"], []string{"22"}) {
+		t.Errorf("Unexpected ssh-ports defaults: %v", values["ssh-ports"])
+	}
+	if _, ok := values["no-default-ports"]; ok {
+		t.Errorf("Unexpected default for no-default-ports")
+	}
+
+	configured := datanode.CreateDataNode("testContainer",
+		[]datanode.DataNode{
+			datanode.CreateDataNode("ports", nil, []string{"8443"}),
+		}, nil)
+	for _, ch := range schema.AddDefaults(cont, configured).YangDataChildren() {
+		if ch.YangDataName() == "ports" &&
+			!reflect.DeepEqual(ch.YangDataValues(), []string{"8443"}) {
+			t.Errorf("Defaults should not be added to configured leaf-list: %v",
+				ch.YangDataValues())
+		}
+	}
+}
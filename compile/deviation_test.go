@@ -20,8 +20,10 @@
 package compile_test
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/sdcio/yang-parser/schema"
 	"github.com/sdcio/yang-parser/testutils"
 )
 
@@ -1062,3 +1064,74 @@ func TestDeviateUnknownStatement(t *testing.T) {
 	}
 	runDeviateTestCases(t, tc)
 }
+
+func TestDeviateLeafListDefaults(t *testing.T) {
+	remote := testutils.TestSchema{
+		Name: testutils.NameDef{
+			Namespace: "prefix-remote",
+			Prefix:    "remote",
+		},
+		SchemaSnippet: `
+		container remotecontainer {
+			leaf-list replaced {
+				type uint8;
+				default 1;
+				default 2;
+				default 3;
+			}
+			leaf-list added {
+				type uint8;
+				default 1;
+			}
+			leaf-list deleted {
+				type uint8;
+				default 1;
+				default 2;
+			}
+		}`,
+	}
+	local := testutils.TestSchema{
+		Name: testutils.NameDef{
+			Namespace: "prefix-test",
+			Prefix:    "test",
+		},
+		Imports: []testutils.NameDef{
+			{"prefix-remote", "remote"}},
+		SchemaSnippet: `
+		deviation /remote:remotecontainer/remote:replaced {
+			deviate replace {
+				default 7;
+			}
+		}
+		deviation /remote:remotecontainer/remote:added {
+			deviate add {
+				default 2;
+				default 3;
+			}
+		}
+		deviation /remote:remotecontainer/remote:deleted {
+			deviate delete {
+				default 1;
+			}
+		}`,
+	}
+
+	st, err := testutils.GetConfigSchema(
+		[]byte(testutils.ConstructSchema(local)),
+		[]byte(testutils.ConstructSchema(remote)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	cont := st.Child("remotecontainer")
+	for name, exp := range map[string][]string{
+		"replaced": {"7"},
+		"added":    {"1", "2", "3"},
+		"deleted":  {"2"},
+	} {
+		actual := cont.Child(name).(schema.LeafList).Defaults()
+		if !reflect.DeepEqual(actual, exp) {
+			t.Errorf("%s: expected defaults %v, got %v", name, exp, actual)
+		}
+	}
+}
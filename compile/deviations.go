@@ -241,7 +241,11 @@ func (n *deviateReplace) propertyAction(target, property parse.Node) error {
 	if len(ch) == 0 {
 		return fmt.Errorf("Only existing proprties can be replaced by deviation")
 	}
-	target.ReplaceChildByType(property.Type(), property)
+	target.ReplaceChild(ch[0], property)
+	// A leaf-list may have several defaults, all of which are replaced
+	for _, old := range ch[1:] {
+		target.ReplaceChild(old)
+	}
 	return nil
 }
 
@@ -251,47 +255,51 @@ func (c *Compiler) processDeviations(module *parse.Module) {
 
 	children := nod.ChildrenByType(parse.NodeDeviation)
 	for _, a := range children {
-		applyToPath := a.ArgSchema()
-		applyToPfx := applyToPath[0].Space
-		applyToMod, err := nod.GetModuleByPrefix(
-			applyToPfx, c.modules, c.skipUnknown)
-		if err != nil {
-			c.error(nod, err)
-		}
+		c.collect(func() { c.processDeviation(nod, a) })
+	}
+}
 
-		allowedNodes := getAugmentableNodesForModule(applyToMod)
-		applyToNode := c.getDataDescendant(
-			a, allowedNodes, applyToPath, func(dst parse.Node) {})
+func (c *Compiler) processDeviation(nod, a parse.Node) {
+	applyToPath := a.ArgSchema()
+	applyToPfx := applyToPath[0].Space
+	applyToMod, err := nod.GetModuleByPrefix(
+		applyToPfx, c.modules, c.skipUnknown)
+	if err != nil {
+		c.error(nod, err)
+	}
 
-		if applyToNode == nil {
-			c.error(a, fmt.Errorf("Invalid path: %s",
-				xmlPathString(applyToPath)))
-		}
+	allowedNodes := getAugmentableNodesForModule(applyToMod)
+	applyToNode := c.getDataDescendant(
+		a, allowedNodes, applyToPath, func(dst parse.Node) {})
 
-		devs := a.ChildrenByType(parse.NodeDeviate)
+	if applyToNode == nil {
+		c.error(a, fmt.Errorf("Invalid path: %s",
+			xmlPathString(applyToPath)))
+	}
 
-		for _, d := range devs {
-			switch d.Type() {
-			case parse.NodeDeviateNotSupported:
-				if len(devs) > 1 {
-					c.error(a, fmt.Errorf("No other deviate statements allowed with not-supported"))
-				}
-				c.doDeviate(applyToNode, d, &deviateNotSupported{})
+	devs := a.ChildrenByType(parse.NodeDeviate)
 
-			case parse.NodeDeviateDelete:
-				c.doDeviate(applyToNode, d, &deviateDelete{})
+	for _, d := range devs {
+		switch d.Type() {
+		case parse.NodeDeviateNotSupported:
+			if len(devs) > 1 {
+				c.error(a, fmt.Errorf("No other deviate statements allowed with not-supported"))
+			}
+			c.doDeviate(applyToNode, d, &deviateNotSupported{})
 
-			case parse.NodeDeviateAdd:
-				c.doDeviate(applyToNode, d, &deviateAdd{})
+		case parse.NodeDeviateDelete:
+			c.doDeviate(applyToNode, d, &deviateDelete{})
 
-			case parse.NodeDeviateReplace:
-				c.doDeviate(applyToNode, d, &deviateReplace{})
-			}
-		}
-		if len(devs) > 0 {
-			c.addDeviation(applyToNode.GetNodeModulename(applyToMod), nod.Name())
+		case parse.NodeDeviateAdd:
+			c.doDeviate(applyToNode, d, &deviateAdd{})
+
+		case parse.NodeDeviateReplace:
+			c.doDeviate(applyToNode, d, &deviateReplace{})
 		}
 	}
+	if len(devs) > 0 {
+		c.addDeviation(applyToNode.GetNodeModulename(applyToMod), nod.Name())
+	}
 }
 
 func (c *Compiler) doDeviate(target, deviate parse.Node, dp deviateProcessor) {
@@ -0,0 +1,318 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sdcio/yang-parser/parse"
+	"github.com/sdcio/yang-parser/schema"
+	"github.com/sdcio/yang-parser/xpath/xutils"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Codes for the xpath warnings generated by CompileDirWithWarnings
+const (
+	WarnXpathPath        ErrorCode = "xpath-path"
+	WarnXpathPrefix      ErrorCode = "xpath-prefix"
+	WarnXpathNPContainer ErrorCode = "xpath-np-container"
+)
+
+// Location is a position in a YANG file. As in error messages, Column is
+// the offset within the line, so the first column is zero. In JSON the
+// column is given whenever the line is.
+type Location struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (l Location) MarshalJSON() ([]byte, error) {
+	type location Location
+	return json.Marshal(struct {
+		location
+		Column *int `json:"column,omitempty"`
+	}{location(l), lineColumn(l.Line, l.Column)})
+}
+
+// lineColumn returns the column to encode, so that column zero is kept
+// whenever there is a line.
+func lineColumn(line, col int) *int {
+	if line == 0 && col == 0 {
+		return nil
+	}
+	return &col
+}
+
+func nodeLocation(n parse.Node, msg string) Location {
+	file, line, col := n.Location()
+	return Location{File: file, Line: line, Column: col, Message: msg}
+}
+
+// Diagnostic describes an error or warning found in a YANG module. Path
+// holds the statements leading from the module to the one at fault, or for
+// an xpath warning the schema node the xpath statement is on. Related
+// holds other locations involved, such as the uses of a grouping in which
+// an error was found.
+type Diagnostic struct {
+	Severity  Severity   `json:"severity"`
+	Code      ErrorCode  `json:"code"`
+	Module    string     `json:"module,omitempty"`
+	File      string     `json:"file,omitempty"`
+	Line      int        `json:"line,omitempty"`
+	Column    int        `json:"column,omitempty"`
+	Statement string     `json:"statement,omitempty"`
+	Path      []string   `json:"path,omitempty"`
+	Message   string     `json:"message"`
+	Related   []Location `json:"related,omitempty"`
+}
+
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	type diagnostic Diagnostic
+	return json.Marshal(struct {
+		diagnostic
+		Column *int `json:"column,omitempty"`
+	}{diagnostic(d), lineColumn(d.Line, d.Column)})
+}
+
+type Diagnostics []Diagnostic
+
+// DiagnosticsFromError returns the diagnostics for an error returned when
+// compiling, one for each error when errors were collected.
+func DiagnosticsFromError(err error) Diagnostics {
+	if err == nil {
+		return nil
+	}
+	var errs CompileErrors
+	if errors.As(err, &errs) {
+		diags := make(Diagnostics, 0, len(errs))
+		for _, e := range errs {
+			diags = append(diags, e.Diagnostic)
+		}
+		return diags
+	}
+	return Diagnostics{asCompileError(err).Diagnostic}
+}
+
+// DiagnosticFromWarning returns the diagnostic for an xpath warning. The
+// warning only records the file and line of the xpath statement.
+func DiagnosticFromWarning(w xutils.Warning) Diagnostic {
+	d := Diagnostic{
+		Severity:  SeverityWarning,
+		Code:      warningCode(w.GetType()),
+		Statement: w.GetXpathStmt(),
+		Path:      []string{w.GetStartNode()},
+	}
+	if w.GetTestPath() == "" || w.GetTestPath() == "(n/a)" {
+		d.Message = fmt.Sprintf("'%s' %s", w.GetXpathStmt(), w.GetType())
+	} else {
+		d.Message = fmt.Sprintf("'%s': path %s %s",
+			w.GetXpathStmt(), w.GetTestPath(), w.GetType())
+	}
+
+	loc := w.GetXpathLoc()
+	if idx := strings.LastIndex(loc, ":"); idx != -1 {
+		if line, err := strconv.Atoi(loc[idx+1:]); err == nil {
+			d.File, d.Line = loc[:idx], line
+		}
+	}
+	if d.File != "" {
+		d.Module, _ = moduleFileName(filepath.Base(d.File))
+		if idx := strings.Index(d.Module, "@"); idx != -1 {
+			d.Module = d.Module[:idx]
+		}
+	}
+	return d
+}
+
+func warningCode(t xutils.WarnType) ErrorCode {
+	switch t {
+	case xutils.DoesntExist:
+		return WarnXpathPath
+	case xutils.MissingOrWrongPrefix:
+		return WarnXpathPrefix
+	case xutils.MustOnNPContainer, xutils.MustOnNPContWithNPChild,
+		xutils.RefNPContainer:
+		return WarnXpathNPContainer
+	}
+	return ErrXpath
+}
+
+// CompileDirWithDiagnostics compiles as CompileDirWithWarnings does, but
+// reports both the errors and the xpath warnings as diagnostics. The model
+// set is nil if there are any errors.
+func CompileDirWithDiagnostics(extensions Extensions, cfg *Config,
+) (schema.ModelSet, Diagnostics) {
+	ms, warns, err := CompileDirWithWarnings(extensions, cfg)
+	diags := DiagnosticsFromError(err)
+	for _, w := range warns {
+		diags = append(diags, DiagnosticFromWarning(w))
+	}
+	return ms, diags
+}
+
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (d Diagnostics) JSON() ([]byte, error) {
+	if d == nil {
+		d = Diagnostics{}
+	}
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// The subset of SARIF 2.1.0 needed to report diagnostics
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                   `json:"id,omitempty"`
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+	Message          *sarifMessage          `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+func sarifPhysical(file string, line, col int) *sarifPhysicalLocation {
+	if file == "" {
+		return nil
+	}
+	loc := &sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(file)},
+	}
+	// SARIF counts columns from one
+	if line > 0 {
+		loc.Region = &sarifRegion{StartLine: line, StartColumn: col + 1}
+	}
+	return loc
+}
+
+// SARIF returns the diagnostics as a SARIF 2.1.0 log of a single run of
+// the named tool, for tools that annotate source changes.
+func (d Diagnostics) SARIF(tool string) ([]byte, error) {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: tool}},
+		Results: make([]sarifResult, 0, len(d)),
+	}
+
+	rules := make(map[ErrorCode]bool)
+	for _, diag := range d {
+		if !rules[diag.Code] {
+			rules[diag.Code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules,
+				sarifRule{ID: string(diag.Code)})
+		}
+
+		result := sarifResult{
+			RuleID:  string(diag.Code),
+			Level:   string(diag.Severity),
+			Message: sarifMessage{Text: diag.Message},
+		}
+		loc := sarifLocation{
+			PhysicalLocation: sarifPhysical(diag.File, diag.Line, diag.Column),
+		}
+		if len(diag.Path) > 0 {
+			loc.LogicalLocations = []sarifLogicalLocation{
+				{FullyQualifiedName: strings.Join(diag.Path, "/")}}
+		}
+		if loc.PhysicalLocation != nil || loc.LogicalLocations != nil {
+			result.Locations = []sarifLocation{loc}
+		}
+		for i, rel := range diag.Related {
+			id := i
+			result.RelatedLocations = append(result.RelatedLocations,
+				sarifLocation{
+					ID:               &id,
+					PhysicalLocation: sarifPhysical(rel.File, rel.Line, rel.Column),
+					Message:          &sarifMessage{Text: rel.Message},
+				})
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	return json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+}
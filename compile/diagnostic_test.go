@@ -0,0 +1,191 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/xpath/xutils"
+)
+
+const diagnosticsModule = `module diag {
+	namespace "urn:diag";
+	prefix diag;
+	grouping bad {
+		leaf value {
+			type uint8;
+			default 300;
+		}
+	}
+	container used {
+		uses bad;
+	}
+}`
+
+func compileDiagnostics(t *testing.T, text string) compile.Diagnostics {
+	dir := writeRevisionFiles(t, map[string]string{"diag.yang": text})
+	_, diags := compile.CompileDirWithDiagnostics(nil, &compile.Config{
+		YangDir:       dir,
+		Filter:        compile.IsConfig,
+		CollectErrors: true,
+	})
+	return diags
+}
+
+func TestDiagnosticRelatedUses(t *testing.T) {
+	diags := compileDiagnostics(t, diagnosticsModule)
+	if len(diags) != 1 || !diags.HasErrors() {
+		t.Fatalf("Expected a single error, got %v", diags)
+	}
+
+	d := diags[0]
+	if d.Severity != compile.SeverityError || d.Code != compile.ErrType ||
+		d.Module != "diag" || d.Line != 6 ||
+		!strings.HasSuffix(d.File, "diag.yang") {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+	if len(d.Related) != 1 || d.Related[0].Line != 11 ||
+		d.Related[0].Message != "used from uses bad" {
+		t.Errorf("Expected uses site as related location, got %+v",
+			d.Related)
+	}
+}
+
+func TestDiagnosticFromWarning(t *testing.T) {
+	d := compile.DiagnosticFromWarning(xutils.NewWarning(
+		xutils.DoesntExist, "/checked", "/missing", "diag@2024-01-01.yang:15",
+		"/urn:diag:missing", ""))
+
+	expected := compile.Diagnostic{
+		Severity:  compile.SeverityWarning,
+		Code:      compile.WarnXpathPath,
+		Module:    "diag",
+		File:      "diag@2024-01-01.yang",
+		Line:      15,
+		Statement: "/missing",
+		Path:      []string{"/checked"},
+		Message:   "'/missing': path /urn:diag:missing doesn't exist",
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("Unexpected diagnostic\n  exp: %+v\n  got: %+v",
+			expected, d)
+	}
+}
+
+func TestDiagnosticsJSON(t *testing.T) {
+	diags := compileDiagnostics(t, diagnosticsModule)
+	out, err := diags.JSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %s\n%s", err, out)
+	}
+	if len(decoded) != 1 || decoded[0]["severity"] != "error" ||
+		decoded[0]["code"] != "type" || decoded[0]["line"] != 6.0 {
+		t.Errorf("Unexpected JSON output:\n%s", out)
+	}
+}
+
+func TestDiagnosticsJSONColumnZero(t *testing.T) {
+	out, err := compile.Diagnostics{{
+		Severity: compile.SeverityWarning,
+		Code:     compile.LintDescription,
+		File:     "diag.yang",
+		Line:     1,
+		Message:  "module diag has no description",
+		Related:  []compile.Location{{File: "diag.yang", Line: 2}, {}},
+	}}.JSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %s\n%s", err, out)
+	}
+	related, _ := decoded[0]["related"].([]interface{})
+	if col, ok := decoded[0]["column"]; !ok || col != 0.0 || len(related) != 2 {
+		t.Fatalf("Expected column 0 in JSON output:\n%s", out)
+	}
+	if _, ok := related[0].(map[string]interface{})["column"]; !ok {
+		t.Errorf("Expected column 0 in related location:\n%s", out)
+	}
+	if _, ok := related[1].(map[string]interface{})["column"]; ok {
+		t.Errorf("Unexpected column without line:\n%s", out)
+	}
+}
+
+func TestDiagnosticsSARIF(t *testing.T) {
+	diags := compileDiagnostics(t, diagnosticsModule)
+	out, err := diags.SARIF("yangc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						Region struct {
+							StartLine   int
+							StartColumn int
+						}
+					}
+				}
+				RelatedLocations []struct {
+					Message struct{ Text string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(out, &log); err != nil {
+		t.Fatalf("Invalid SARIF: %s\n%s", err, out)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected SARIF log:\n%s", out)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "yangc" || len(run.Results) != 1 ||
+		len(run.Tool.Driver.Rules) != 1 ||
+		run.Tool.Driver.Rules[0].ID != "type" {
+		t.Fatalf("Unexpected SARIF run:\n%s", out)
+	}
+	res := run.Results[0]
+	if res.RuleID != "type" || res.Level != "error" ||
+		len(res.Locations) != 1 || len(res.RelatedLocations) != 1 {
+		t.Fatalf("Unexpected SARIF result:\n%s", out)
+	}
+	region := res.Locations[0].PhysicalLocation.Region
+	if region.StartLine != 6 || region.StartColumn != diags[0].Column+1 {
+		t.Errorf("Unexpected region %+v", region)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/sdcio/yang-parser/parse"
+)
+
+// ErrorCode classifies a compile error by the kind of statement at fault.
+// Codes are stable, so may be used to filter or suppress errors.
+type ErrorCode string
+
+const (
+	ErrCompile   ErrorCode = "compile"
+	ErrStatement ErrorCode = "statement"
+	ErrImport    ErrorCode = "import"
+	ErrType      ErrorCode = "type"
+	ErrDefault   ErrorCode = "default"
+	ErrGrouping  ErrorCode = "grouping"
+	ErrAugment   ErrorCode = "augment"
+	ErrDeviation ErrorCode = "deviation"
+	ErrXpath     ErrorCode = "xpath"
+	ErrFeature   ErrorCode = "feature"
+	ErrIdentity  ErrorCode = "identity"
+	ErrKey       ErrorCode = "key"
+	ErrStatus    ErrorCode = "status"
+)
+
+func errorCode(n parse.Node) ErrorCode {
+	switch t := n.Type(); {
+	case t == parse.NodeImport, t == parse.NodeInclude,
+		t == parse.NodeBelongsTo, t == parse.NodeRevisionDate:
+		return ErrImport
+	case t == parse.NodeTypedef, t.IsTypeRestriction(), t == parse.NodeBase,
+		t == parse.NodeValue, t == parse.NodePosition,
+		t == parse.NodeModifier:
+		return ErrType
+	case t == parse.NodeDefault:
+		return ErrDefault
+	case t == parse.NodeGrouping, t == parse.NodeUses, t == parse.NodeRefine:
+		return ErrGrouping
+	case t == parse.NodeAugment, t == parse.NodeOpdAugment:
+		return ErrAugment
+	case t == parse.NodeDeviation, t.IsDeviateNode():
+		return ErrDeviation
+	case t == parse.NodeMust, t == parse.NodeWhen,
+		t == parse.NodeConfigdMust:
+		return ErrXpath
+	case t == parse.NodeFeature, t == parse.NodeIfFeature:
+		return ErrFeature
+	case t == parse.NodeIdentity:
+		return ErrIdentity
+	case t == parse.NodeKey, t == parse.NodeUnique:
+		return ErrKey
+	case t == parse.NodeStatus:
+		return ErrStatus
+	}
+	return ErrStatement
+}
+
+// CompileError is an error found in a YANG statement.
+type CompileError struct {
+	Diagnostic
+}
+
+func (e *CompileError) Error() string {
+	if e.Statement == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s",
+		e.File, e.Line, e.Column, e.Statement, e.Message)
+}
+
+// CompileErrors is returned when Config.CollectErrors is set, holding each
+// error found in the order found.
+type CompileErrors []*CompileError
+
+func (e CompileErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (c *Compiler) newCompileError(n parse.Node, err error) *CompileError {
+	file, line, col := n.Location()
+	var module string
+	if root := n.Root(); root != nil {
+		module = root.Name()
+	}
+	path, related := c.statementPath(n)
+	return &CompileError{Diagnostic{
+		Severity:  SeverityError,
+		Code:      errorCode(n),
+		Module:    module,
+		File:      file,
+		Line:      line,
+		Column:    col,
+		Statement: n.String(),
+		Path:      path,
+		Message:   err.Error(),
+		Related:   related,
+	}}
+}
+
+// Errors not raised against a statement, such as a cycle in the imports,
+// are still reported, just without a location.
+func asCompileError(err error) *CompileError {
+	var cerr *CompileError
+	if errors.As(err, &cerr) {
+		return cerr
+	}
+	return &CompileError{Diagnostic{
+		Severity: SeverityError,
+		Code:     ErrCompile,
+		Message:  err.Error(),
+	}}
+}
+
+// statementPath returns the statements leading from the module to n, and
+// the location of each uses that copied n, or one of its ancestors, from
+// a grouping. Statements copied from a grouping or augment may be found in
+// any of the modules, so all of them are searched.
+func (c *Compiler) statementPath(n parse.Node) ([]string, []Location) {
+	names := make([]string, 0, len(c.modules)+len(c.submodules))
+	roots := make(map[string]parse.Node, cap(names))
+	for name, m := range c.modules {
+		names, roots[name] = append(names, name), m.GetModule()
+	}
+	for name, m := range c.submodules {
+		names, roots[name] = append(names, name), m.GetModule()
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		found := findStatement(roots[name], n)
+		if found == nil {
+			continue
+		}
+		path := make([]string, 0, len(found))
+		var related []Location
+		for _, stmt := range found {
+			path = append(path, stmt.String())
+			if use, ok := c.usesSites[stmt]; ok {
+				related = append(related,
+					nodeLocation(use, "used from "+use.String()))
+			}
+		}
+		return path, related
+	}
+	return nil, nil
+}
+
+func findStatement(from, n parse.Node) []parse.Node {
+	if from == n {
+		return []parse.Node{from}
+	}
+	for _, ch := range from.Children() {
+		if path := findStatement(ch, n); path != nil {
+			return append([]parse.Node{from}, path...)
+		}
+	}
+	return nil
+}
+
+// collect runs fn. When collecting errors, an error raised by fn is
+// recorded and false returned, so that the caller may carry on with the
+// parts of the schema that don't depend on whatever failed.
+func (c *Compiler) collect(fn func()) (ok bool) {
+	if !c.collectErrors {
+		fn()
+		return true
+	}
+
+	defer func() {
+		e := recover()
+		if e == nil {
+			return
+		}
+		if _, isRuntime := e.(runtime.Error); isRuntime {
+			panic(e)
+		}
+		c.addError(asCompileError(e.(error)))
+		ok = false
+	}()
+	fn()
+	return true
+}
+
+// The same fault may be found more than once, for instance a bad typedef
+// used by several leaves, but is only reported once.
+func (c *Compiler) addError(err *CompileError) {
+	for _, e := range c.errs {
+		if e.Error() == err.Error() {
+			return
+		}
+	}
+	c.errs = append(c.errs, err)
+}
+
+func (c *Compiler) collectedErrors() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
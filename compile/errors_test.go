@@ -0,0 +1,115 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+)
+
+const manyErrorsModule = `module many {
+	namespace "urn:many";
+	prefix many;
+	typedef bad-range {
+		type uint8 {
+			range "1..300";
+		}
+	}
+	container one {
+		leaf bad-default {
+			type uint8;
+			default 256;
+		}
+		leaf good {
+			type string;
+		}
+	}
+	container two {
+		uses missing;
+		leaf good {
+			type string;
+		}
+	}
+	container three {
+		leaf bad-typedef {
+			type bad-range;
+		}
+		leaf also-bad-typedef {
+			type bad-range;
+		}
+	}
+}`
+
+func compileManyErrors(t *testing.T, collect bool) error {
+	dir := writeRevisionFiles(t, map[string]string{
+		"many.yang": manyErrorsModule,
+	})
+	_, err := compile.CompileDir(nil, &compile.Config{
+		YangDir:       dir,
+		Filter:        compile.IsConfig,
+		CollectErrors: collect,
+	})
+	return err
+}
+
+func TestCompileStopsAtFirstError(t *testing.T) {
+	err := compileManyErrors(t, false)
+	var errs compile.CompileErrors
+	if err == nil || errors.As(err, &errs) {
+		t.Fatalf("Expected a single error, got %v", err)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	err := compileManyErrors(t, true)
+	var errs compile.CompileErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected collected errors, got %v", err)
+	}
+
+	expected := []struct {
+		code      compile.ErrorCode
+		line      int
+		statement string
+		message   string
+	}{
+		{compile.ErrGrouping, 19, "uses missing",
+			"Unknown grouping (grouping missing) referenced from two"},
+		{compile.ErrType, 11, "type uint8",
+			"Invalid default '256'"},
+		{compile.ErrType, 5, "type uint8",
+			"derived type range must be restrictive"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s",
+			len(expected), len(errs), err)
+	}
+	for i, exp := range expected {
+		got := errs[i]
+		if got.Code != exp.code || got.Line != exp.line ||
+			got.Statement != exp.statement {
+			t.Errorf("Error %d: expected %s at line %d in %q, got %s at "+
+				"line %d in %q", i, exp.code, exp.line, exp.statement,
+				got.Code, got.Line, got.Statement)
+		}
+		assertErrorContains(t, got, exp.message)
+		if len(got.Path) < 3 || got.Path[0] != "module many" ||
+			got.Path[len(got.Path)-1] != exp.statement {
+			t.Errorf("Error %d: unexpected statement path %v", i, got.Path)
+		}
+	}
+}
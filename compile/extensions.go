@@ -50,6 +50,10 @@ type Extensions interface {
 	// as trees
 	ExtendRpc(parse.Node, schema.Rpc) (schema.Rpc, error)
 
+	// Extend the Action node, which like an RPC includes input and
+	// output parameters as trees
+	ExtendAction(parse.Node, schema.Action) (schema.Action, error)
+
 	// Extend the Notification node
 	ExtendNotification(parse.Node, schema.Notification) (schema.Notification, error)
 
@@ -62,6 +66,10 @@ type Extensions interface {
 	ExtendLeaf(parse.Node, schema.Leaf) (schema.Leaf, error)
 	ExtendLeafList(parse.Node, schema.LeafList) (schema.LeafList, error)
 
+	// Extend anydata and anyxml nodes
+	ExtendAnydata(parse.Node, schema.Anydata) (schema.Anydata, error)
+	ExtendAnyxml(parse.Node, schema.Anyxml) (schema.Anyxml, error)
+
 	// Extend choice and case nodes
 	ExtendChoice(parse.Node, schema.Choice) (schema.Choice, error)
 	ExtendCase(parse.Node, schema.Case) (schema.Case, error)
@@ -117,6 +125,21 @@ func (comp *Compiler) extendRpc(p parse.Node, r schema.Rpc) schema.Rpc {
 	return r2
 }
 
+func (comp *Compiler) extendAction(p parse.Node, a schema.Action) schema.Action {
+
+	if comp.extensions == nil {
+		return a
+	}
+
+	a2, e := comp.extensions.ExtendAction(p, a)
+	if e != nil {
+		comp.error(p, e)
+		return a
+	}
+
+	return a2
+}
+
 func (comp *Compiler) extendNotification(p parse.Node, n schema.Notification) schema.Notification {
 	if comp.extensions == nil {
 		return n
@@ -178,6 +201,36 @@ func (comp *Compiler) extendList(p parse.Node, l schema.List) schema.List {
 	return l2
 }
 
+func (comp *Compiler) extendAnydata(p parse.Node, a schema.Anydata) schema.Anydata {
+
+	if comp.extensions == nil {
+		return a
+	}
+
+	a2, e := comp.extensions.ExtendAnydata(p, a)
+	if e != nil {
+		comp.error(p, e)
+		return a
+	}
+
+	return a2
+}
+
+func (comp *Compiler) extendAnyxml(p parse.Node, a schema.Anyxml) schema.Anyxml {
+
+	if comp.extensions == nil {
+		return a
+	}
+
+	a2, e := comp.extensions.ExtendAnyxml(p, a)
+	if e != nil {
+		comp.error(p, e)
+		return a
+	}
+
+	return a2
+}
+
 func (comp *Compiler) extendLeaf(p parse.Node, l schema.Leaf) schema.Leaf {
 
 	if comp.extensions == nil {
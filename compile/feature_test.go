@@ -22,8 +22,12 @@
 package compile_test
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
 
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/schema"
 	. "github.com/sdcio/yang-parser/testutils"
 )
 
@@ -180,6 +184,27 @@ var ifFeaturePassTests = []TestCase{
 		}`,
 		ExpResult: true,
 	},
+	{
+		Description: "if-feature boolean expression",
+		Template:    BlankTemplate,
+		Schema: `feature foo;
+		feature bar;
+		feature baz;
+		container testcontainer {
+			if-feature "foo and (bar or not test:baz)";
+		}`,
+		ExpResult: true,
+	},
+	{
+		Description: "if-feature expression in feature",
+		Template:    BlankTemplate,
+		Schema: `feature foo;
+		feature bar;
+		feature baz {
+			if-feature "not foo or bar";
+		}`,
+		ExpResult: true,
+	},
 	{
 		Description: "if-feature supported in list",
 		Template:    BlankTemplate,
@@ -253,6 +278,48 @@ var ifFeatureFailTests = []TestCase{
 		ExpErrMsg: "if-feature bad-module:thirdtestfeature: " +
 			"unknown import bad-module",
 	},
+	{
+		Description: "if-feature expression with unknown feature",
+		Template:    BlankTemplate,
+		Schema: `feature foo;
+		container testcontainer {
+			if-feature "foo and not unknownfeature";
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "feature not valid: unknownfeature",
+	},
+	{
+		Description: "if-feature expression with missing parenthesis",
+		Template:    BlankTemplate,
+		Schema: `feature foo;
+		feature bar;
+		container testcontainer {
+			if-feature "(foo or bar";
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "if-feature: missing ')'",
+	},
+	{
+		Description: "if-feature expression with dangling operator",
+		Template:    BlankTemplate,
+		Schema: `feature foo;
+		container testcontainer {
+			if-feature "foo and";
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "invalid identifier",
+	},
+	{
+		Description: "if-feature expression with missing operator",
+		Template:    BlankTemplate,
+		Schema: `feature foo;
+		feature bar;
+		container testcontainer {
+			if-feature "foo bar";
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "if-feature: unexpected \"bar\"",
+	},
 	{
 		Description: "if-feature not allowed as a module substatement",
 		Template:    BlankTemplate,
@@ -280,3 +347,154 @@ func TestIfFeaturePass(t *testing.T) {
 func TestIfFeatureRejects(t *testing.T) {
 	runTestCases(t, ifFeatureFailTests)
 }
+
+func TestIfFeatureExpressionEvaluation(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`feature foo;
+		feature bar;
+		feature baz;
+		container both {
+			if-feature "foo and bar";
+		}
+		container either {
+			if-feature "foo or bar";
+		}
+		container not-baz {
+			if-feature "not baz";
+		}
+		container grouped {
+			if-feature "foo and (bar or not baz)";
+		}
+		container precedence {
+			if-feature "not foo and bar or baz";
+		}`))
+
+	features := compile.FeaturesFromNames(true, "test-yang-compile:foo")
+	st, err := GetConfigSchemaWithFeatures(features, schema_text.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := map[string]bool{
+		"both":       false,
+		"either":     true,
+		"not-baz":    true,
+		"grouped":    true,
+		"precedence": false,
+	}
+	for name, present := range expected {
+		if (st.Child(name) != nil) != present {
+			t.Errorf("Container %s: expected present %v", name, present)
+		}
+	}
+}
+
+func TestIfFeatureExpressionUnknownFeatureLocation(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`feature foo;
+		container testcontainer {
+			if-feature "foo or missing";
+		}`))
+
+	_, err := GetConfigSchema(schema_text.Bytes())
+	assertErrorContains(t, err,
+		"schema0:11:3:", "feature not valid: missing")
+}
+
+func TestIfFeatureOnTypeValues(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`feature foo;
+		feature bar;
+		identity base-id;
+		identity on-id {
+			base base-id;
+		}
+		identity off-id {
+			if-feature bar;
+			base base-id;
+		}
+		identity below-off-id {
+			base off-id;
+		}
+		leaf colour {
+			type enumeration {
+				enum red;
+				enum green {
+					if-feature bar;
+				}
+				enum blue {
+					if-feature foo;
+				}
+			}
+		}
+		leaf flags {
+			type bits {
+				bit up;
+				bit down {
+					if-feature bar;
+				}
+				bit left;
+			}
+		}
+		leaf ident {
+			type identityref {
+				base base-id;
+			}
+		}`))
+
+	features := compile.FeaturesFromNames(true, "test-yang-compile:foo")
+	st, err := GetConfigSchemaWithFeatures(features, schema_text.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	enums := st.Child("colour").Type().(schema.Enumeration).Enums()
+	if len(enums) != 2 || enums[0].Val != "red" || enums[1].Val != "blue" {
+		t.Fatalf("Unexpected enums: %v", enums)
+	}
+	if enums[1].Value != 2 {
+		t.Errorf("Enum blue has value %d, expected 2", enums[1].Value)
+	}
+
+	bits := st.Child("flags").Type().(schema.Bits).Bits()
+	if len(bits) != 2 || bits[1].Name != "left" || bits[1].Pos != 2 {
+		t.Errorf("Unexpected bits: %v", bits)
+	}
+
+	expected := map[string][]string{
+		"colour": {"red", "blue"},
+		"flags":  {"up", "left"},
+		"ident":  {"on-id"},
+	}
+	for name, allowed := range expected {
+		typ := st.Child(name).Type()
+		values, err := typ.AllowedValues(nil, false)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if fmt.Sprint(values) != fmt.Sprint(allowed) {
+			t.Errorf("Leaf %s allows %v, expected %v",
+				name, values, allowed)
+		}
+	}
+
+	rejected := map[string]string{
+		"colour": "green",
+		"flags":  "up down",
+		"ident":  "off-id",
+	}
+	for name, value := range rejected {
+		typ := st.Child(name).Type()
+		err := typ.Validate(nil, []string{name}, value)
+		if err == nil {
+			t.Errorf("Leaf %s accepted disabled value %s", name, value)
+		}
+	}
+	if err := st.Child("flags").Type().Validate(
+		nil, []string{"flags"}, "up left"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
@@ -19,7 +19,9 @@
 package compile
 
 import (
+	"io/fs"
 	"os"
+	"path"
 )
 
 type FeatureStatus int
@@ -84,38 +86,29 @@ func (f *featuresMap) getFeatures(location string, enable bool) {
 		// None defined
 		return
 	}
-	fi, err := os.Stat(location)
+	f.getFeaturesFS(os.DirFS(location), ".", enable)
+}
+
+// getFeaturesFS adds the features found in dir of fsys, laid out as for
+// getFeatures.
+func (f *featuresMap) getFeaturesFS(fsys fs.FS, dir string, enable bool) {
+	names, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		//  features does not exist
 		return
 	}
 
-	if fi.Mode().IsDir() {
-		d, err := os.Open(location)
-		if err != nil {
-			return
-		}
-		defer d.Close()
-
-		names, err := d.Readdir(0)
-		if err != nil {
-			return
-		}
-
-		for _, name := range names {
-			if name.IsDir() {
-				featDir, err := os.Open(location + "/" + name.Name())
-				features, err := featDir.Readdir(0)
-				if err != nil {
-					// Skip any problematic directories
-					continue
-				}
-				for _, feat := range features {
-					if !feat.IsDir() {
-						f.features[name.Name()+":"+feat.Name()] = enable
-					}
+	for _, name := range names {
+		if name.IsDir() {
+			features, err := fs.ReadDir(fsys, path.Join(dir, name.Name()))
+			if err != nil {
+				// Skip any problematic directories
+				continue
+			}
+			for _, feat := range features {
+				if !feat.IsDir() {
+					f.features[name.Name()+":"+feat.Name()] = enable
 				}
-				featDir.Close()
 			}
 		}
 	}
@@ -131,6 +124,16 @@ func FeaturesFromLocations(enabled bool, locations ...string) FeaturesChecker {
 	return &m
 }
 
+// FeaturesFromFS is FeaturesFromLocations for directories within fsys.
+func FeaturesFromFS(enabled bool, fsys fs.FS, dirs ...string) FeaturesChecker {
+	m := newFeaturesMap()
+	for _, d := range dirs {
+		m.getFeaturesFS(fsys, d, enabled)
+	}
+
+	return &m
+}
+
 func FeaturesFromNames(enabled bool, features ...string) FeaturesChecker {
 	f := make(map[string]bool)
 	m := featuresMap{features: f}
@@ -0,0 +1,1011 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file writes a module of a compiled model set back out as YANG. The
+// compiled schema has groupings expanded, typedefs resolved, features
+// pruned and deviations applied, so the module written needs none of
+// them, and can be read by tools that do not support them.
+
+package compile
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sdcio/yang-parser/parse"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+// WriteFlatModule writes the named module of ms as a YANG module that
+// has no groupings, typedefs, features or deviations. Nodes of the module
+// are written as compiled, with their types written in full, and the
+// nodes it adds to other modules are written as augments. The header,
+// such as the namespace and revisions, is copied from the module's YANG.
+//
+// Children are written in order of name, as the compiled schema does not
+// keep the order of the YANG statements. Statements the compiled schema
+// does not keep, such as 'reference' and the text of 'presence', are
+// left out.
+func WriteFlatModule(w io.Writer, ms schema.ModelSet, module string) error {
+	if _, ok := ms.Modules()[module]; !ok {
+		return fmt.Errorf("module %s not found", module)
+	}
+	f, err := newFlattener(ms, module)
+	if err != nil {
+		return err
+	}
+	text, err := f.module()
+	if err != nil {
+		return err
+	}
+
+	// Parsing the text both checks it, and lets it be written in the
+	// same layout as other YANG.
+	tree, err := parse.Parse(module+".yang", text, nil)
+	if err != nil {
+		return fmt.Errorf("flattened module %s: %s", module, err)
+	}
+	return parse.WriteYang(w, tree)
+}
+
+// A flatText holds YANG statements as they are generated. Arguments are
+// always quoted, and WriteYang() lays them out afterwards.
+type flatText struct {
+	strings.Builder
+}
+
+// flatQuote returns s as a single quoted string, which YANG takes
+// literally. Single quotes are added by concatenation.
+func flatQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `' + "'" + '`) + "'"
+}
+
+func (t *flatText) stmt(keyword, arg string) {
+	fmt.Fprintf(t, "%s %s;\n", keyword, flatQuote(arg))
+}
+
+// block writes a statement with its substatements. Statements with no
+// argument, such as 'input', are given an empty arg.
+func (t *flatText) block(keyword, arg, body string) {
+	if arg == "" {
+		fmt.Fprintf(t, "%s {\n%s}\n", keyword, body)
+		return
+	}
+	fmt.Fprintf(t, "%s %s {\n%s}\n", keyword, flatQuote(arg), body)
+}
+
+// A flatParent describes where nodes are being written: the schema path
+// of their parent, and the properties they inherit from it.
+type flatParent struct {
+	path      string
+	config    bool
+	status    schema.Status
+	operation bool
+	keys      map[string]bool
+}
+
+// An augment of a node of another module, or of a node of the module
+// with the 'when' statements of an augment.
+type flatAugment struct {
+	parent flatParent
+	when   string
+	text   flatText
+}
+
+type flattener struct {
+	ms     schema.ModelSet
+	name   string
+	source *parse.Tree
+
+	// The module of each namespace, and the modules known in each
+	// module by prefix, as given by its YANG.
+	modules  map[string]string
+	contexts map[string]map[string]string
+
+	// The prefix given to each module in the flattened module, and the
+	// modules that must be imported for the prefixes used.
+	prefixes map[string]string
+	byPrefix map[string]string
+	imports  map[string]bool
+
+	augments   map[string]*flatAugment
+	identities map[string]*schema.Identity
+
+	// The first type that could not be written
+	err error
+}
+
+func newFlattener(ms schema.ModelSet, name string) (*flattener, error) {
+	f := &flattener{
+		ms:         ms,
+		name:       name,
+		modules:    make(map[string]string),
+		contexts:   make(map[string]map[string]string),
+		prefixes:   make(map[string]string),
+		byPrefix:   make(map[string]string),
+		imports:    make(map[string]bool),
+		augments:   make(map[string]*flatAugment),
+		identities: make(map[string]*schema.Identity),
+	}
+
+	own := make(map[string]string)
+	for _, mod := range sortedKeys(ms.Modules()) {
+		tree, err := parseModuleData(mod, ms.Modules()[mod].Data())
+		if err != nil {
+			return nil, err
+		}
+		if mod == name {
+			f.source = tree
+		}
+		f.modules[tree.Root.Ns()] = mod
+		own[mod] = tree.Root.Prefix()
+		f.addContext(mod, tree.Root.Prefix(), tree.Root)
+	}
+	for _, sub := range sortedKeys(ms.Submodules()) {
+		tree, err := parseModuleData(sub, ms.Submodules()[sub].Data())
+		if err != nil {
+			return nil, err
+		}
+		for _, bt := range tree.Root.ChildrenByType(parse.NodeBelongsTo) {
+			f.addContext(bt.Argument().String(), bt.Prefix(), tree.Root)
+		}
+	}
+
+	// Each module keeps its own prefix where it can, with the module
+	// being flattened first to choose.
+	order := append([]string{name}, sortedKeys(ms.Modules())...)
+	for _, mod := range order {
+		if _, ok := f.prefixes[mod]; ok {
+			continue
+		}
+		prefix := own[mod]
+		for i := 2; f.byPrefix[prefix] != ""; i++ {
+			prefix = fmt.Sprintf("%s%d", own[mod], i)
+		}
+		f.prefixes[mod] = prefix
+		f.byPrefix[prefix] = mod
+	}
+	return f, nil
+}
+
+func parseModuleData(name, data string) (*parse.Tree, error) {
+	var tree *parse.Tree
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(data), "<") {
+		tree, err = parse.ParseYin(name+".yin", data, nil)
+	} else {
+		tree, err = parse.Parse(name+".yang", data, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("module %s: %s", name, err)
+	}
+	return tree, nil
+}
+
+// addContext adds the prefixes of a module or submodule of mod
+func (f *flattener) addContext(mod, prefix string, root parse.Node) {
+	ctx, ok := f.contexts[mod]
+	if !ok {
+		ctx = make(map[string]string)
+		f.contexts[mod] = ctx
+	}
+	ctx[prefix] = mod
+	for _, imp := range root.ChildrenByType(parse.NodeImport) {
+		ctx[imp.Prefix()] = imp.Name()
+	}
+}
+
+// prefix returns the prefix of mod, which is then imported
+func (f *flattener) prefix(mod string) string {
+	if mod != f.name {
+		f.imports[mod] = true
+	}
+	return f.prefixes[mod]
+}
+
+func (f *flattener) moduleOfPrefix(prefix, context string) string {
+	if mod, ok := f.contexts[context][prefix]; ok {
+		return mod
+	}
+	// Expressions in groupings and typedefs use the prefixes of the
+	// module they were defined in, which is not known. Modules are most
+	// often imported with their own prefix.
+	for _, mod := range sortedKeys(f.contexts) {
+		if f.contexts[mod][prefix] == mod {
+			return mod
+		}
+	}
+	return ""
+}
+
+func isXPathNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isXPathNameChar(c byte) bool {
+	return isXPathNameStart(c) || c == '-' || c == '.' || (c >= '0' && c <= '9')
+}
+
+// xpath returns expr with the prefixes of context, the module it was
+// written in, changed to those of the flattened module.
+func (f *flattener) xpath(expr, context string) string {
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				b.WriteString(expr[i:])
+				return b.String()
+			}
+			b.WriteString(expr[i : i+end+2])
+			i += end + 2
+		case isXPathNameStart(c):
+			j := i + 1
+			for j < len(expr) && isXPathNameChar(expr[j]) {
+				j++
+			}
+			name := expr[i:j]
+			// Axes are followed by '::', and prefixes by a single ':'
+			if j+1 < len(expr) && expr[j] == ':' && expr[j+1] != ':' {
+				if mod := f.moduleOfPrefix(name, context); mod != "" {
+					name = f.prefix(mod)
+				}
+			}
+			b.WriteString(name)
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func (f *flattener) module() (string, error) {
+	top := flatParent{config: true, status: schema.Current}
+
+	var body flatText
+	for _, mod := range sortedKeys(f.ms.Modules()) {
+		m := f.ms.Modules()[mod]
+		var t *flatText
+		if mod == f.name {
+			t = &body
+		}
+		f.children(t, m, top)
+		for _, name := range sortedKeys(m.Rpcs()) {
+			rpc := m.Rpcs()[name]
+			f.operation(t, "rpc", mod, name, rpc.Input(), rpc.Output(), top)
+		}
+		for _, name := range sortedKeys(m.Notifications()) {
+			f.notification(t, mod, name, m.Notifications()[name].Schema(), top)
+		}
+	}
+
+	if f.err != nil {
+		return "", f.err
+	}
+	var identities flatText
+	if err := f.writeIdentities(&identities); err != nil {
+		return "", err
+	}
+	for _, key := range sortedKeys(f.augments) {
+		a := f.augments[key]
+		var text flatText
+		if a.when != "" {
+			text.stmt("when", a.when)
+		}
+		text.WriteString(a.text.String())
+		body.block("augment", a.parent.path, text.String())
+	}
+
+	var t flatText
+	t.stmt("yang-version", "1.1")
+	t.stmt("namespace", f.source.Root.Ns())
+	t.stmt("prefix", f.prefixes[f.name])
+	for _, mod := range sortedKeys(f.imports) {
+		var imp flatText
+		imp.stmt("prefix", f.prefixes[mod])
+		t.block("import", mod, imp.String())
+	}
+	for _, n := range f.source.Root.Children() {
+		switch n.Type() {
+		case parse.NodeOrganization, parse.NodeContact,
+			parse.NodeDescription, parse.NodeReference:
+			t.stmt(n.Statement(), n.Argument().String())
+		}
+	}
+	for _, rev := range f.source.Root.ChildrenByType(parse.NodeRevision) {
+		var text flatText
+		for _, n := range rev.Children() {
+			switch n.Type() {
+			case parse.NodeDescription, parse.NodeReference:
+				text.stmt(n.Statement(), n.Argument().String())
+			}
+		}
+		t.block("revision", rev.Argument().String(), text.String())
+	}
+	t.WriteString(identities.String())
+	t.WriteString(body.String())
+
+	var module flatText
+	module.block("module", f.name, t.String())
+	return module.String(), nil
+}
+
+func (p flatParent) child(prefix, name string, n schema.Node) flatParent {
+	child := flatParent{
+		path:      p.path + "/" + prefix + ":" + name,
+		config:    p.config,
+		status:    p.status,
+		operation: p.operation,
+	}
+	if n != nil {
+		child.config = n.Config()
+		child.status = n.Status()
+	}
+	if l, ok := n.(schema.List); ok {
+		child.keys = make(map[string]bool)
+		for _, key := range l.Keys() {
+			child.keys[key] = true
+		}
+	}
+	return child
+}
+
+// augmentWhens returns the 'when' statements of n that were given on an
+// augment, which apply in the context of its parent.
+func augmentWhens(n schema.Node) []schema.WhenContext {
+	var whens []schema.WhenContext
+	for _, w := range n.Whens() {
+		if w.RunAsParent {
+			whens = append(whens, w)
+		}
+	}
+	return whens
+}
+
+// place returns the text to write a node of the module being flattened
+// to. This is t, the text of its parent, unless the parent is not being
+// written, or the node has 'when' statements of an augment, which apply in
+// the context of the parent.
+// The node is then added by an augment.
+func (f *flattener) place(
+	t *flatText,
+	p flatParent,
+	whens []schema.WhenContext,
+) *flatText {
+	if t != nil && (len(whens) == 0 || p.path == "") {
+		return t
+	}
+
+	var exprs []string
+	for _, w := range whens {
+		exprs = append(exprs, f.xpath(w.Mach.GetExpr(), f.modules[w.Namespace]))
+	}
+	when := strings.Join(exprs, " and ")
+	if len(exprs) > 1 {
+		when = "(" + strings.Join(exprs, ") and (") + ")"
+	}
+
+	key := p.path + "\n" + when
+	a, ok := f.augments[key]
+	if !ok {
+		a = &flatAugment{parent: p, when: when}
+		f.augments[key] = a
+		for _, elem := range strings.Split(p.path, "/") {
+			if idx := strings.Index(elem, ":"); idx != -1 {
+				f.prefix(f.byPrefix[elem[:idx]])
+			}
+		}
+	}
+	return &a.text
+}
+
+// children writes the nodes of the module being flattened found under n.
+// If t is nil, n is not being written, and they are written as augments.
+func (f *flattener) children(t *flatText, n schema.Node, p flatParent) {
+	for _, ch := range schema.SchemaChildren(n) {
+		f.collectIdentities(ch)
+		if ch.Module() == f.name {
+			f.node(f.place(t, p, augmentWhens(ch)), ch, p)
+			continue
+		}
+		f.children(nil, ch, p.child(f.prefixes[ch.Module()], ch.Name(), ch))
+	}
+
+	actions := n.Actions()
+	for _, name := range sortedKeys(actions) {
+		a := actions[name]
+		mod := operationModule(n, a.Input(), a.Output())
+		var at *flatText
+		if mod == f.name {
+			at = f.place(t, p, nil)
+		}
+		f.operation(at, "action", mod, name, a.Input(), a.Output(), p)
+	}
+
+	notifs := n.NestedNotifications()
+	for _, name := range sortedKeys(notifs) {
+		tree := notifs[name].Schema()
+		mod := operationModule(n, tree)
+		var nt *flatText
+		if mod == f.name {
+			nt = f.place(t, p, nil)
+		}
+		f.notification(nt, mod, name, tree, p)
+	}
+}
+
+// operationModule guesses the module of an action or notification, which
+// the compiled schema does not keep, from the nodes within it.
+func operationModule(parent schema.Node, trees ...schema.Tree) string {
+	mod := ""
+	for _, tree := range trees {
+		if tree == nil {
+			continue
+		}
+		for _, ch := range tree.Children() {
+			if ch.Module() == parent.Module() {
+				return ch.Module()
+			}
+			if mod == "" {
+				mod = ch.Module()
+			}
+		}
+	}
+	if mod == "" {
+		return parent.Module()
+	}
+	return mod
+}
+
+func (f *flattener) operation(
+	t *flatText,
+	keyword, mod, name string,
+	input, output schema.Tree,
+	p flatParent,
+) {
+	p = p.child(f.prefixes[mod], name, nil)
+	p.operation = true
+
+	var body flatText
+	for _, io := range []struct {
+		keyword string
+		tree    schema.Tree
+	}{{"input", input}, {"output", output}} {
+		if io.tree == nil {
+			continue
+		}
+		var text *flatText
+		if t != nil {
+			text = &flatText{}
+		}
+		f.children(text, io.tree, p.child(f.prefixes[mod], io.keyword, nil))
+		if text != nil && text.Len() > 0 {
+			body.block(io.keyword, "", text.String())
+		}
+	}
+	if t != nil {
+		t.block(keyword, name, body.String())
+	}
+}
+
+func (f *flattener) notification(
+	t *flatText,
+	mod, name string,
+	tree schema.Tree,
+	p flatParent,
+) {
+	p = p.child(f.prefixes[mod], name, nil)
+	p.operation = true
+
+	var text *flatText
+	if t != nil {
+		text = &flatText{}
+	}
+	f.children(text, tree, p)
+	if t != nil {
+		t.block("notification", name, text.String())
+	}
+}
+
+func (f *flattener) node(t *flatText, n schema.Node, p flatParent) {
+	var keyword string
+	var body flatText
+	context := f.modules[n.Namespace()]
+
+	for _, w := range n.Whens() {
+		if !w.RunAsParent || p.path == "" {
+			body.stmt("when", f.xpath(w.Mach.GetExpr(), f.modules[w.Namespace]))
+		}
+	}
+
+	switch v := n.(type) {
+	case schema.Container:
+		keyword = "container"
+		if v.Presence() {
+			body.stmt("presence", "")
+		}
+	case schema.List:
+		keyword = "list"
+		if len(v.Keys()) > 0 {
+			body.stmt("key", strings.Join(v.Keys(), " "))
+		}
+		for _, unique := range v.Uniques() {
+			body.stmt("unique", f.unique(unique))
+		}
+		f.limit(&body, v.Limit())
+		if v.OrdBy() == "user" {
+			body.stmt("ordered-by", "user")
+		}
+	case schema.Leaf:
+		keyword = "leaf"
+		f.typ(&body, v.Type(), context)
+		if v.Units() != "" {
+			body.stmt("units", v.Units())
+		}
+		if def, ok := v.Default(); ok && !p.keys[n.Name()] {
+			body.stmt("default", f.defaultValue(def, v.Type(), context))
+		}
+		if v.Mandatory() && !p.keys[n.Name()] {
+			body.stmt("mandatory", "true")
+		}
+	case schema.LeafList:
+		keyword = "leaf-list"
+		f.typ(&body, v.Type(), context)
+		if v.Units() != "" {
+			body.stmt("units", v.Units())
+		}
+		for _, def := range v.Defaults() {
+			body.stmt("default", f.defaultValue(def, v.Type(), context))
+		}
+		f.limit(&body, v.Limit())
+		if v.OrdBy() == "user" {
+			body.stmt("ordered-by", "user")
+		}
+	case schema.Choice:
+		keyword = "choice"
+		if v.DefaultCase() != "" {
+			body.stmt("default", v.DefaultCase())
+		}
+		if v.Mandatory() {
+			body.stmt("mandatory", "true")
+		}
+	case schema.Case:
+		keyword = "case"
+	case schema.Anydata:
+		keyword = "anydata"
+		if v.Mandatory() {
+			body.stmt("mandatory", "true")
+		}
+	case schema.Anyxml:
+		keyword = "anyxml"
+		if v.Mandatory() {
+			body.stmt("mandatory", "true")
+		}
+	default:
+		return
+	}
+
+	for _, m := range n.Musts() {
+		expr := m.Mach.GetExpr()
+		var text flatText
+		if m.ErrMsg != fmt.Sprintf("'must' condition is false: '%s'", expr) {
+			text.stmt("error-message", m.ErrMsg)
+		}
+		if m.AppTag != "" {
+			text.stmt("error-app-tag", m.AppTag)
+		}
+		body.block("must", f.xpath(expr, f.modules[m.Namespace]), text.String())
+	}
+	if !n.Config() && p.config && !p.operation {
+		body.stmt("config", "false")
+	}
+	if n.Status() != p.status {
+		body.stmt("status", strings.ToLower(n.Status().String()))
+	}
+	if n.Description() != "" {
+		body.stmt("description", n.Description())
+	}
+
+	f.children(&body, n, p.child(f.prefixes[n.Module()], n.Name(), n))
+	t.block(keyword, n.Name(), body.String())
+}
+
+func (f *flattener) limit(t *flatText, limit schema.Limit) {
+	if limit.Min > 0 {
+		t.stmt("min-elements", strconv.FormatUint(uint64(limit.Min), 10))
+	}
+	if limit.Max != ^uint(0) {
+		t.stmt("max-elements", strconv.FormatUint(uint64(limit.Max), 10))
+	}
+}
+
+func (f *flattener) unique(unique [][]xml.Name) string {
+	var paths []string
+	for _, path := range unique {
+		var elems []string
+		for _, name := range path {
+			mod := f.modules[name.Space]
+			if mod == f.name || mod == "" {
+				elems = append(elems, name.Local)
+				continue
+			}
+			elems = append(elems, f.prefix(mod)+":"+name.Local)
+		}
+		paths = append(paths, strings.Join(elems, "/"))
+	}
+	return strings.Join(paths, " ")
+}
+
+func hasIdentityref(typ schema.Type) bool {
+	switch v := typ.(type) {
+	case schema.Identityref:
+		return true
+	case schema.Union:
+		for _, t := range v.Typs() {
+			if hasIdentityref(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Identities in default values have a prefix
+func (f *flattener) defaultValue(def string, typ schema.Type, context string) string {
+	if hasIdentityref(typ) {
+		return f.xpath(def, context)
+	}
+	return def
+}
+
+func restriction(t *flatText, keyword, arg, msg, appTag string) {
+	var text flatText
+	if msg != "" {
+		text.stmt("error-message", msg)
+	}
+	if appTag != "" {
+		text.stmt("error-app-tag", appTag)
+	}
+	t.block(keyword, arg, text.String())
+}
+
+// rangeAppTag returns the error-app-tag of a range, which is
+// range-violation unless given
+func rangeAppTag(n schema.Number) string {
+	if n.AppTag() == "range-violation" {
+		return ""
+	}
+	return n.AppTag()
+}
+
+func rangeString(r schema.RangeBoundarySlicer) string {
+	var parts []string
+	for i := 0; i < r.Len(); i++ {
+		parts = append(parts, r.String(i))
+	}
+	return strings.Join(parts, " | ")
+}
+
+func decimalRangeString(rbs schema.DrbSlice, fd schema.Fracdigit) string {
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', int(fd), 64)
+	}
+	var parts []string
+	for _, rb := range rbs {
+		if rb.Start == rb.End {
+			parts = append(parts, format(rb.Start))
+			continue
+		}
+		parts = append(parts, format(rb.Start)+".."+format(rb.End))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// lengthString returns the length restriction, or "" for any length
+func lengthString(l *schema.Length) string {
+	if l == nil {
+		return ""
+	}
+	if len(l.Lbs) == 1 && l.Lbs[0].Start == 0 &&
+		l.Lbs[0].End == math.MaxUint32 && l.Msg == "" && l.AppTag == "" {
+		return ""
+	}
+	var parts []string
+	for i := range l.Lbs {
+		parts = append(parts, l.Lbs[i].String())
+	}
+	return strings.Join(parts, " | ")
+}
+
+func statusStmt(t *flatText, s schema.Status) {
+	if s != schema.Current {
+		t.stmt("status", strings.ToLower(s.String()))
+	}
+}
+
+// typ writes the type, with the restrictions of the typedefs it is derived
+// from, as they are all kept in the compiled type.
+func (f *flattener) typ(t *flatText, typ schema.Type, context string) {
+	name := builtinTypeName(typ)
+	var body flatText
+	var none xml.Name
+
+	switch v := typ.(type) {
+	case schema.Integer:
+		full := schema.NewInteger(v.BitWidth(), none, nil, "", "", "", false)
+		if r := rangeString(v.Ranges()); r != rangeString(full.Ranges()) ||
+			v.Msg() != "" || rangeAppTag(v) != "" {
+			restriction(&body, "range", r, v.Msg(), rangeAppTag(v))
+		}
+	case schema.Uinteger:
+		full := schema.NewUinteger(v.BitWidth(), none, nil, "", "", "", false)
+		if r := rangeString(v.Ranges()); r != rangeString(full.Ranges()) ||
+			v.Msg() != "" || rangeAppTag(v) != "" {
+			restriction(&body, "range", r, v.Msg(), rangeAppTag(v))
+		}
+	case schema.Decimal64:
+		body.stmt("fraction-digits", strconv.Itoa(int(v.Fd())))
+		full := schema.NewDecimal64(none, v.Fd(), nil, "", "", "", false)
+		if r := decimalRangeString(v.Rbs(), v.Fd()); r !=
+			decimalRangeString(full.Rbs(), v.Fd()) ||
+			v.Msg() != "" || rangeAppTag(v) != "" {
+			restriction(&body, "range", r, v.Msg(), rangeAppTag(v))
+		}
+	case schema.String:
+		if l := lengthString(v.Len()); l != "" {
+			restriction(&body, "length", l, v.Len().Msg, v.Len().AppTag)
+		}
+		for _, pats := range v.Pats() {
+			for _, pat := range pats {
+				var text flatText
+				if pat.InvertMatch {
+					text.stmt("modifier", "invert-match")
+				}
+				if pat.Msg != "" {
+					text.stmt("error-message", pat.Msg)
+				}
+				if pat.AppTag != "" {
+					text.stmt("error-app-tag", pat.AppTag)
+				}
+				body.block("pattern", pat.Pattern, text.String())
+			}
+		}
+	case schema.Binary:
+		if l := lengthString(v.Length()); l != "" {
+			restriction(&body, "length", l, v.Length().Msg, v.Length().AppTag)
+		}
+	case schema.Boolean:
+	case schema.Empty:
+	case schema.Enumeration:
+		for _, e := range v.Enums() {
+			var text flatText
+			text.stmt("value", strconv.Itoa(e.Value))
+			statusStmt(&text, e.Status())
+			if e.Desc != "" {
+				text.stmt("description", e.Desc)
+			}
+			body.block("enum", e.Val, text.String())
+		}
+	case schema.Bits:
+		for _, b := range v.Bits() {
+			var text flatText
+			text.stmt("position", strconv.FormatUint(uint64(b.Pos), 10))
+			statusStmt(&text, b.Status())
+			if b.Desc != "" {
+				text.stmt("description", b.Desc)
+			}
+			body.block("bit", b.Name, text.String())
+		}
+	case schema.Union:
+		for _, member := range v.Typs() {
+			f.typ(&body, member, context)
+		}
+	case schema.Identityref:
+		bases := identityrefBases(v.Identities())
+		if len(bases) == 0 && f.err == nil {
+			f.err = fmt.Errorf(
+				"identityref %s has no identities, so its base is not known",
+				v.Name().Local)
+		}
+		for _, base := range bases {
+			mod, id := splitIdentity(base)
+			body.stmt("base", f.prefix(mod)+":"+id)
+		}
+	case schema.InstanceId:
+		if !v.Require() {
+			body.stmt("require-instance", "false")
+		}
+	case schema.Leafref:
+		body.stmt("path", f.xpath(v.Mach().GetExpr(), context))
+	}
+	if name != "" {
+		t.block("type", name, body.String())
+	}
+}
+
+// builtinTypeName returns the name of the YANG built-in type of typ
+func builtinTypeName(typ schema.Type) string {
+	switch v := typ.(type) {
+	case schema.Integer:
+		return fmt.Sprintf("int%d", v.BitWidth())
+	case schema.Uinteger:
+		return fmt.Sprintf("uint%d", v.BitWidth())
+	case schema.Decimal64:
+		return "decimal64"
+	case schema.String:
+		return "string"
+	case schema.Binary:
+		return "binary"
+	case schema.Boolean:
+		return "boolean"
+	case schema.Empty:
+		return "empty"
+	case schema.Enumeration:
+		return "enumeration"
+	case schema.Bits:
+		return "bits"
+	case schema.Union:
+		return "union"
+	case schema.Identityref:
+		return "identityref"
+	case schema.InstanceId:
+		return "instance-identifier"
+	case schema.Leafref:
+		return "leafref"
+	}
+	return ""
+}
+
+func splitIdentity(name string) (mod, id string) {
+	if idx := strings.Index(name, ":"); idx != -1 {
+		return name[:idx], name[idx+1:]
+	}
+	return "", name
+}
+
+func identityKey(id *schema.Identity) string {
+	return id.Module + ":" + id.Value
+}
+
+// identityrefBases works out the bases of an identityref, which are not
+// kept in the compiled type, as the identities all its identities are
+// derived from that are not themselves valid values.
+func identityrefBases(ids []*schema.Identity) []string {
+	members := make(map[string]*schema.Identity, len(ids))
+	for _, id := range ids {
+		members[identityKey(id)] = id
+	}
+
+	var derives func(id *schema.Identity, base string, seen map[string]bool) bool
+	derives = func(id *schema.Identity, base string, seen map[string]bool) bool {
+		if seen[identityKey(id)] {
+			return false
+		}
+		seen[identityKey(id)] = true
+		for _, b := range id.Bases() {
+			if b == base {
+				return true
+			}
+			if m, ok := members[b]; ok && derives(m, base, seen) {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidates := make(map[string]bool)
+	for _, id := range ids {
+		for _, b := range id.Bases() {
+			if members[b] == nil {
+				candidates[b] = true
+			}
+		}
+	}
+	var bases []string
+	for _, base := range sortedKeys(candidates) {
+		all := true
+		for _, id := range ids {
+			if !derives(id, base, make(map[string]bool)) {
+				all = false
+				break
+			}
+		}
+		if all {
+			bases = append(bases, base)
+		}
+	}
+	return bases
+}
+
+// collectIdentities notes the identities used by n, so the module's own
+// identities can be written.
+func (f *flattener) collectIdentities(n schema.Node) {
+	var typ schema.Type
+	switch v := n.(type) {
+	case schema.Leaf:
+		typ = v.Type()
+	case schema.LeafList:
+		typ = v.Type()
+	}
+	f.collectTypeIdentities(typ)
+}
+
+func (f *flattener) collectTypeIdentities(typ schema.Type) {
+	switch v := typ.(type) {
+	case schema.Identityref:
+		for _, id := range v.Identities() {
+			f.identities[identityKey(id)] = id
+		}
+	case schema.Union:
+		for _, t := range v.Typs() {
+			f.collectTypeIdentities(t)
+		}
+	}
+}
+
+// writeIdentities writes the identities of the module that are used by
+// an identityref, and the identities they are derived from.
+func (f *flattener) writeIdentities(t *flatText) error {
+	own := make(map[string]*schema.Identity)
+	for _, id := range f.identities {
+		if id.Module == f.name {
+			own[id.Value] = id
+		}
+		for _, base := range id.Bases() {
+			if mod, name := splitIdentity(base); mod == f.name {
+				if _, ok := own[name]; !ok {
+					own[name] = nil
+				}
+			}
+		}
+	}
+	for _, name := range sortedKeys(own) {
+		id := own[name]
+		var text flatText
+		if id != nil {
+			for _, base := range id.Bases() {
+				mod, bname := splitIdentity(base)
+				if mod == "" {
+					return fmt.Errorf("identity %s: base %s has no module",
+						name, base)
+				}
+				text.stmt("base", f.prefix(mod)+":"+bname)
+			}
+			statusStmt(&text, id.Status())
+			if id.Desc != "" {
+				text.stmt("description", id.Desc)
+			}
+		}
+		t.block("identity", name, text.String())
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,377 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+const flatBaseModule = `module flat-base {
+	namespace "urn:flat-base";
+	prefix fb;
+
+	identity animal;
+
+	typedef percent {
+		type uint8 {
+			range "0..100";
+		}
+	}
+}`
+
+const flatModule = `module flat {
+	namespace "urn:flat";
+	prefix f;
+
+	import flat-base {
+		prefix b;
+	}
+
+	organization "Flat Co";
+	description "A module to flatten";
+
+	revision 2024-02-01 {
+		description "Second revision.";
+	}
+
+	feature extra;
+
+	identity cat {
+		base b:animal;
+		description "A cat";
+	}
+
+	typedef name-type {
+		type string {
+			length "1..32";
+			pattern '[a-z]+';
+		}
+		default "none";
+	}
+
+	grouping named {
+		leaf name {
+			type name-type;
+		}
+	}
+
+	container top {
+		uses named;
+		leaf level {
+			type b:percent;
+			units percent;
+		}
+		leaf pet {
+			type identityref {
+				base b:animal;
+			}
+			default cat;
+		}
+		leaf extra {
+			if-feature extra;
+			type string;
+		}
+		leaf removed {
+			type string;
+		}
+		list item {
+			key "id";
+			must "id != 5" {
+				error-message "Not five";
+			}
+			leaf id {
+				type int32 {
+					range "1..10 | 20";
+				}
+			}
+			leaf ref {
+				type leafref {
+					path "/f:top/f:name";
+				}
+			}
+		}
+		container state {
+			config false;
+		}
+		choice pick {
+			leaf one {
+				type empty;
+			}
+			case two {
+				leaf two {
+					type decimal64 {
+						fraction-digits 2;
+						range "0 .. 1.5";
+					}
+				}
+			}
+		}
+	}
+
+	augment /f:top/f:state {
+		when "../f:level > 50";
+		uses named;
+	}
+
+	rpc reset {
+		input {
+			uses named;
+		}
+	}
+}`
+
+const flatAugmentModule = `module flat-aug {
+	namespace "urn:flat-aug";
+	prefix fa;
+
+	import flat {
+		prefix f;
+	}
+
+	deviation /f:top/f:removed {
+		deviate not-supported;
+	}
+
+	augment /f:top {
+		when "f:level > 10";
+		leaf note {
+			type string;
+		}
+	}
+
+	augment /f:reset/f:input {
+		leaf why {
+			type string;
+		}
+	}
+}`
+
+func compileFlatModules(t *testing.T, files map[string][]byte) schema.ModelSet {
+	t.Helper()
+	ms, err := compile.CompileDir(nil, &compile.Config{
+		Repository: compile.MapRepository(files),
+		Features:   compile.FeaturesFromNames(true),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected compilation error: %s", err)
+	}
+	return ms
+}
+
+func writeFlatModule(t *testing.T, ms schema.ModelSet, module string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := compile.WriteFlatModule(&buf, ms, module); err != nil {
+		t.Fatalf("Unexpected WriteFlatModule error: %s", err)
+	}
+	return buf.String()
+}
+
+func flatTestSchema(t *testing.T) schema.ModelSet {
+	return compileFlatModules(t, map[string][]byte{
+		"flat-base.yang": []byte(flatBaseModule),
+		"flat.yang":      []byte(flatModule),
+		"flat-aug.yang":  []byte(flatAugmentModule),
+	})
+}
+
+const flatModuleFlattened = `module flat {
+  yang-version 1.1;
+  namespace urn:flat;
+  prefix f;
+
+  import flat-base {
+    prefix fb;
+  }
+
+  organization "Flat Co";
+  description "A module to flatten";
+
+  revision 2024-02-01 {
+    description "Second revision.";
+  }
+
+  identity cat {
+    base fb:animal;
+    description "A cat";
+  }
+
+  container top {
+    list item {
+      must "id != 5" {
+        error-message "Not five";
+      }
+      key id;
+      leaf id {
+        type int32 {
+          range "1..10 | 20";
+        }
+      }
+      leaf ref {
+        type leafref {
+          path "/f:top/f:name";
+        }
+      }
+    }
+    leaf level {
+      type uint8 {
+        range 0..100;
+      }
+      units percent;
+    }
+    leaf name {
+      type string {
+        length 1..32;
+        pattern '[a-z]+';
+      }
+      default none;
+    }
+    leaf pet {
+      type identityref {
+        base fb:animal;
+      }
+      default cat;
+    }
+    choice pick {
+      case one {
+        leaf one {
+          type empty;
+        }
+      }
+      case two {
+        leaf two {
+          type decimal64 {
+            fraction-digits 2;
+            range 0.00..1.50;
+          }
+        }
+      }
+    }
+    container state {
+      config false;
+    }
+  }
+
+  rpc reset {
+    input {
+      leaf name {
+        type string {
+          length 1..32;
+          pattern '[a-z]+';
+        }
+        default none;
+      }
+    }
+  }
+
+  augment /f:top/f:state {
+    when "../f:level > 50";
+    leaf name {
+      type string {
+        length 1..32;
+        pattern '[a-z]+';
+      }
+      default none;
+    }
+  }
+}
+`
+
+const flatAugmentFlattened = `module flat-aug {
+  yang-version 1.1;
+  namespace urn:flat-aug;
+  prefix fa;
+
+  import flat {
+    prefix f;
+  }
+
+  augment /f:reset/f:input {
+    leaf why {
+      type string;
+    }
+  }
+
+  augment /f:top {
+    when "f:level > 10";
+    leaf note {
+      type string;
+    }
+  }
+}
+`
+
+const flatBaseFlattened = `module flat-base {
+  yang-version 1.1;
+  namespace urn:flat-base;
+  prefix fb;
+
+  identity animal;
+}
+`
+
+// Groupings are expanded and typedefs written in full, with the feature
+// disabled and node removed by deviation left out. The augment of the
+// module's own node is kept, as its 'when' applies to the parent.
+func TestWriteFlatModule(t *testing.T) {
+	ms := flatTestSchema(t)
+	for _, test := range []struct {
+		module, expected string
+	}{
+		{"flat", flatModuleFlattened},
+		{"flat-aug", flatAugmentFlattened},
+		{"flat-base", flatBaseFlattened},
+	} {
+		t.Run(test.module, func(t *testing.T) {
+			if act := writeFlatModule(t, ms, test.module); act != test.expected {
+				t.Errorf("Unexpected flattened module\nexpected:\n%s\nactual:\n%s",
+					test.expected, act)
+			}
+		})
+	}
+}
+
+// Compiling the flattened modules gives the same schema, so flattening
+// it again gives the same modules.
+func TestWriteFlatModuleCompiles(t *testing.T) {
+	ms := flatTestSchema(t)
+	flattened := make(map[string]string)
+	files := make(map[string][]byte)
+	for name := range ms.Modules() {
+		flattened[name] = writeFlatModule(t, ms, name)
+		files[name+".yang"] = []byte(flattened[name])
+	}
+	flat := compileFlatModules(t, files)
+
+	for name, exp := range flattened {
+		if act := writeFlatModule(t, flat, name); act != exp {
+			t.Errorf("Flattened schema differs for %s\nexpected:\n%s\nactual:\n%s",
+				name, exp, act)
+		}
+	}
+}
+
+func TestWriteFlatModuleNotFound(t *testing.T) {
+	ms := flatTestSchema(t)
+	var buf bytes.Buffer
+	err := compile.WriteFlatModule(&buf, ms, "missing")
+	if err == nil || err.Error() != "module missing not found" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
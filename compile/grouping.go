@@ -100,7 +100,7 @@ func (c *Compiler) validateGrouping(
 
 func isMandatory(nod parse.Node) bool {
 	switch nod.Type() {
-	case parse.NodeLeaf, parse.NodeChoice:
+	case parse.NodeLeaf, parse.NodeChoice, parse.NodeAnyxml, parse.NodeAnydata:
 		return nod.Mandatory()
 	case parse.NodeLeafList, parse.NodeList:
 		// List/Leaf-List is mandatory if min-elements > 0
@@ -127,8 +127,8 @@ func isMandatory(nod parse.Node) bool {
 }
 
 // Only some node types are augmentable - data (leaf, list, leaf-list and
-// cont), Input and Output.  RPC is also needed here as we have to include
-// nodes that may be parents of augmentable nodes.
+// cont), Input, Output and Notification.  RPC and action are also needed
+// here as we have to include nodes that may be parents of augmentable nodes.
 func getAugmentableNodesForModule(applyToMod parse.Node) []parse.Node {
 	allowedNodes := applyToMod.ChildrenByType(parse.NodeDataDef)
 	allowedNodes = append(allowedNodes,
@@ -137,6 +137,10 @@ func getAugmentableNodesForModule(applyToMod parse.Node) []parse.Node {
 		applyToMod.ChildrenByType(parse.NodeOpdDef)...)
 	allowedNodes = append(allowedNodes,
 		applyToMod.ChildrenByType(parse.NodeRpc)...)
+	allowedNodes = append(allowedNodes,
+		applyToMod.ChildrenByType(parse.NodeAction)...)
+	allowedNodes = append(allowedNodes,
+		applyToMod.ChildrenByType(parse.NodeNotification)...)
 	allowedNodes = append(allowedNodes,
 		applyToMod.ChildrenByType(parse.NodeInput)...)
 	allowedNodes = append(allowedNodes,
@@ -166,7 +170,8 @@ func (c *Compiler) applyAugment(
 	c.assertReferenceStatus(a, applyToNode, parentStatus)
 
 	for _, ch := range a.Children() {
-		if ch.Type().IsDataNode() || ch.Type().IsOpdDefNode() || ch.Type().IsExtensionNode() {
+		if ch.Type().IsDataNode() || ch.Type().IsOpdDefNode() || ch.Type().IsExtensionNode() ||
+			ch.Type() == parse.NodeAction || ch.Type() == parse.NodeNotification {
 			inheritCommonProperties(a, ch, true)
 			c.applyChange(a, applyToNode, ch)
 		}
@@ -205,38 +210,44 @@ func (c *Compiler) expandModule(module *parse.Module) {
 	children := nod.ChildrenByType(parse.NodeAugment)
 	children = append(children, nod.ChildrenByType(parse.NodeOpdAugment)...)
 	for _, a := range children {
-
-		if _, ok := a.Argument().(*parse.AbsoluteSchemaArg); !ok {
-			c.error(a,
-				fmt.Errorf("invalid argument %s expected absolute schema id",
-					a.Argument().String()))
+		if !c.collect(func() { c.expandAugment(nod, a) }) {
+			nod.ReplaceChild(a)
 		}
-		applyToPath := a.ArgSchema()
-		applyToPfx := applyToPath[0].Space
-		applyToMod, err := nod.GetModuleByPrefix(
-			applyToPfx, c.modules, c.skipUnknown)
-		if err != nil {
-			c.error(nod, err)
-		}
-		if applyToMod != nod {
-			if isMandatory(a) {
-				c.error(a, fmt.Errorf("Cannot add mandatory nodes to another module: %s",
-					applyToPfx))
-			}
+	}
+}
+
+func (c *Compiler) expandAugment(nod, a parse.Node) {
+
+	if _, ok := a.Argument().(*parse.AbsoluteSchemaArg); !ok {
+		c.error(a,
+			fmt.Errorf("invalid argument %s expected absolute schema id",
+				a.Argument().String()))
+	}
+	applyToPath := a.ArgSchema()
+	applyToPfx := applyToPath[0].Space
+	applyToMod, err := nod.GetModuleByPrefix(
+		applyToPfx, c.modules, c.skipUnknown)
+	if err != nil {
+		c.error(nod, err)
+	}
+	if applyToMod != nod {
+		if isMandatory(a) {
+			c.error(a, fmt.Errorf("Cannot add mandatory nodes to another module: %s",
+				applyToPfx))
 		}
+	}
 
-		// In this mode we add paths we might need
-		if c.skipUnknown {
-			var nc parse.NodeCardinality
-			if c.extensions != nil {
-				nc = c.extensions.NodeCardinality
-			}
-			c.addFakePathToNode(nc, applyToMod, applyToPath)
+	// In this mode we add paths we might need
+	if c.skipUnknown {
+		var nc parse.NodeCardinality
+		if c.extensions != nil {
+			nc = c.extensions.NodeCardinality
 		}
-		allowedNodes := getAugmentableNodesForModule(applyToMod)
-		c.applyAugment(a, allowedNodes, applyToPath, schema.Current) //AGJ
-		nod.ReplaceChild(a)
+		c.addFakePathToNode(nc, applyToMod, applyToPath)
 	}
+	allowedNodes := getAugmentableNodesForModule(applyToMod)
+	c.applyAugment(a, allowedNodes, applyToPath, schema.Current) //AGJ
+	nod.ReplaceChild(a)
 }
 
 func (c *Compiler) expandGroupings(mod, nod parse.Node, parentStatus schema.Status) error {
@@ -249,16 +260,7 @@ func (c *Compiler) expandGroupings(mod, nod parse.Node, parentStatus schema.Stat
 
 	// Expand any groupings found in any children before applying refines
 	for _, kid := range nod.Children() {
-		// If any expanded grouping contains a 'uses' at the top-level,
-		// we need to expand this directly.  Otherwise we will pass the
-		// 'uses' into expandGroupings (instead of as child of the node
-		// passed in) and won't expand it.
-		if kid.Type() == parse.NodeUses {
-			if err := c.applyUsesToNode(mod, nod, kid, status); err != nil {
-				return err
-			}
-		}
-		if err := c.expandGroupings(mod, kid, status); err != nil {
+		if err := c.expandChildGroupings(mod, nod, kid, status); err != nil {
 			return err
 		}
 	}
@@ -270,6 +272,37 @@ func (c *Compiler) expandGroupings(mod, nod parse.Node, parentStatus schema.Stat
 	return nil
 }
 
+func (c *Compiler) expandChildGroupings(
+	mod, nod, kid parse.Node,
+	status schema.Status,
+) (err error) {
+	expanded := c.collect(func() {
+		// If any expanded grouping contains a 'uses' at the top-level,
+		// we need to expand this directly.  Otherwise we will pass the
+		// 'uses' into expandGroupings (instead of as child of the node
+		// passed in) and won't expand it.
+		if kid.Type() == parse.NodeUses {
+			if err = c.applyUsesToNode(mod, nod, kid, status); err != nil {
+				if c.collectErrors {
+					c.error(kid, err)
+				}
+				return
+			}
+		}
+		err = c.expandGroupings(mod, kid, status)
+	})
+
+	// When collecting errors the error has been recorded, and a uses that
+	// failed is dropped so the rest of the tree can still be expanded.
+	if !expanded {
+		if kid.Type() == parse.NodeUses {
+			nod.ReplaceChild(kid)
+		}
+		return nil
+	}
+	return err
+}
+
 func (c *Compiler) getNext(
 	srcNode parse.Node, // See comment in function
 	nods []parse.Node,
@@ -342,15 +375,37 @@ func (c *Compiler) addFakePathToNode(
 	c.addFakePathToNode(extCard, next, path[1:])
 }
 
+// Each refinement may only be applied to the kinds of node listed for it
+// in RFC 7950 section 7.13.2.
 func (c *Compiler) refinementIsValid(refine, applyToNode, refinement parse.Node) error {
 	if refinement.Type().IsExtensionNode() {
 		return nil
 	}
+
+	var targets []parse.NodeType
 	switch refinement.Type() {
 	case parse.NodeDescription, parse.NodeReference, parse.NodeConfig,
-		parse.NodeMandatory, parse.NodePresence, parse.NodeMust,
-		parse.NodeDefault, parse.NodeMinElements, parse.NodeMaxElements:
+		parse.NodeIfFeature:
 		return nil
+	case parse.NodeMust:
+		targets = []parse.NodeType{parse.NodeContainer, parse.NodeLeaf,
+			parse.NodeLeafList, parse.NodeList, parse.NodeAnyxml,
+			parse.NodeAnydata}
+	case parse.NodePresence:
+		targets = []parse.NodeType{parse.NodeContainer}
+	case parse.NodeDefault:
+		targets = []parse.NodeType{parse.NodeLeaf, parse.NodeLeafList,
+			parse.NodeChoice}
+	case parse.NodeMandatory:
+		targets = []parse.NodeType{parse.NodeLeaf, parse.NodeChoice,
+			parse.NodeAnyxml, parse.NodeAnydata}
+	case parse.NodeMinElements, parse.NodeMaxElements:
+		targets = []parse.NodeType{parse.NodeLeafList, parse.NodeList}
+	}
+	for _, target := range targets {
+		if applyToNode.Type() == target {
+			return nil
+		}
 	}
 
 	return fmt.Errorf("invalid refinement %s for statement %s",
@@ -523,6 +578,7 @@ func (c *Compiler) applyUsesToNode(mod, nod, use parse.Node, parentStatus schema
 	refinedNodes := []parse.Node{}
 	for _, kid := range group.Children() {
 		newKid := kid.Clone(kidmod)
+		c.usesSites[newKid] = use
 		inheritCommonProperties(use, newKid, false)
 
 		// Deal with 'double' forward reference of grouping where first
@@ -554,6 +610,13 @@ func (c *Compiler) applyUsesToNode(mod, nod, use parse.Node, parentStatus schema
 				}
 			}
 		}
+		// A leaf-list may have several defaults, all of which are
+		// replaced by those given in the refine.
+		if len(r.ChildrenByType(parse.NodeDefault)) > 0 {
+			for _, def := range applyToNode.ChildrenByType(parse.NodeDefault) {
+				applyToNode.ReplaceChild(def)
+			}
+		}
 		for _, ch := range r.Children() {
 			c.applyChange(r, applyToNode, ch)
 		}
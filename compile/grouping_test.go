@@ -28,8 +28,10 @@ package compile_test
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"testing"
 
+	"github.com/sdcio/yang-parser/schema"
 	"github.com/sdcio/yang-parser/testutils"
 )
 
@@ -679,6 +681,100 @@ func TestForbiddenRefine(t *testing.T) {
 	assertErrorContains(t, err, expected)
 }
 
+func TestRefineTargets(t *testing.T) {
+	st := buildSchema(t, `
+		feature unused;
+		grouping g1 {
+			container box {
+				leaf-list items {
+					type uint8;
+					default 1;
+					default 2;
+				}
+				list entries {
+					key name;
+					leaf name {
+						type string;
+					}
+				}
+			}
+			container gated;
+		}
+		uses g1 {
+			refine box {
+				presence "box is configured";
+				must "count(entries) < 5";
+			}
+			refine box/items {
+				default 7;
+				default 8;
+				max-elements 3;
+			}
+			refine box/entries {
+				min-elements 2;
+			}
+			refine gated {
+				if-feature unused;
+			}
+		}`)
+
+	box, ok := st.Child("box").(schema.Container)
+	if !ok || !box.Presence() {
+		t.Fatalf("Container box should have presence")
+	}
+	if len(box.Musts()) != 1 {
+		t.Errorf("Container box should have one must, got %d",
+			len(box.Musts()))
+	}
+	items := box.Child("items").(schema.LeafList)
+	if !reflect.DeepEqual(items.Defaults(), []string{"7", "8"}) {
+		t.Errorf("Unexpected defaults: %v", items.Defaults())
+	}
+	if lim := items.Limit(); lim.Max != 3 {
+		t.Errorf("Unexpected leaf-list limit: %v", lim)
+	}
+	entries := box.Child("entries").(schema.List)
+	if lim := entries.Limit(); lim.Min != 2 {
+		t.Errorf("Unexpected list limit: %v", lim)
+	}
+	if st.Child("gated") != nil {
+		t.Errorf("Container gated should be disabled by if-feature")
+	}
+}
+
+func TestInvalidRefineTargets(t *testing.T) {
+	for _, test := range []struct {
+		target, refine, expected string
+	}{
+		{"one", "presence true;",
+			"invalid refinement presence for statement leaf"},
+		{"one", "min-elements 1;",
+			"invalid refinement min-elements for statement leaf"},
+		{"one", "max-elements 1;",
+			"invalid refinement max-elements for statement leaf"},
+		{"box", "default foo;",
+			"invalid refinement default for statement container"},
+		{"box", "mandatory true;",
+			"invalid refinement mandatory for statement container"},
+	} {
+		schema_text := bytes.NewBufferString(fmt.Sprintf(
+			SchemaTemplate,
+			`grouping g1 {
+				leaf one {
+					type string;
+				}
+				container box;
+			}
+			uses g1 {
+				refine `+test.target+` {
+					`+test.refine+`
+				}
+			}`))
+		_, err := testutils.GetConfigSchema(schema_text.Bytes())
+		assertErrorContains(t, err, test.expected)
+	}
+}
+
 func TestComplexGroupExpansion(t *testing.T) {
 
 	schema_text := bytes.NewBufferString(fmt.Sprintf(
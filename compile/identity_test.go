@@ -22,8 +22,11 @@
 package compile_test
 
 import (
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/sdcio/yang-parser/schema"
 	"github.com/sdcio/yang-parser/testutils"
 )
 
@@ -328,3 +331,73 @@ func TestIdentityRefBaseRemoteIdentityNotValid(t *testing.T) {
 	}
 	runIdentityTestCases(t, tc)
 }
+
+func TestIdentityMultipleBases(t *testing.T) {
+	schema_snippet := `
+	identity crypto-alg;
+	identity symmetric {
+		base crypto-alg;
+	}
+	identity hash;
+	identity keyed {
+		base crypto-alg;
+		base hash;
+	}
+	// Reachable from crypto-alg through both parents
+	identity hmac-sha256 {
+		base symmetric;
+		base keyed;
+	}
+	identity sha256 {
+		base hash;
+	}
+
+	leaf any-alg {
+		type identityref {
+			base crypto-alg;
+		}
+	}
+	leaf keyed-hash {
+		type identityref {
+			base crypto-alg;
+			base hash;
+		}
+	}
+`
+	st := buildSchema(t, schema_snippet)
+
+	checkIdentities := func(leaf string, exp ...string) {
+		idref, ok := st.Child(leaf).Type().(schema.Identityref)
+		if !ok {
+			t.Fatalf("%s is not an identityref", leaf)
+		}
+		var actual []string
+		for _, id := range idref.Identities() {
+			actual = append(actual, id.Val)
+		}
+		sort.Strings(actual)
+		sort.Strings(exp)
+		if strings.Join(actual, " ") != strings.Join(exp, " ") {
+			t.Errorf("%s: expected identities %v, got %v", leaf, exp, actual)
+		}
+	}
+	checkIdentities("any-alg", "symmetric", "keyed", "hmac-sha256")
+	checkIdentities("keyed-hash", "keyed", "hmac-sha256")
+
+	idref := st.Child("keyed-hash").Type().(schema.Identityref)
+	for _, id := range idref.Identities() {
+		if id.Val != "hmac-sha256" {
+			continue
+		}
+		bases := strings.Join(id.Bases(), " ")
+		if bases != "test-yang-compile:symmetric test-yang-compile:keyed" {
+			t.Errorf("Unexpected bases for hmac-sha256: %s", bases)
+		}
+	}
+	if err := idref.Validate(nil, []string{"keyed-hash"}, "sha256"); err == nil {
+		t.Errorf("sha256 is not derived from all bases and should be rejected")
+	}
+	if err := idref.Validate(nil, []string{"keyed-hash"}, "keyed"); err != nil {
+		t.Errorf("Unexpected error validating keyed: %s", err)
+	}
+}
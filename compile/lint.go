@@ -0,0 +1,428 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file checks modules against the guidelines for authors of YANG
+// modules in RFC 8407. Unlike the errors found when compiling, the
+// findings don't stop a module being used, so are reported as diagnostics
+// of a severity each rule may be given.
+
+package compile
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sdcio/yang-parser/parse"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+// Codes of the lint rules, each of which may be disabled or given a
+// severity in a LintConfig.
+const (
+	LintDescription    ErrorCode = "lint-description"
+	LintReference      ErrorCode = "lint-reference"
+	LintPrefix         ErrorCode = "lint-prefix"
+	LintNamespace      ErrorCode = "lint-namespace"
+	LintRevision       ErrorCode = "lint-revision"
+	LintTopMandatory   ErrorCode = "lint-top-mandatory"
+	LintConfigStmt     ErrorCode = "lint-config"
+	LintUnusedGrouping ErrorCode = "lint-unused-grouping"
+	LintUnusedTypedef  ErrorCode = "lint-unused-typedef"
+)
+
+// A LintRule describes one of the checks made by the linter
+type LintRule struct {
+	Code        ErrorCode
+	Severity    Severity
+	Description string
+}
+
+var lintRules = []LintRule{
+	{LintDescription, SeverityWarning,
+		"modules and definitions have a description"},
+	{LintReference, SeverityWarning,
+		"revisions have a reference"},
+	{LintPrefix, SeverityWarning,
+		"prefixes are lower case, and imports use the imported module's prefix"},
+	{LintNamespace, SeverityWarning,
+		"namespaces are unique, and IETF and IANA modules use their URN"},
+	{LintRevision, SeverityWarning,
+		"modules have a described revision, matching any in the file name"},
+	{LintTopMandatory, SeverityWarning,
+		"top-level configuration is not mandatory"},
+	{LintConfigStmt, SeverityWarning,
+		"config is only given where it changes the inherited value"},
+	{LintUnusedGrouping, SeverityWarning,
+		"groupings within definitions are used"},
+	{LintUnusedTypedef, SeverityWarning,
+		"typedefs within definitions are used"},
+}
+
+// LintRules returns the rules checked by the linter, with their default
+// severities.
+func LintRules() []LintRule {
+	return append([]LintRule{}, lintRules...)
+}
+
+// LintConfig selects the rules checked by the linter. Rules not disabled
+// are checked, and have their default severity unless given here.
+type LintConfig struct {
+	Disabled   map[ErrorCode]bool
+	Severities map[ErrorCode]Severity
+}
+
+func (c *LintConfig) severity(code ErrorCode) (Severity, bool) {
+	if c != nil {
+		if c.Disabled[code] {
+			return "", false
+		}
+		if sev, ok := c.Severities[code]; ok {
+			return sev, true
+		}
+	}
+	for _, rule := range lintRules {
+		if rule.Code == code {
+			return rule.Severity, true
+		}
+	}
+	return SeverityWarning, true
+}
+
+type linter struct {
+	cfg   *LintConfig
+	diags Diagnostics
+}
+
+// report adds a finding against the statement n, reached through path,
+// unless the rule is disabled.
+func (l *linter) report(
+	code ErrorCode,
+	n parse.Node,
+	path []string,
+	format string,
+	args ...interface{},
+) bool {
+	sev, enabled := l.cfg.severity(code)
+	if !enabled {
+		return false
+	}
+	d := Diagnostic{
+		Severity: sev,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if n != nil {
+		d.File, d.Line, d.Column = n.Location()
+		d.Statement = n.String()
+		if root := n.Root(); root != nil {
+			d.Module = root.Name()
+		}
+	}
+	d.Path = append([]string{}, path...)
+	l.diags = append(l.diags, d)
+	return true
+}
+
+// LintParseTrees checks the modules and submodules, as returned by
+// ParseYang, against the rules that apply to the statements as written.
+// Compiling a module expands its parse tree, so the trees are to be
+// checked before they are compiled.
+func LintParseTrees(trees map[string]*parse.Tree, cfg *LintConfig) Diagnostics {
+	l := &linter{cfg: cfg}
+
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prefixes := make(map[string]string)
+	namespaces := make(map[string]string)
+	for _, name := range names {
+		root := trees[name].Root
+		prefixes[root.Name()] = root.Prefix()
+		if ns := root.Ns(); ns != "" {
+			if other, ok := namespaces[ns]; ok && other != root.Name() {
+				l.report(LintNamespace, root.ChildByType(parse.NodeNamespace),
+					[]string{root.String()},
+					"namespace %s is also used by module %s", ns, other)
+			} else {
+				namespaces[ns] = root.Name()
+			}
+		}
+	}
+
+	for _, name := range names {
+		l.module(trees[name], prefixes)
+	}
+	return l.diags
+}
+
+// Statements which RFC 8407 says should have a description
+func lintNeedsDescription(t parse.NodeType) bool {
+	switch t {
+	case parse.NodeModule, parse.NodeSubmodule,
+		parse.NodeContainer, parse.NodeLeaf, parse.NodeLeafList,
+		parse.NodeList, parse.NodeChoice, parse.NodeAnydata,
+		parse.NodeAnyxml, parse.NodeRpc, parse.NodeAction,
+		parse.NodeNotification, parse.NodeTypedef, parse.NodeGrouping,
+		parse.NodeIdentity, parse.NodeFeature, parse.NodeExtension,
+		parse.NodeAugment, parse.NodeDeviation:
+		return true
+	}
+	return false
+}
+
+func isLintPrefix(prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	for _, r := range prefix {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *linter) module(t *parse.Tree, prefixes map[string]string) {
+	root := t.Root
+	path := []string{root.String()}
+	name := root.Name()
+
+	prefixStmt := root.ChildByType(parse.NodePrefix)
+	if belongsTo := root.ChildByType(parse.NodeBelongsTo); belongsTo != nil {
+		prefixStmt = belongsTo.ChildByType(parse.NodePrefix)
+	}
+	prefix := ""
+	if prefixStmt != nil {
+		prefix = prefixStmt.ArgPrefix()
+		if !isLintPrefix(prefix) {
+			l.report(LintPrefix, prefixStmt, path,
+				"prefix %s is not lower case letters, digits and hyphens", prefix)
+		}
+	}
+
+	for _, imp := range root.ChildrenByType(parse.NodeImport) {
+		own, known := prefixes[imp.Name()]
+		switch {
+		case !isLintPrefix(imp.Prefix()):
+			l.report(LintPrefix, imp, path,
+				"prefix %s is not lower case letters, digits and hyphens",
+				imp.Prefix())
+		case known && own != "" && imp.Prefix() != own:
+			l.report(LintPrefix, imp, path,
+				"module %s is imported with prefix %s instead of its own prefix %s",
+				imp.Name(), imp.Prefix(), own)
+		}
+	}
+
+	if root.Type() == parse.NodeModule {
+		for _, ietf := range []string{"ietf", "iana"} {
+			urn := "urn:ietf:params:xml:ns:yang:" + name
+			if strings.HasPrefix(name, ietf+"-") && root.Ns() != urn {
+				l.report(LintNamespace, root.ChildByType(parse.NodeNamespace), path,
+					"namespace of %s module should be %s", ietf, urn)
+			}
+		}
+	}
+
+	l.revisions(t, path)
+
+	l.statements(root, path, true, true)
+	l.unused(root, path, prefix)
+}
+
+func (l *linter) revisions(t *parse.Tree, path []string) {
+	root := t.Root
+	revisions := root.ChildrenByType(parse.NodeRevision)
+	if len(revisions) == 0 {
+		l.report(LintRevision, root, path, "%s has no revision", root.Statement())
+	}
+	for _, rev := range revisions {
+		if rev.Desc() == "" {
+			l.report(LintRevision, rev, path, "revision has no description")
+		}
+		if rev.Ref() == "" {
+			l.report(LintReference, rev, path, "revision has no reference")
+		}
+	}
+
+	base, _ := moduleFileName(filepath.Base(t.ParseName))
+	if idx := strings.Index(base, "@"); idx != -1 && len(revisions) > 0 {
+		if fileRev := base[idx+1:]; fileRev != root.Revision() {
+			l.report(LintRevision, revisions[0], path,
+				"revision %s in file name is not the latest revision %s",
+				fileRev, root.Revision())
+		}
+	}
+}
+
+// statements checks n and the statements under it. Config is the value
+// inherited by n's children, and inData says whether that is known, as it
+// isn't for the body of a grouping or augment.
+func (l *linter) statements(n parse.Node, path []string, config, inData bool) {
+	if lintNeedsDescription(n.Type()) && n.Desc() == "" {
+		l.report(LintDescription, n, path, "%s has no description", n)
+	}
+
+	for _, ch := range n.Children() {
+		chPath := lintPath(path, ch)
+		childConfig, childInData := config, inData
+		switch t := ch.Type(); {
+		case t == parse.NodeGrouping, t == parse.NodeAugment,
+			t == parse.NodeDeviation:
+			childInData = false
+		case t == parse.NodeRpc, t == parse.NodeAction,
+			t == parse.NodeNotification:
+			childInData = false
+			l.operationConfig(ch, chPath)
+		case t.IsDataNode() && ch.HasConfig():
+			childConfig = ch.Config()
+			if inData && childConfig == config {
+				l.report(LintConfigStmt, ch.ChildByType(parse.NodeConfig), chPath,
+					"config %t is inherited, so need not be given", childConfig)
+			}
+		}
+		l.statements(ch, chPath, childConfig, childInData)
+	}
+}
+
+// lintPath returns the statements leading to n, from those leading to its
+// parent.
+func lintPath(path []string, n parse.Node) []string {
+	return append(path[:len(path):len(path)], n.String())
+}
+
+// Config is ignored in the input and output of operations and in
+// notifications, so giving it suggests a misunderstanding.
+func (l *linter) operationConfig(op parse.Node, path []string) {
+	l.ignoredConfig(op, op, path)
+}
+
+func (l *linter) ignoredConfig(op, n parse.Node, path []string) {
+	for _, ch := range n.Children() {
+		chPath := lintPath(path, ch)
+		if ch.Type().IsDataNode() && ch.HasConfig() {
+			l.report(LintConfigStmt, ch.ChildByType(parse.NodeConfig), chPath,
+				"config is ignored within %s", op)
+		}
+		if ch.Type() == parse.NodeGrouping {
+			continue
+		}
+		l.ignoredConfig(op, ch, chPath)
+	}
+}
+
+// unused reports groupings and typedefs defined within other definitions
+// but not used. Those at the top level of a module may be used by other
+// modules, so are not reported.
+func (l *linter) unused(root parse.Node, path []string, prefix string) {
+	used := make(map[parse.Node]bool)
+	var findUses func(n parse.Node)
+	findUses = func(n parse.Node) {
+		for _, ch := range n.Children() {
+			switch ch.Type() {
+			case parse.NodeUses, parse.NodeTyp:
+				ref := ch.Name()
+				if idx := strings.Index(ref, ":"); idx != -1 {
+					if ref[:idx] != prefix {
+						break
+					}
+					ref = ref[idx+1:]
+				}
+				var def parse.Node
+				var ok bool
+				if ch.Type() == parse.NodeUses {
+					def, ok = ch.LookupGrouping(ref)
+				} else {
+					def, ok = ch.LookupType(ref)
+				}
+				if ok {
+					used[def] = true
+				}
+			}
+			findUses(ch)
+		}
+	}
+	findUses(root)
+
+	var check func(n parse.Node, path []string, top bool)
+	check = func(n parse.Node, path []string, top bool) {
+		for _, ch := range n.Children() {
+			if !top && !used[ch] {
+				switch ch.Type() {
+				case parse.NodeGrouping:
+					l.report(LintUnusedGrouping, ch, path,
+						"grouping %s is not used", ch.Name())
+				case parse.NodeTypedef:
+					l.report(LintUnusedTypedef, ch, path,
+						"typedef %s is not used", ch.Name())
+				}
+			}
+			check(ch, lintPath(path, ch), false)
+		}
+	}
+	check(root, path, true)
+}
+
+// LintModelSet checks the compiled schema against the rules that apply to
+// the schema as a whole. The modules, as returned by CompileDirKeepMods,
+// give the locations of the nodes, and may be nil.
+func LintModelSet(ms schema.ModelSet, modules map[string]*parse.Module, cfg *LintConfig) Diagnostics {
+	l := &linter{cfg: cfg}
+	for _, n := range schema.SchemaChildren(ms) {
+		if !n.Config() || !isMandatoryNode(n) {
+			continue
+		}
+		stmt := findSchemaStatement(modules,
+			schema.SchemaPath{{Module: n.Module(), Name: n.Name()}})
+		var path []string
+		if mod, ok := modules[n.Module()]; ok && stmt != nil {
+			path = []string{mod.GetModule().String(), stmt.String()}
+		}
+		if l.report(LintTopMandatory, stmt, path,
+			"top-level %s %s is mandatory", schema.NodeKind(n), n.Name()) {
+			l.diags[len(l.diags)-1].Module = n.Module()
+		}
+	}
+	return l.diags
+}
+
+// CompileDirWithLint compiles as CompileDirWithDiagnostics does, and also
+// reports the findings of the lint rules enabled by lint. The model set is
+// nil if there are any errors.
+func CompileDirWithLint(extensions Extensions, cfg *Config, lint *LintConfig,
+) (schema.ModelSet, Diagnostics) {
+	mods, err := getMods(extensions, cfg)
+	if err != nil {
+		return nil, DiagnosticsFromError(err)
+	}
+	diags := LintParseTrees(mods, lint)
+
+	modules, submodules := parse.GetModulesAndSubmodules(mods)
+	ms, warns, err := compileInternal(extensions, modules, submodules,
+		cfg.features(), cfg.SkipUnknown, genWarnings, cfg.Filter,
+		cfg.UserFnCheckFn, cfg.CollectErrors)
+	diags = append(diags, DiagnosticsFromError(err)...)
+	for _, w := range warns {
+		diags = append(diags, DiagnosticFromWarning(w))
+	}
+	if err != nil {
+		return nil, diags
+	}
+	return ms, append(diags, LintModelSet(ms, modules, lint)...)
+}
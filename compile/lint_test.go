@@ -0,0 +1,188 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+)
+
+const lintBaseModule = `module lint-base {
+	namespace "urn:lint-base";
+	prefix lb;
+
+	description "Base module";
+
+	revision 2024-01-01 {
+		description "Initial revision.";
+		reference "None";
+	}
+
+	typedef percent {
+		description "A percentage";
+		type uint8 {
+			range "0..100";
+		}
+	}
+}`
+
+const lintModule = `module lint {
+	namespace "urn:lint-base";
+	prefix Lint;
+
+	import lint-base {
+		prefix base;
+	}
+
+	revision 2024-02-01 {
+		reference "None";
+	}
+
+	container top {
+		description "Top";
+		config true;
+
+		typedef unused-type {
+			description "Not used";
+			type string;
+		}
+		typedef used-type {
+			description "Used";
+			type base:percent;
+		}
+		grouping unused-group {
+			description "Not used";
+			leaf x {
+				description "X";
+				type string;
+			}
+		}
+
+		leaf level {
+			description "Level";
+			type used-type;
+			mandatory true;
+		}
+		container state {
+			description "State";
+			config false;
+			leaf counter {
+				description "Counter";
+				type uint32;
+				config false;
+			}
+		}
+	}
+
+	rpc reset {
+		description "Reset";
+		input {
+			leaf force {
+				description "Force";
+				type boolean;
+				config true;
+			}
+		}
+	}
+}`
+
+func lintDiagnostics(t *testing.T, lint *compile.LintConfig) []string {
+	t.Helper()
+	_, diags := compile.CompileDirWithLint(nil, &compile.Config{
+		Repository: compile.MapRepository(map[string][]byte{
+			"lint-base@2024-01-01.yang": []byte(lintBaseModule),
+			"lint@2024-01-01.yang":      []byte(lintModule),
+		}),
+		Features: compile.FeaturesFromNames(true),
+	}, lint)
+	var act []string
+	for _, d := range diags {
+		act = append(act, fmt.Sprintf("%s:%d:%d: %s %s: %s",
+			d.File, d.Line, d.Column, d.Severity, d.Code, d.Message))
+	}
+	return act
+}
+
+func TestCompileDirWithLint(t *testing.T) {
+	expected := []string{
+		"lint-base@2024-01-01.yang:2:1: warning lint-namespace: " +
+			"namespace urn:lint-base is also used by module lint",
+		"lint@2024-01-01.yang:3:1: warning lint-prefix: " +
+			"prefix Lint is not lower case letters, digits and hyphens",
+		"lint@2024-01-01.yang:5:1: warning lint-prefix: " +
+			"module lint-base is imported with prefix base instead of its own prefix lb",
+		"lint@2024-01-01.yang:9:1: warning lint-revision: revision has no description",
+		"lint@2024-01-01.yang:9:1: warning lint-revision: " +
+			"revision 2024-01-01 in file name is not the latest revision 2024-02-01",
+		"lint@2024-01-01.yang:1:0: warning lint-description: module lint has no description",
+		"lint@2024-01-01.yang:15:2: warning lint-config: " +
+			"config true is inherited, so need not be given",
+		"lint@2024-01-01.yang:44:4: warning lint-config: " +
+			"config false is inherited, so need not be given",
+		"lint@2024-01-01.yang:55:4: warning lint-config: config is ignored within rpc reset",
+		"lint@2024-01-01.yang:17:2: warning lint-unused-typedef: typedef unused-type is not used",
+		"lint@2024-01-01.yang:25:2: warning lint-unused-grouping: " +
+			"grouping unused-group is not used",
+		"lint@2024-01-01.yang:13:1: warning lint-top-mandatory: " +
+			"top-level container top is mandatory",
+	}
+
+	if act := lintDiagnostics(t, nil); strings.Join(act, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected diagnostics\nexpected:\n%s\nactual:\n%s",
+			strings.Join(expected, "\n"), strings.Join(act, "\n"))
+	}
+}
+
+func TestCompileDirWithLintConfig(t *testing.T) {
+	lint := &compile.LintConfig{
+		Disabled: map[compile.ErrorCode]bool{
+			compile.LintNamespace:      true,
+			compile.LintPrefix:         true,
+			compile.LintRevision:       true,
+			compile.LintDescription:    true,
+			compile.LintConfigStmt:     true,
+			compile.LintUnusedTypedef:  true,
+			compile.LintUnusedGrouping: true,
+		},
+		Severities: map[compile.ErrorCode]compile.Severity{
+			compile.LintTopMandatory: compile.SeverityError,
+		},
+	}
+	expected := []string{
+		"lint@2024-01-01.yang:13:1: error lint-top-mandatory: " +
+			"top-level container top is mandatory",
+	}
+	if act := lintDiagnostics(t, lint); strings.Join(act, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected diagnostics\nexpected:\n%s\nactual:\n%s",
+			strings.Join(expected, "\n"), strings.Join(act, "\n"))
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	seen := make(map[compile.ErrorCode]bool)
+	for _, rule := range compile.LintRules() {
+		if seen[rule.Code] || rule.Description == "" ||
+			rule.Severity != compile.SeverityWarning {
+			t.Errorf("Unexpected rule: %+v", rule)
+		}
+		seen[rule.Code] = true
+	}
+	if len(seen) != 9 {
+		t.Errorf("Expected 9 rules, got %d", len(seen))
+	}
+}
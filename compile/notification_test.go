@@ -123,3 +123,132 @@ func TestNotificationListSuccess(t *testing.T) {
 		expected.check(t, actual)
 	}
 }
+
+func getNestedNotificationSchemaNode(
+	t *testing.T,
+	schema_text *bytes.Buffer,
+	path []string,
+	name string,
+) schema.Notification {
+	st, err := testutils.GetConfigSchema(schema_text.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error when parsing Notification schema: %s", err)
+	}
+
+	parent := st.Descendant(path)
+	if parent == nil {
+		t.Fatalf("Unable to find notification parent %v", path)
+	}
+	if actual := parent.NestedNotifications()[name]; actual != nil {
+		return actual
+	}
+
+	t.Errorf("Unable to find notification %s under %v", name, path)
+	return nil
+}
+
+func TestNotificationInContainer(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`container server {
+			leaf name {
+				type string;
+			}
+			notification restarted {
+				leaf reason {
+					type string;
+				}
+			}
+		}`))
+
+	expected := NotificationChecker{
+		NewTreeChecker("Notification", []NodeChecker{
+			NewLeafChecker("reason", CheckType("string")),
+		}),
+	}
+
+	if actual := getNestedNotificationSchemaNode(t, schema_text,
+		[]string{"server"}, "restarted"); actual != nil {
+		expected.check(t, actual)
+	}
+}
+
+func TestNotificationInListFromGroupingAndAugment(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`grouping link-events {
+			notification link-down;
+		}
+		list interface {
+			key name;
+			leaf name {
+				type string;
+			}
+			uses link-events;
+		}
+		augment /interface/link-down {
+			leaf since {
+				type uint64;
+			}
+		}`))
+
+	expected := NotificationChecker{
+		NewTreeChecker("Notification", []NodeChecker{
+			NewLeafChecker("since", CheckType("uint64")),
+		}),
+	}
+
+	if actual := getNestedNotificationSchemaNode(t, schema_text,
+		[]string{"interface"}, "link-down"); actual != nil {
+		expected.check(t, actual)
+	}
+}
+
+func TestNotificationDisabledByFeature(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`feature restarts;
+		container server {
+			notification restarted {
+				if-feature restarts;
+			}
+		}`))
+
+	st, err := testutils.GetConfigSchema(schema_text.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error when parsing Notification schema: %s", err)
+	}
+	if _, ok := st.Child("server").NestedNotifications()["restarted"]; ok {
+		t.Errorf("Notification with disabled feature should not be compiled")
+	}
+}
+
+func TestNotificationNotAllowedInNotification(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		SchemaTemplate,
+		`notification event {
+			container data {
+				notification nested;
+			}
+		}`))
+
+	_, err := testutils.GetConfigSchema(schema_text.Bytes())
+	assertErrorContains(t, err,
+		"notification not allowed within notification event")
+}
+
+func TestNotificationNotAllowedInKeylessList(t *testing.T) {
+	err := compileWithoutListKey(t,
+		`container state {
+			config false;
+			list entries {
+				key name;
+				leaf name {
+					type string;
+				}
+				notification changed;
+			}
+		}`, "entries")
+	assertErrorContains(t, err,
+		"notification not allowed within list entries without a key")
+}
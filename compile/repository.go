@@ -0,0 +1,316 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sdcio/yang-parser/parse"
+)
+
+// ModuleRepository holds the YANG files for a set of modules and
+// submodules. Files are named after the module, optionally followed by
+// '@' and the revision, as described in RFC 7950 section 5.2. Files with
+// a ".yin" suffix rather than ".yang" are in YIN syntax.
+//
+// A YangLocator, such as YangDirs(), is a repository of files on the OS
+// filesystem, FSRepository() covers an fs.FS such as an embed.FS or a zip
+// archive, and MapRepository() holds files in memory.
+type ModuleRepository interface {
+	// Modules returns every file in the repository, in search order.
+	Modules() ([]ModuleSource, error)
+
+	// Find returns the file for the named module or submodule. An empty
+	// revision selects the latest revision. A file without a revision in
+	// its name is used when there is no better match, so the caller must
+	// check the revision it contains.
+	Find(name, revision string) (ModuleSource, error)
+}
+
+// ModuleSource is a YANG file within a ModuleRepository.
+type ModuleSource struct {
+	Name     string
+	Revision string
+	File     string
+	read     func() ([]byte, error)
+}
+
+func (m ModuleSource) Text() ([]byte, error) {
+	return m.read()
+}
+
+// moduleFileName returns the name of a module file without its suffix, or
+// false if the name is not that of a YANG or YIN file.
+func moduleFileName(file string) (string, bool) {
+	if name, ok := strings.CutSuffix(file, ".yang"); ok {
+		return name, true
+	}
+	return strings.CutSuffix(file, ".yin")
+}
+
+// parseModuleSource parses a YANG file, or a YIN file by its suffix. The
+// modules a YIN file imports are found in repo, if given, for the arguments
+// of their extensions.
+func parseModuleSource(
+	src ModuleSource,
+	repo ModuleRepository,
+	extCard parse.NodeCardinality,
+	stringInterner *parse.StringInterner,
+	argInterner *parse.ArgInterner,
+) (*parse.Tree, error) {
+	text, err := src.Text()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !strings.HasSuffix(src.File, ".yin") {
+		return parse.ParseWithInterners(
+			src.File, string(text), extCard, stringInterner, argInterner)
+	}
+	modules, err := yinImportedModules(src.File, string(text),
+		repo, extCard, stringInterner, argInterner)
+	if err != nil {
+		return nil, err
+	}
+	return parse.ParseYinWithModules(
+		src.File, string(text), extCard, modules, stringInterner, argInterner)
+}
+
+// yinImportedModules parses the modules imported by a YIN file that are
+// found in repo. Only their extension statements are used, so their own
+// imports are not looked for.
+func yinImportedModules(
+	file, text string,
+	repo ModuleRepository,
+	extCard parse.NodeCardinality,
+	stringInterner *parse.StringInterner,
+	argInterner *parse.ArgInterner,
+) (map[string]*parse.Tree, error) {
+	if repo == nil {
+		return nil, nil
+	}
+	imports, err := parse.YinImports(file, text)
+	if err != nil {
+		return nil, err
+	}
+	modules := make(map[string]*parse.Tree, len(imports))
+	for name, rev := range imports {
+		src, err := repo.Find(name, rev)
+		if err != nil {
+			// Left for the compiler to report where it is imported
+			continue
+		}
+		t, err := parseModuleSource(
+			src, nil, extCard, stringInterner, argInterner)
+		if err != nil {
+			return nil, err
+		}
+		modules[name] = t
+	}
+	return modules, nil
+}
+
+func newModuleSource(file string, read func() ([]byte, error)) ModuleSource {
+	name, _ := moduleFileName(path.Base(filepath.ToSlash(file)))
+	var rev string
+	if idx := strings.Index(name, "@"); idx != -1 {
+		name, rev = name[:idx], name[idx+1:]
+	}
+	return ModuleSource{Name: name, Revision: rev, File: file, read: read}
+}
+
+func findModule(
+	repo ModuleRepository,
+	name, revision string,
+) (ModuleSource, error) {
+	mods, err := repo.Modules()
+	if err != nil {
+		return ModuleSource{}, err
+	}
+
+	var found, unrevisioned *ModuleSource
+	for i := range mods {
+		m := &mods[i]
+		switch {
+		case m.Name != name:
+		case m.Revision == "":
+			if unrevisioned == nil {
+				unrevisioned = m
+			}
+		case revision != "":
+			if m.Revision == revision && found == nil {
+				found = m
+			}
+		case found == nil || found.Revision < m.Revision:
+			found = m
+		}
+	}
+	if found == nil {
+		found = unrevisioned
+	}
+	if found == nil {
+		if revision != "" {
+			return ModuleSource{}, fmt.Errorf(
+				"revision %s of module %s not found", revision, name)
+		}
+		return ModuleSource{}, fmt.Errorf("module %s not found", name)
+	}
+	return *found, nil
+}
+
+func (l YangLocator) Modules() ([]ModuleSource, error) {
+	files, err := l()
+	if err != nil {
+		return nil, err
+	}
+	mods := make([]ModuleSource, 0, len(files))
+	for _, file := range files {
+		mods = append(mods, newModuleSource(file, func() ([]byte, error) {
+			return os.ReadFile(file)
+		}))
+	}
+	return mods, nil
+}
+
+func (l YangLocator) Find(name, revision string) (ModuleSource, error) {
+	return findModule(l, name, revision)
+}
+
+type fsRepository struct {
+	fsys fs.FS
+	dirs []string
+
+	once sync.Once
+	mods []ModuleSource
+	err  error
+}
+
+// FSRepository returns a repository of the YANG files in each of the
+// given directories of fsys, searched in order. The root of fsys is used
+// if no directories are given. As with YangDirs(), a directory that does
+// not exist is skipped, but any other error reading a directory is
+// returned. The directories are read once, when first searched.
+func FSRepository(fsys fs.FS, dirs ...string) ModuleRepository {
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	return &fsRepository{fsys: fsys, dirs: dirs}
+}
+
+// ZipRepository returns a repository of the YANG files in each of the given
+// directories of a zip archive.
+func ZipRepository(r *zip.Reader, dirs ...string) ModuleRepository {
+	return FSRepository(r, dirs...)
+}
+
+func (r *fsRepository) Modules() ([]ModuleSource, error) {
+	r.once.Do(func() { r.mods, r.err = r.readModules() })
+	return r.mods, r.err
+}
+
+func (r *fsRepository) readModules() ([]ModuleSource, error) {
+	mods := make([]ModuleSource, 0)
+	for _, dir := range r.dirs {
+		entries, err := fs.ReadDir(r.fsys, dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if _, ok := moduleFileName(entry.Name()); entry.IsDir() || !ok {
+				continue
+			}
+			file := path.Join(dir, entry.Name())
+			mods = append(mods, newModuleSource(file, func() ([]byte, error) {
+				return fs.ReadFile(r.fsys, file)
+			}))
+		}
+	}
+	return mods, nil
+}
+
+func (r *fsRepository) Find(name, revision string) (ModuleSource, error) {
+	return findModule(r, name, revision)
+}
+
+type mapRepository map[string][]byte
+
+// MapRepository returns a repository holding the given YANG text for each
+// file name.
+func MapRepository(files map[string][]byte) ModuleRepository {
+	return mapRepository(files)
+}
+
+func (r mapRepository) Modules() ([]ModuleSource, error) {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mods := make([]ModuleSource, 0, len(names))
+	for _, name := range names {
+		text := r[name]
+		mods = append(mods, newModuleSource(name, func() ([]byte, error) {
+			return text, nil
+		}))
+	}
+	return mods, nil
+}
+
+func (r mapRepository) Find(name, revision string) (ModuleSource, error) {
+	return findModule(r, name, revision)
+}
+
+type repositories []ModuleRepository
+
+// Repositories combines several repositories into one, searched in the
+// order given. Nil repositories are ignored.
+func Repositories(repos ...ModuleRepository) ModuleRepository {
+	out := make(repositories, 0, len(repos))
+	for _, repo := range repos {
+		if repo != nil {
+			out = append(out, repo)
+		}
+	}
+	return out
+}
+
+func (r repositories) Modules() ([]ModuleSource, error) {
+	mods := make([]ModuleSource, 0)
+	for _, repo := range r {
+		m, err := repo.Modules()
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, m...)
+	}
+	return mods, nil
+}
+
+func (r repositories) Find(name, revision string) (ModuleSource, error) {
+	return findModule(r, name, revision)
+}
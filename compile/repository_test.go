@@ -0,0 +1,257 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/parse"
+)
+
+const repoModule = `module repo {
+	namespace "urn:repo";
+	prefix repo;
+	feature extra;
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf extra {
+			if-feature extra;
+			type string;
+		}
+	}
+}`
+
+func TestRepositoryFind(t *testing.T) {
+	repo := compile.MapRepository(map[string][]byte{
+		"remote@2020-01-01.yang": []byte(remoteRevOld),
+		"remote@2021-06-01.yang": []byte(remoteRevNew),
+		"local.yang":             []byte(repoModule),
+	})
+
+	for _, test := range []struct {
+		name, revision, file string
+	}{
+		{"remote", "", "remote@2021-06-01.yang"},
+		{"remote", "2020-01-01", "remote@2020-01-01.yang"},
+		{"local", "", "local.yang"},
+		{"local", "2020-01-01", "local.yang"},
+	} {
+		src, err := repo.Find(test.name, test.revision)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+			continue
+		}
+		if src.File != test.file {
+			t.Errorf("Find(%s, %s): expected %s, got %s",
+				test.name, test.revision, test.file, src.File)
+		}
+	}
+
+	_, err := repo.Find("remote", "2019-01-01")
+	assertErrorContains(t, err, "revision 2019-01-01 of module remote not found")
+	_, err = repo.Find("missing", "")
+	assertErrorContains(t, err, "module missing not found")
+}
+
+func TestRepositoriesSearchOrder(t *testing.T) {
+	repo := compile.Repositories(
+		compile.MapRepository(map[string][]byte{
+			"first/repo.yang": []byte(repoModule)}),
+		nil,
+		compile.MapRepository(map[string][]byte{
+			"second/repo.yang": []byte(repoModule)}),
+	)
+
+	src, err := repo.Find("repo", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if src.File != "first/repo.yang" {
+		t.Errorf("Expected first repository to be searched first, got %s",
+			src.File)
+	}
+}
+
+func compileRepository(t *testing.T, repo compile.ModuleRepository) {
+	ms, err := compile.CompileDir(nil, &compile.Config{
+		Repository: repo,
+		Filter:     compile.IsConfig,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if ms.Child("top") == nil {
+		t.Errorf("Module from repository not compiled")
+	}
+}
+
+func TestFSRepository(t *testing.T) {
+	fsys := fstest.MapFS{
+		"models/yang/repo.yang": {Data: []byte(repoModule)},
+		"models/yang/README":    {Data: []byte("not yang")},
+	}
+	compileRepository(t, compile.FSRepository(fsys, "models/yang", "missing"))
+}
+
+// readDirFS counts the directories read, failing those named in errs
+type readDirFS struct {
+	fstest.MapFS
+	reads int
+	errs  map[string]error
+}
+
+func (f *readDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f.reads++
+	if err, ok := f.errs[name]; ok {
+		return nil, err
+	}
+	return f.MapFS.ReadDir(name)
+}
+
+func TestFSRepositoryReadsOnce(t *testing.T) {
+	fsys := &readDirFS{MapFS: fstest.MapFS{
+		"yang/repo.yang":  {Data: []byte(repoModule)},
+		"yang/other.yang": {Data: []byte(repoModule)},
+	}}
+	repo := compile.FSRepository(fsys, "yang")
+	for _, name := range []string{"repo", "other", "repo"} {
+		if _, err := repo.Find(name, ""); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if fsys.reads != 1 {
+		t.Errorf("Expected directory to be read once, read %d times", fsys.reads)
+	}
+}
+
+func TestFSRepositoryReadError(t *testing.T) {
+	fsys := &readDirFS{
+		MapFS: fstest.MapFS{"yang/repo.yang": {Data: []byte(repoModule)}},
+		errs:  map[string]error{"locked": fs.ErrPermission},
+	}
+	repo := compile.FSRepository(fsys, "yang", "locked")
+	if _, err := repo.Modules(); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+	if _, err := repo.Find("repo", ""); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+}
+
+func TestZipRepository(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("yang/repo.yang")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.Write([]byte(repoModule))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	compileRepository(t, compile.ZipRepository(r, "yang"))
+}
+
+func TestYinRepository(t *testing.T) {
+	tree, err := parse.Parse("repo.yang", repoModule, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var yin bytes.Buffer
+	if err := parse.WriteYin(&yin, tree, nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	compileRepository(t, compile.MapRepository(map[string][]byte{
+		"repo.yin": yin.Bytes()}))
+}
+
+func TestYinRepositoryImportedExtension(t *testing.T) {
+	ext := `module ext {
+	namespace "urn:ext";
+	prefix ext;
+	extension marker;
+}`
+	yang := `module marked {
+	namespace "urn:marked";
+	prefix marked;
+	import ext {
+		prefix ext;
+	}
+	ext:marker {
+		ext:marker;
+	}
+}`
+	trees := make(map[string]*parse.Tree)
+	for name, text := range map[string]string{"ext": ext, "marked": yang} {
+		tree, err := parse.Parse(name+".yang", text, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		trees[name] = tree
+	}
+	var yin bytes.Buffer
+	if err := parse.WriteYin(&yin, trees["marked"], trees); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	parsed, err := compile.ParseYang(nil, compile.MapRepository(map[string][]byte{
+		"ext.yang":   []byte(ext),
+		"marked.yin": yin.Bytes(),
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var marker parse.Node
+	for _, ch := range parsed["marked"].Root.Children() {
+		if ch.Statement() == "ext:marker" {
+			marker = ch
+		}
+	}
+	if marker == nil || len(marker.Children()) != 1 ||
+		marker.Argument().String() != "" {
+		t.Errorf("Extension substatement not kept:\n%s", yin.String())
+	}
+}
+
+func TestFeaturesFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"features/repo/extra": {},
+	}
+	ms, err := compile.CompileDir(nil, &compile.Config{
+		Repository: compile.MapRepository(map[string][]byte{
+			"repo.yang": []byte(repoModule)}),
+		Features: compile.FeaturesFromFS(true, fsys, "features"),
+		Filter:   compile.IsConfig,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if ms.Child("top").Child("extra") == nil {
+		t.Errorf("Feature from fs.FS not enabled")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compile_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/parse"
+)
+
+const remoteRevOld = `
+module remote {
+	namespace "urn:remote";
+	prefix remote;
+	revision 2020-01-01;
+	container old {
+		leaf value {
+			type string;
+		}
+	}
+}`
+
+const remoteRevNew = `
+module remote {
+	namespace "urn:remote";
+	prefix remote;
+	revision 2021-06-01;
+	revision 2020-01-01;
+	container new {
+		leaf value {
+			type string;
+		}
+	}
+}`
+
+const localImportTemplate = `
+module local {
+	namespace "urn:local";
+	prefix local;
+	import remote {
+		prefix remote;
+		%s
+	}
+	augment /remote:%s {
+		leaf extra {
+			type string;
+		}
+	}
+}`
+
+func writeRevisionFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, text := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644)
+		if err != nil {
+			t.Fatalf("Unable to write %s: %s", name, err)
+		}
+	}
+	return dir
+}
+
+func TestParseModuleDirSeveralRevisions(t *testing.T) {
+	dir := writeRevisionFiles(t, map[string]string{
+		"remote@2020-01-01.yang": remoteRevOld,
+		"remote@2021-06-01.yang": remoteRevNew,
+	})
+
+	trees, err := compile.ParseModuleDir(dir, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if rev := trees["remote"].Root.Revision(); rev != "2021-06-01" {
+		t.Errorf("Expected latest revision keyed by name, got %s", rev)
+	}
+	old, ok := trees[parse.RevisionKey("remote", "2020-01-01")]
+	if !ok || old.Root.Revision() != "2020-01-01" {
+		t.Errorf("Older revision not found: %v", trees)
+	}
+}
+
+func TestParseModuleDirDuplicateRevision(t *testing.T) {
+	dir := writeRevisionFiles(t, map[string]string{
+		"remote.yang":            remoteRevOld,
+		"remote@2020-01-01.yang": remoteRevOld,
+	})
+
+	_, err := compile.ParseModuleDir(dir, nil)
+	assertErrorContains(t, err, "module remote is already defined by file")
+}
+
+func compileRevisions(
+	t *testing.T,
+	revisionDate, container string,
+) error {
+	stmt := ""
+	if revisionDate != "" {
+		stmt = "revision-date " + revisionDate + ";"
+	}
+	dir := writeRevisionFiles(t, map[string]string{
+		"remote@2020-01-01.yang": remoteRevOld,
+		"remote@2021-06-01.yang": remoteRevNew,
+		"local.yang": fmt.Sprintf(
+			localImportTemplate, stmt, container),
+	})
+
+	_, err := compile.CompileDir(nil, &compile.Config{
+		YangDir: dir,
+		Filter:  compile.IsConfig,
+	})
+	return err
+}
+
+func TestImportLatestRevision(t *testing.T) {
+	if err := compileRevisions(t, "", "new"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	err := compileRevisions(t, "", "old")
+	assertErrorContains(t, err, "Invalid path: remote:old")
+}
+
+func TestImportRevisionDate(t *testing.T) {
+	if err := compileRevisions(t, "2020-01-01", "old"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := compileRevisions(t, "2021-06-01", "new"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestImportMissingRevision(t *testing.T) {
+	err := compileRevisions(t, "2019-01-01", "new")
+	assertErrorContains(t, err,
+		"revision 2019-01-01 of module remote not found, "+
+			"available revisions: 2021-06-01, 2020-01-01")
+}
+
+func TestImportConflictingRevisions(t *testing.T) {
+	dir := writeRevisionFiles(t, map[string]string{
+		"remote@2020-01-01.yang": remoteRevOld,
+		"remote@2021-06-01.yang": remoteRevNew,
+		"local.yang": fmt.Sprintf(localImportTemplate,
+			"revision-date 2020-01-01;", "old"),
+		"other.yang": `
+		module other {
+			namespace "urn:other";
+			prefix other;
+			import remote {
+				prefix remote;
+				revision-date 2021-06-01;
+			}
+		}`,
+	})
+
+	_, err := compile.CompileDir(nil, &compile.Config{
+		YangDir: dir,
+		Filter:  compile.IsConfig,
+	})
+	assertErrorContains(t, err,
+		"revision 2021-06-01 required by other conflicts with "+
+			"revision 2020-01-01 required by local")
+}
@@ -225,6 +225,218 @@ func TestBitsFail(t *testing.T) {
 	runTestCases(t, BitsFailTests)
 }
 
+var SubsetFailTests = []testutils.TestCase{
+	{
+		Description: "Enum subset: enum not in base",
+		Template:    ContainerTemplate,
+		Schema: `typedef colour {
+			type enumeration {
+				enum red;
+				enum green;
+			}
+		}
+		leaf testLeaf {
+			type colour {
+				enum blue;
+			}
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "enum blue is not defined in the base enumeration",
+	},
+	{
+		Description: "Enum subset: value differs from base",
+		Template:    ContainerTemplate,
+		Schema: `typedef colour {
+			type enumeration {
+				enum red;
+				enum green;
+			}
+		}
+		leaf testLeaf {
+			type colour {
+				enum green {
+					value 7;
+				}
+			}
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "enum green value 7 does not match base value 1",
+	},
+	{
+		Description: "Enum: duplicate value",
+		Template:    LeafTemplate,
+		Schema: `type enumeration {
+			enum red {
+				value 3;
+			}
+			enum green {
+				value 3;
+			}
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "enum green value 3 already used by enum red",
+	},
+	{
+		Description: "Bits subset: bit not in base",
+		Template:    ContainerTemplate,
+		Schema: `typedef flags {
+			type bits {
+				bit up;
+				bit running;
+			}
+		}
+		leaf testLeaf {
+			type flags {
+				bit loopback;
+			}
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "bit loopback is not defined in the base bits type",
+	},
+	{
+		Description: "Bits subset: position differs from base",
+		Template:    ContainerTemplate,
+		Schema: `typedef flags {
+			type bits {
+				bit up;
+				bit running;
+			}
+		}
+		leaf testLeaf {
+			type flags {
+				bit running {
+					position 0;
+				}
+			}
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "bit running position 0 does not match base position 1",
+	},
+	{
+		Description: "Bits: duplicate position",
+		Template:    LeafTemplate,
+		Schema: `type bits {
+			bit up {
+				position 4;
+			}
+			bit running {
+				position 4;
+			}
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "bit running position 4 already used by bit up",
+	},
+}
+
+func TestSubsetFail(t *testing.T) {
+	runTestCases(t, SubsetFailTests)
+}
+
+func TestEnumSubset(t *testing.T) {
+	schema_snippet := `
+	typedef colour {
+		type enumeration {
+			enum red;
+			enum green {
+				value 10;
+			}
+			enum blue;
+			enum black {
+				value 2;
+			}
+			enum white;
+		}
+	}
+	leaf paint {
+		type colour {
+			enum blue;
+			enum green {
+				value 10;
+			}
+		}
+	}
+	leaf any {
+		type colour;
+	}
+`
+	st := buildSchema(t, schema_snippet)
+
+	expAll := map[string]int{
+		"red": 0, "green": 10, "blue": 11, "black": 2, "white": 12}
+	all := st.Child("any").Type().(schema.Enumeration).Enums()
+	if len(all) != len(expAll) {
+		t.Fatalf("Expected %d enums, got %d", len(expAll), len(all))
+	}
+	for _, en := range all {
+		if en.Value != expAll[en.Val] {
+			t.Errorf("Enum %s: expected value %d, got %d",
+				en.Val, expAll[en.Val], en.Value)
+		}
+	}
+
+	typ := st.Child("paint").Type().(schema.Enumeration)
+	subset := typ.Enums()
+	if len(subset) != 2 ||
+		subset[0].Val != "blue" || subset[0].Value != 11 ||
+		subset[1].Val != "green" || subset[1].Value != 10 {
+		t.Errorf("Unexpected enum subset: %v", subset)
+	}
+	if err := typ.Validate(nil, []string{"paint"}, "blue"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	err := typ.Validate(nil, []string{"paint"}, "red")
+	assertErrorContains(t, err, OneOfValueErrStr)
+}
+
+func TestBitsSubset(t *testing.T) {
+	schema_snippet := `
+	typedef flags {
+		type bits {
+			bit up;
+			bit running {
+				position 8;
+			}
+			bit loopback;
+			bit broadcast {
+				position 3;
+			}
+		}
+	}
+	leaf state {
+		type flags {
+			bit loopback;
+			bit up {
+				position 0;
+			}
+		}
+	}
+	leaf any {
+		type flags;
+	}
+`
+	st := buildSchema(t, schema_snippet)
+
+	expAll := map[string]uint32{
+		"up": 0, "running": 8, "loopback": 9, "broadcast": 3}
+	all := st.Child("any").Type().(schema.Bits).Bits()
+	if len(all) != len(expAll) {
+		t.Fatalf("Expected %d bits, got %d", len(expAll), len(all))
+	}
+	for _, b := range all {
+		if b.Pos != expAll[b.Name] {
+			t.Errorf("Bit %s: expected position %d, got %d",
+				b.Name, expAll[b.Name], b.Pos)
+		}
+	}
+
+	subset := st.Child("state").Type().(schema.Bits).Bits()
+	if len(subset) != 2 ||
+		subset[0].Name != "loopback" || subset[0].Pos != 9 ||
+		subset[1].Name != "up" || subset[1].Pos != 0 {
+		t.Errorf("Unexpected bits subset: %v", subset)
+	}
+}
+
 var Dec64Passes = []testutils.TestCase{
 	{
 		Description: "Dec64: Passing Testcases",
@@ -469,6 +681,65 @@ func TestString(t *testing.T) {
 	runTestCases(t, StringPasses)
 }
 
+var StringFails = []testutils.TestCase{
+	{
+		Description: "String: invalid pattern modifier",
+		Template:    LeafTemplate,
+		Schema: `type string {
+			pattern "[a-z]*" {
+				modifier ignore-case;
+			}
+		}`,
+		ExpResult: false,
+		ExpErrMsg: "invalid argument: ignore-case",
+	},
+}
+
+func TestStringFail(t *testing.T) {
+	runTestCases(t, StringFails)
+}
+
+func TestStringPatternInvertMatch(t *testing.T) {
+	schema_snippet := `
+	typedef identifier {
+		type string {
+			pattern "[a-zA-Z_][a-zA-Z0-9_.-]*";
+			pattern "[xX][mM][lL].*" {
+				modifier invert-match;
+			}
+		}
+	}
+	leaf name {
+		type identifier;
+	}
+`
+	st := buildSchema(t, schema_snippet)
+	typ, ok := st.Child("name").Type().(schema.String)
+	if !ok {
+		t.Fatalf("name is not a string")
+	}
+
+	var inverted int
+	for _, ps := range typ.Pats() {
+		for _, p := range ps {
+			if p.InvertMatch {
+				inverted++
+			}
+		}
+	}
+	if inverted != 1 {
+		t.Errorf("Expected 1 inverted pattern, got %d", inverted)
+	}
+
+	if err := typ.Validate(nil, []string{"name"}, "interface"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	err := typ.Validate(nil, []string{"name"}, "xmlns")
+	assertErrorContains(t, err, "Matches inverted pattern [xX][mM][lL].*")
+	err = typ.Validate(nil, []string{"name"}, "9lives")
+	assertErrorContains(t, err, "Does not match pattern")
+}
+
 // For each Uint type, verify:
 //
 // - multiple ranges are accepted
@@ -50,3 +50,21 @@ func (n *datanode) YangDataValues() []string {
 func (n *datanode) YangDataValuesNoSorting() []string {
 	return n.values
 }
+
+type opaqueDataNode struct {
+	datanode
+	format  PayloadFormat
+	payload []byte
+}
+
+func CreateOpaqueDataNode(name string, format PayloadFormat, payload []byte) DataNode {
+	return &opaqueDataNode{datanode{name: name}, format, payload}
+}
+
+func (n *opaqueDataNode) YangDataPayload() []byte {
+	return n.payload
+}
+
+func (n *opaqueDataNode) YangDataPayloadFormat() PayloadFormat {
+	return n.format
+}
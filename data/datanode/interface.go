@@ -44,3 +44,24 @@ type DataNode interface {
 	YangDataValues() []string
 	YangDataValuesNoSorting() []string
 }
+
+// The encoding of the payload carried by an OpaqueDataNode
+type PayloadFormat int
+
+const (
+	PayloadJSON PayloadFormat = iota
+	PayloadXML
+)
+
+/*
+ * Data for anydata and anyxml nodes is not described by the schema, so it
+ * is carried as an opaque payload in the encoding it was received in.
+ * Such a node has no children or values of its own.
+ */
+type OpaqueDataNode interface {
+	DataNode
+
+	// The raw content of the node, excluding the node's own name
+	YangDataPayload() []byte
+	YangDataPayloadFormat() PayloadFormat
+}
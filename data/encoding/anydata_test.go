@@ -0,0 +1,211 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sdcio/yang-parser/data/datanode"
+	"github.com/sdcio/yang-parser/data/encoding"
+	"github.com/sdcio/yang-parser/schema"
+	"github.com/sdcio/yang-parser/testutils"
+)
+
+const anySchema = `
+module test-any {
+	yang-version 1.1;
+	namespace "urn:test:any";
+	prefix any;
+
+	container top {
+		leaf name {
+			type string;
+		}
+		anydata extra;
+		anyxml blob;
+	}
+}`
+
+func getAnySchema(t *testing.T) schema.Node {
+	ms, err := testutils.GetConfigSchema([]byte(anySchema))
+	if err != nil {
+		t.Fatalf("Unexpected error compiling schema: %s", err)
+	}
+	return ms.Child("top")
+}
+
+func findOpaque(t *testing.T, n datanode.DataNode, name string) datanode.OpaqueDataNode {
+	for _, ch := range n.YangDataChildren() {
+		if ch.YangDataName() != name {
+			continue
+		}
+		on, ok := ch.(datanode.OpaqueDataNode)
+		if !ok {
+			t.Fatalf("Node %s is not opaque", name)
+		}
+		return on
+	}
+	t.Fatalf("Node %s not found", name)
+	return nil
+}
+
+func TestAnydataSchemaNodes(t *testing.T) {
+	top := getAnySchema(t)
+	if _, ok := top.Child("extra").(schema.Anydata); !ok {
+		t.Errorf("extra is not an anydata node: %T", top.Child("extra"))
+	}
+	if _, ok := top.Child("blob").(schema.Anyxml); !ok {
+		t.Errorf("blob is not an anyxml node: %T", top.Child("blob"))
+	}
+}
+
+func TestAnydataJSONRoundTrip(t *testing.T) {
+	top := getAnySchema(t)
+	input := `{"extra":{"a":"1","b":{"c":[1,2]}},"name":"x"}`
+
+	dn, err := encoding.UnmarshalJSON(top, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if on := findOpaque(t, dn, "extra"); on.YangDataPayloadFormat() !=
+		datanode.PayloadJSON {
+		t.Errorf("Unexpected payload format %v", on.YangDataPayloadFormat())
+	}
+
+	if out := string(encoding.ToJSON(top, dn)); out != input {
+		t.Errorf("Unexpected JSON output\n  exp: %s\n  got: %s", input, out)
+	}
+}
+
+func TestAnydataRFC7951RoundTrip(t *testing.T) {
+	top := getAnySchema(t)
+	input := `{"test-any:extra":{"other:thing":"1"},"test-any:name":"x"}`
+
+	dn, err := encoding.UnmarshalRFC7951(top, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if out := string(encoding.ToRFC7951(top, dn)); out != input {
+		t.Errorf("Unexpected RFC7951 output\n  exp: %s\n  got: %s", input, out)
+	}
+}
+
+func TestAnydataPayloadVerbatim(t *testing.T) {
+	top := getAnySchema(t)
+	payload := `{"z":"1","a":12345678901234567890,"d":1.10,"e":[1e3, -0.0]}`
+	input := `{"extra":` + payload + `,"name":"x"}`
+
+	dn, err := encoding.UnmarshalJSON(top, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if on := findOpaque(t, dn, "extra"); string(on.YangDataPayload()) != payload {
+		t.Errorf("Unexpected payload: %s", on.YangDataPayload())
+	}
+
+	if out := string(encoding.ToJSON(top, dn)); out != input {
+		t.Errorf("Unexpected JSON output\n  exp: %s\n  got: %s", input, out)
+	}
+}
+
+func TestAnyxmlXMLRoundTrip(t *testing.T) {
+	top := getAnySchema(t)
+	payload := `<foo xmlns="urn:other"><bar>1</bar><bar>2</bar></foo>`
+	input := `<top xmlns="urn:test:any"><blob>` + payload +
+		`</blob><name>x</name></top>`
+
+	dn, err := encoding.UnmarshalXML(top, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if on := findOpaque(t, dn, "blob"); string(on.YangDataPayload()) != payload {
+		t.Errorf("Unexpected payload: %s", on.YangDataPayload())
+	}
+
+	out := string(encoding.ToXML(top, dn))
+	if !strings.Contains(out, `<blob xmlns="urn:test:any">`+payload+`</blob>`) {
+		t.Errorf("Opaque content not preserved: %s", out)
+	}
+
+	if js := string(encoding.ToJSON(top, dn)); !strings.Contains(js,
+		`"blob":{"foo":{"bar":["1","2"]}}`) {
+		t.Errorf("Unexpected JSON conversion: %s", js)
+	}
+}
+
+func TestAnyxmlAncestorNamespaces(t *testing.T) {
+	top := getAnySchema(t)
+	input := `<top xmlns="urn:test:any" xmlns:o="urn:other"><blob>` +
+		`<o:foo><o:bar>1</o:bar></o:foo><baz xmlns="urn:baz"/>` +
+		`</blob></top>`
+
+	dn, err := encoding.UnmarshalXML(top, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	exp := `<o:foo xmlns="urn:test:any" xmlns:o="urn:other">` +
+		`<o:bar>1</o:bar></o:foo>` +
+		`<baz xmlns:o="urn:other" xmlns="urn:baz"/>`
+	if on := findOpaque(t, dn, "blob"); string(on.YangDataPayload()) != exp {
+		t.Errorf("Unexpected payload\n  exp: %s\n  got: %s",
+			exp, on.YangDataPayload())
+	}
+}
+
+func TestAnydataJSONToXMLNamespaces(t *testing.T) {
+	top := getAnySchema(t)
+	input := `{"test-any:extra":{"test-any:a":{"b":"1"},"c":"2",` +
+		`"other:thing":{"d":"3"}}}`
+
+	dn, err := encoding.UnmarshalRFC7951(top, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Members keep their order. The namespace of the unknown module
+	// "other" cannot be found, so its members are in no namespace.
+	exp := `<extra xmlns="urn:test:any">` +
+		`<a xmlns="urn:test:any"><b xmlns="urn:test:any">1</b></a>` +
+		`<c xmlns="urn:test:any">2</c>` +
+		`<thing xmlns=""><d>3</d></thing></extra>`
+	if out := string(encoding.ToXML(top, dn)); !strings.Contains(out, exp) {
+		t.Errorf("Unexpected XML conversion\n  exp: %s\n  got: %s", exp, out)
+	}
+}
+
+func TestAnydataMandatory(t *testing.T) {
+	ms, err := testutils.GetConfigSchema([]byte(`
+module test-any {
+	namespace "urn:test:any";
+	prefix any;
+
+	container top {
+		anydata extra {
+			mandatory true;
+		}
+	}
+}`))
+	if err != nil {
+		t.Fatalf("Unexpected error compiling schema: %s", err)
+	}
+
+	_, err = encoding.UnmarshalJSON(ms.Child("top"), []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "Missing mandatory node extra") {
+		t.Errorf("Expected mandatory error, got: %v", err)
+	}
+}
@@ -25,7 +25,9 @@ package encoding
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/danos/encoding/rfc7951"
@@ -36,6 +38,38 @@ import (
 type JSONReader struct {
 	decodedName string
 	decodedMsg  interface{}
+
+	// The encoded form of the value is only needed for anydata and anyxml
+	// payloads, so it is located lazily from the parent's encoded form.
+	raw    json.RawMessage
+	parent *JSONReader
+	index  int // Position within a JSON array, or -1 for object members
+}
+
+func (jr *JSONReader) rawMsg() (json.RawMessage, error) {
+	if jr.raw != nil || jr.parent == nil {
+		return jr.raw, nil
+	}
+	parent, err := jr.parent.rawMsg()
+	if err != nil || parent == nil {
+		return nil, err
+	}
+	if jr.index >= 0 {
+		var list []json.RawMessage
+		if err := json.Unmarshal(parent, &list); err != nil {
+			return nil, err
+		}
+		if jr.index < len(list) {
+			jr.raw = list[jr.index]
+		}
+		return jr.raw, nil
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(parent, &members); err != nil {
+		return nil, err
+	}
+	jr.raw = members[jr.decodedName]
+	return jr.raw, nil
 }
 
 func (jr *JSONReader) name() string {
@@ -45,7 +79,7 @@ func (jr *JSONReader) name() string {
 	return jr.decodedName
 }
 
-func decodeValue(val interface{}) (string, error) {
+func decodeValue(val interface{}, typ schema.Type) (string, error) {
 	switch typeValue := val.(type) {
 	case string: // Non-empty Leaf containing string
 		return typeValue, nil
@@ -56,8 +90,10 @@ func decodeValue(val interface{}) (string, error) {
 		} else {
 			return "false", nil
 		}
-	case float64: // Non-empty Leaf containing number of any sort
-		return fmt.Sprintf("%d", int(typeValue)), nil
+	case json.Number: // Non-empty Leaf containing number of any sort
+		return canonicalNumber(string(typeValue), typ)
+	case rfc7951.Number:
+		return canonicalNumber(string(typeValue), typ)
 	case nil: // Empty leaf
 		return "", nil
 	default:
@@ -65,13 +101,89 @@ func decodeValue(val interface{}) (string, error) {
 	}
 }
 
-func (jr *JSONReader) values() ([]string, error) {
+// The largest exponent that a number may be given with. No more is needed
+// for any integer or decimal64 value, and a larger exponent would be
+// costly to expand.
+const maxNumberExponent = 64
+
+// canonicalNumber returns the canonical form of a JSON number for a leaf
+// of type typ, or an error if the number has no exact equivalent in an
+// integer or decimal64 type. Values out of range are left to the type's
+// validation to report. Numbers for other types are left as written.
+func canonicalNumber(num string, typ schema.Type) (string, error) {
+	switch t := typ.(type) {
+	case schema.Integer, schema.Uinteger:
+		r, ok := exactNumber(num)
+		if !ok || !r.IsInt() {
+			return "", schema.NewInexactNumberError(nil, num, t.Name().Local)
+		}
+		return r.Num().String(), nil
+	case schema.Decimal64:
+		r, ok := exactNumber(num)
+		if ok {
+			scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Fd())), nil)
+			r.Mul(r, new(big.Rat).SetInt(scale))
+		}
+		if !ok || !r.IsInt() {
+			return "", schema.NewInexactNumberError(nil, num, t.Name().Local)
+		}
+		return canonicalDecimal64(r.Num(), int(t.Fd())), nil
+	case schema.Union:
+		// As the value is validated against each type of a union in turn,
+		// the first numeric type that accepts it gives its form.
+		for _, member := range t.Typs() {
+			switch member.(type) {
+			case schema.Integer, schema.Uinteger, schema.Decimal64:
+				v, err := canonicalNumber(num, member)
+				if err == nil && member.Validate(nil, nil, v) == nil {
+					return v, nil
+				}
+			}
+		}
+	}
+	return num, nil
+}
+
+// exactNumber returns the exact value of a JSON number literal
+func exactNumber(num string) (*big.Rat, bool) {
+	if idx := strings.IndexAny(num, "eE"); idx != -1 {
+		exp, err := strconv.Atoi(num[idx+1:])
+		if err != nil || exp > maxNumberExponent || exp < -maxNumberExponent {
+			return nil, false
+		}
+	}
+	return new(big.Rat).SetString(num)
+}
+
+// canonicalDecimal64 returns the canonical form of RFC 7950 section 9.3.2
+// for the decimal64 value scaled by 10^fd, which has no leading or trailing
+// zeros, but at least one digit either side of the decimal point.
+func canonicalDecimal64(scaled *big.Int, fd int) string {
+	digits := new(big.Int).Abs(scaled).String()
+	if len(digits) <= fd {
+		digits = strings.Repeat("0", fd-len(digits)+1) + digits
+	}
+	point := len(digits) - fd
+	frac := strings.TrimRight(digits[point:], "0")
+	if frac == "" {
+		frac = "0"
+	}
+	sign := ""
+	if scaled.Sign() < 0 {
+		sign = "-"
+	}
+	return sign + digits[:point] + "." + frac
+}
+
+// Numbers are converted to the canonical form for the type of sn
+func (jr *JSONReader) values(sn schema.Node) ([]string, error) {
+	typ := sn.Type()
 
 	switch typeValue := jr.decodedMsg.(type) {
 	case []interface{}:
 		list := make([]string, 0, len(typeValue))
 		for _, v := range typeValue {
-			if val, e := decodeValue(v); e != nil {
+			if val, e := decodeValue(v, typ); e != nil {
 				return nil, e
 			} else {
 				list = append(list, val)
@@ -80,7 +192,7 @@ func (jr *JSONReader) values() ([]string, error) {
 		return list, nil
 
 	default:
-		v, e := decodeValue(jr.decodedMsg)
+		v, e := decodeValue(jr.decodedMsg, typ)
 		if e != nil {
 			return nil, e
 		}
@@ -93,19 +205,43 @@ func (jr *JSONReader) unserializedChildren(_ []string, sn schema.Node) ([]unseri
 
 	switch typeValue := jr.decodedMsg.(type) {
 	case map[string]interface{}: // Container
-		for k, v := range typeValue {
-			child := &JSONReader{decodedName: k, decodedMsg: v}
+		// Decode members in a stable order, so that the encoded output
+		// of the resulting tree does not vary from run to run.
+		names := make([]string, 0, len(typeValue))
+		for k := range typeValue {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			child := &JSONReader{decodedName: k, decodedMsg: typeValue[k],
+				parent: jr, index: -1}
 			children = append(children, child)
 		}
 	case []interface{}: // List or leaf-list
-		for _, listV := range typeValue {
-			child := &JSONReader{decodedName: jr.decodedName, decodedMsg: listV}
+		for i, listV := range typeValue {
+			child := &JSONReader{decodedName: jr.decodedName, decodedMsg: listV,
+				parent: jr, index: i}
 			children = append(children, child)
 		}
 	}
 	return children, nil
 }
 
+// The payload is kept exactly as received, so that numbers keep their
+// precision and representation, and members keep their order.
+func (jr *JSONReader) payload() (datanode.PayloadFormat, []byte, error) {
+	raw, err := jr.rawMsg()
+	if err != nil {
+		return datanode.PayloadJSON, nil, err
+	}
+	if raw == nil {
+		return datanode.PayloadJSON, nil, schema.NewMissingValueError(nil)
+	}
+	buf := make([]byte, len(raw))
+	copy(buf, raw)
+	return datanode.PayloadJSON, buf, nil
+}
+
 type ConfigOrState bool
 
 const (
@@ -175,16 +311,12 @@ func unmarshalJSONInternal(
 	enc EncType,
 ) (datanode.DataNode, error) {
 
-	jr := JSONReader{decodedName: sn.Name()}
-	if enc == RFC7951 {
-		if err := rfc7951.Unmarshal(json_input, &jr.decodedMsg); err != nil {
-			return nil, err
-		}
-	} else {
-		if err := json.Unmarshal(json_input, &jr.decodedMsg); err != nil {
-			return nil, err
-		}
+	jr := JSONReader{decodedName: sn.Name(), raw: json_input}
+	msg, err := decodeJSON(json_input, enc)
+	if err != nil {
+		return nil, err
 	}
+	jr.decodedMsg = msg
 
 	datatree, err := convertToDataNode([]string{}, sn.Name(), &jr, sn)
 	if err != nil {
@@ -201,6 +333,33 @@ func unmarshalJSONInternal(
 	return datatree, nil
 }
 
+// decodeJSON decodes the input as Unmarshal would, but with numbers kept as
+// written, so that they can be converted without loss for their leaf.
+func decodeJSON(input []byte, enc EncType) (interface{}, error) {
+	// Check the syntax first, so that errors are as Unmarshal gives
+	if enc == RFC7951 {
+		var raw rfc7951.RawMessage
+		if err := rfc7951.Unmarshal(input, &raw); err != nil {
+			return nil, err
+		}
+		var msg interface{}
+		dec := rfc7951.NewDecoder(bytes.NewReader(input))
+		dec.UseNumber()
+		err := dec.Decode(&msg)
+		return msg, err
+	}
+
+	var raw json.RawMessage
+	if err := json.Unmarshal(input, &raw); err != nil {
+		return nil, err
+	}
+	var msg interface{}
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	err := dec.Decode(&msg)
+	return msg, err
+}
+
 func (jw *JSONWriter) writeValue(sn schema.Node, value string) {
 	switch tt := sn.Type().(type) {
 	case schema.Empty:
@@ -249,6 +408,26 @@ func (jw *JSONWriter) writeNullLeafValue(sn schema.Node) {
 	jw.WriteString("null")
 }
 
+func (jw *JSONWriter) writeOpaqueValue(n datanode.DataNode) {
+	on, ok := n.(datanode.OpaqueDataNode)
+	if !ok || len(on.YangDataPayload()) == 0 {
+		jw.WriteString("{}")
+		return
+	}
+
+	switch on.YangDataPayloadFormat() {
+	case datanode.PayloadXML:
+		buf, err := xmlPayloadToJSON(on.YangDataPayload())
+		if err != nil {
+			// Not well-formed, so pass it on as a string
+			buf, _ = json.Marshal(string(on.YangDataPayload()))
+		}
+		jw.Write(buf)
+	default:
+		jw.Write(on.YangDataPayload())
+	}
+}
+
 func (jw *JSONWriter) PushName(sn schema.Node) string {
 	if jw.moduleName == nil {
 		jw.moduleName = make([]string, 0)
@@ -351,6 +530,9 @@ func (jw *JSONWriter) encodeJsonChildren(sn schema.Node, n datanode.DataNode) {
 				}
 			}
 			jw.WriteByte(']')
+
+		case schema.Anydata, schema.Anyxml:
+			jw.writeOpaqueValue(cn)
 		}
 		if jw.rfc7951 {
 			jw.PopName()
@@ -0,0 +1,76 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"github.com/sdcio/yang-parser/data/datanode"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+// FindNestedNotification returns the notification identified by path,
+// which is the instance path of the data node the notification is defined
+// in, followed by the notification name. As elsewhere, a list entry is
+// given as the list name followed by its key value, and key values are
+// validated.
+func FindNestedNotification(
+	sn schema.Node,
+	path []string,
+) (schema.Notification, error) {
+	if len(path) == 0 {
+		return nil, schema.NewInvalidPathError(path)
+	}
+
+	parent := sn
+	last := len(path) - 1
+	for i, elem := range path[:last] {
+		ch := parent.Child(elem)
+		if ch == nil {
+			return nil, schema.NewSchemaMismatchError(elem, path[:i])
+		}
+		if entry, ok := ch.(schema.ListEntry); ok {
+			key := entry.Child(entry.Keys()[0])
+			if err := key.Validate(nil, path[:i], []string{elem}); err != nil {
+				return nil, err
+			}
+		}
+		parent = ch
+	}
+
+	if l, ok := parent.(schema.List); ok {
+		// A notification is bound to a list entry, not the list itself
+		return nil, schema.NewMissingKeyError(l.Keys())
+	}
+	notif, ok := parent.NestedNotifications()[path[last]]
+	if !ok {
+		return nil, schema.NewSchemaMismatchError(path[last], path[:last])
+	}
+	return notif, nil
+}
+
+// UnmarshalNotification unmarshals, and validates according to u, the
+// payload of a notification defined within a data node. See
+// FindNestedNotification for the form of path.
+func UnmarshalNotification(
+	u Unmarshaller,
+	sn schema.Node,
+	path []string,
+	input []byte,
+) (datanode.DataNode, error) {
+	notif, err := FindNestedNotification(sn, path)
+	if err != nil {
+		return nil, err
+	}
+	return u.Unmarshal(notif.Schema(), input)
+}
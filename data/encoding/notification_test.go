@@ -0,0 +1,134 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sdcio/yang-parser/data/encoding"
+	"github.com/sdcio/yang-parser/schema"
+	"github.com/sdcio/yang-parser/testutils"
+)
+
+const notifSchema = `
+module test-notif {
+	yang-version 1.1;
+	namespace "urn:test:notif";
+	prefix notif;
+
+	container interfaces {
+		list interface {
+			key name;
+			leaf name {
+				type string {
+					length 1..8;
+				}
+			}
+			notification link-down {
+				leaf reason {
+					type enumeration {
+						enum admin;
+						enum carrier;
+					}
+				}
+				leaf since {
+					mandatory true;
+					type uint64;
+				}
+			}
+		}
+	}
+}`
+
+func getNotifSchema(t *testing.T) schema.ModelSet {
+	ms, err := testutils.GetConfigSchema([]byte(notifSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error compiling schema: %s", err)
+	}
+	return ms
+}
+
+func TestUnmarshalNestedNotification(t *testing.T) {
+	ms := getNotifSchema(t)
+	path := []string{"interfaces", "interface", "eth0", "link-down"}
+
+	dn, err := encoding.UnmarshalNotification(
+		encoding.NewUnmarshaller(encoding.JSON), ms, path,
+		[]byte(`{"reason":"carrier","since":"12"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(dn.YangDataChildren()) != 2 {
+		t.Errorf("Unexpected notification content: %v", dn.YangDataChildren())
+	}
+
+	_, err = encoding.UnmarshalNotification(
+		encoding.NewUnmarshaller(encoding.XML), ms, path,
+		[]byte(`<link-down xmlns="urn:test:notif">`+
+			`<reason>admin</reason><since>3</since></link-down>`))
+	if err != nil {
+		t.Fatalf("Unexpected error unmarshalling XML: %s", err)
+	}
+}
+
+func TestUnmarshalNestedNotificationInvalid(t *testing.T) {
+	ms := getNotifSchema(t)
+	u := encoding.NewUnmarshaller(encoding.JSON)
+
+	for _, tc := range []struct {
+		desc   string
+		path   []string
+		input  string
+		expErr string
+	}{
+		{
+			desc:   "Invalid payload value",
+			path:   []string{"interfaces", "interface", "eth0", "link-down"},
+			input:  `{"reason":"unknown","since":"1"}`,
+			expErr: "Must have one of the following values: admin, carrier",
+		},
+		{
+			desc:   "Missing mandatory payload node",
+			path:   []string{"interfaces", "interface", "eth0", "link-down"},
+			input:  `{"reason":"admin"}`,
+			expErr: "Missing mandatory node since",
+		},
+		{
+			desc:   "Invalid key value",
+			path:   []string{"interfaces", "interface", "too-long-name", "link-down"},
+			input:  `{"since":"1"}`,
+			expErr: "Must have length between 1 and 8 characters",
+		},
+		{
+			desc:   "Missing list key",
+			path:   []string{"interfaces", "interface", "link-down"},
+			input:  `{"since":"1"}`,
+			expErr: "List entry is missing key",
+		},
+		{
+			desc:   "Unknown notification",
+			path:   []string{"interfaces", "interface", "eth0", "link-up"},
+			input:  `{"since":"1"}`,
+			expErr: "link-up",
+		},
+	} {
+		_, err := encoding.UnmarshalNotification(u, ms, tc.path, []byte(tc.input))
+		if err == nil || !strings.Contains(err.Error(), tc.expErr) {
+			t.Errorf("%s: expected error containing %q, got: %v",
+				tc.desc, tc.expErr, err)
+		}
+	}
+}
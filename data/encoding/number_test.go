@@ -0,0 +1,174 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sdcio/yang-parser/data/datanode"
+	"github.com/sdcio/yang-parser/data/encoding"
+	"github.com/sdcio/yang-parser/schema"
+	"github.com/sdcio/yang-parser/testutils"
+)
+
+const numberSchema = `
+module test-number {
+	yang-version 1.1;
+	namespace "urn:test:number";
+	prefix num;
+
+	container top {
+		leaf big {
+			type uint64;
+		}
+		leaf small {
+			type int64;
+		}
+		leaf ratio {
+			type decimal64 {
+				fraction-digits 3;
+			}
+		}
+		leaf name {
+			type string;
+		}
+		leaf either {
+			type union {
+				type int8;
+				type decimal64 {
+					fraction-digits 2;
+				}
+			}
+		}
+		leaf-list counts {
+			type uint8;
+		}
+		list entry {
+			key id;
+			leaf id {
+				type uint8;
+			}
+		}
+	}
+}`
+
+func getNumberSchema(t *testing.T) schema.Node {
+	ms, err := testutils.GetConfigSchema([]byte(numberSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error compiling schema: %s", err)
+	}
+	return ms.Child("top")
+}
+
+func leafValues(dn datanode.DataNode, name string) []string {
+	for _, ch := range dn.YangDataChildren() {
+		if ch.YangDataName() == name {
+			return ch.YangDataValues()
+		}
+	}
+	return nil
+}
+
+func TestUnmarshalJSONNumbers(t *testing.T) {
+	top := getNumberSchema(t)
+	for _, test := range []struct {
+		name, input, leaf string
+		expected          []string
+	}{
+		{"uint64 max", `{"big":18446744073709551615}`, "big",
+			[]string{"18446744073709551615"}},
+		{"int64 min", `{"small":-9223372036854775808}`, "small",
+			[]string{"-9223372036854775808"}},
+		{"integer exponent", `{"small":1.5e3}`, "small", []string{"1500"}},
+		{"integer point", `{"small":12.000}`, "small", []string{"12"}},
+		{"decimal64", `{"ratio":1.25}`, "ratio", []string{"1.25"}},
+		{"decimal64 zeros", `{"ratio":-1.500}`, "ratio", []string{"-1.5"}},
+		{"decimal64 integer", `{"ratio":2}`, "ratio", []string{"2.0"}},
+		{"decimal64 small", `{"ratio":0.001}`, "ratio", []string{"0.001"}},
+		{"decimal64 zero", `{"ratio":-0}`, "ratio", []string{"0.0"}},
+		{"string", `{"name":1.50}`, "name", []string{"1.50"}},
+		{"union integer", `{"either":7}`, "either", []string{"7"}},
+		{"union decimal64", `{"either":7.10}`, "either", []string{"7.1"}},
+		{"leaf-list", `{"counts":[1,2.0,3e0]}`, "counts",
+			[]string{"1", "2", "3"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dn, err := encoding.UnmarshalJSON(top, []byte(test.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			act := leafValues(dn, test.leaf)
+			if strings.Join(act, " ") != strings.Join(test.expected, " ") {
+				t.Errorf("Unexpected values %q, expected %q", act, test.expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRFC7951Numbers(t *testing.T) {
+	top := getNumberSchema(t)
+	dn, err := encoding.UnmarshalRFC7951(top,
+		[]byte(`{"test-number:ratio":3.140,"test-number:big":"12345678901234567890"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if act := leafValues(dn, "ratio"); len(act) != 1 || act[0] != "3.14" {
+		t.Errorf("Unexpected ratio %q", act)
+	}
+	if act := leafValues(dn, "big"); len(act) != 1 || act[0] != "12345678901234567890" {
+		t.Errorf("Unexpected big %q", act)
+	}
+}
+
+func TestUnmarshalJSONInexactNumbers(t *testing.T) {
+	top := getNumberSchema(t)
+	for _, test := range []struct {
+		name, input, expected string
+	}{
+		{"integer fraction", `{"small":1.5}`,
+			"1.5 cannot be represented exactly as int64"},
+		{"decimal64 digits", `{"ratio":1.2345}`,
+			"1.2345 cannot be represented exactly as decimal64"},
+		{"huge exponent", `{"small":1e100000}`,
+			"1e100000 cannot be represented exactly as int64"},
+		{"uint64 overflow", `{"big":18446744073709551616}`, ""},
+		{"union", `{"either":1.234}`, ""},
+		{"list key", `{"entry":[{"id":1.5}]}`,
+			"1.5 cannot be represented exactly as uint8"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := encoding.UnmarshalJSON(top, []byte(test.input))
+			if err == nil {
+				t.Fatalf("Unexpected success")
+			}
+			if test.expected != "" && !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSONSyntaxError(t *testing.T) {
+	top := getNumberSchema(t)
+	for _, input := range []string{`{"small":1`, `{"small":1} x`} {
+		if _, err := encoding.UnmarshalJSON(top, []byte(input)); err == nil {
+			t.Errorf("Unexpected success for %s", input)
+		}
+		if _, err := encoding.UnmarshalRFC7951(top, []byte(input)); err == nil {
+			t.Errorf("Unexpected success for RFC 7951 %s", input)
+		}
+	}
+}
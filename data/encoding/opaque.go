@@ -0,0 +1,224 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Anydata and anyxml content is normally written back in the encoding it
+// was received in. The conversions here are only used when it is written
+// in the other encoding, and follow the same layout rules as schema data:
+// repeated elements become a JSON array, and elements with no children
+// become a string.
+
+type opaqueElem struct {
+	name string
+	text strings.Builder
+	kids []*opaqueElem
+}
+
+func xmlPayloadToJSON(payload []byte) ([]byte, error) {
+	root := &opaqueElem{}
+	stack := []*opaqueElem{root}
+
+	dec := xml.NewDecoder(bytes.NewReader(payload))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		cur := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &opaqueElem{name: t.Name.Local}
+			cur.kids = append(cur.kids, e)
+			stack = append(stack, e)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			cur.text.Write(t)
+		}
+	}
+
+	var buf bytes.Buffer
+	writeOpaqueElemJSON(&buf, root)
+	return buf.Bytes(), nil
+}
+
+func writeOpaqueElemJSON(buf *bytes.Buffer, e *opaqueElem) {
+	if len(e.kids) == 0 {
+		b, _ := json.Marshal(strings.TrimSpace(e.text.String()))
+		buf.Write(b)
+		return
+	}
+
+	// Group repeated elements while keeping the order of first appearance
+	var names []string
+	groups := make(map[string][]*opaqueElem)
+	for _, k := range e.kids {
+		if _, ok := groups[k.name]; !ok {
+			names = append(names, k.name)
+		}
+		groups[k.name] = append(groups[k.name], k)
+	}
+
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		b, _ := json.Marshal(name)
+		buf.Write(b)
+		buf.WriteByte(':')
+		elems := groups[name]
+		if len(elems) == 1 {
+			writeOpaqueElemJSON(buf, elems[0])
+			continue
+		}
+		buf.WriteByte('[')
+		for j, el := range elems {
+			if j != 0 {
+				buf.WriteByte(',')
+			}
+			writeOpaqueElemJSON(buf, el)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteByte('}')
+}
+
+// Module qualified JSON member names are written in the namespace of the
+// module, and unqualified names in the namespace of their parent, as in
+// RFC 7951. The namespace of a module is only known if the module has
+// nodes in the schema being encoded; other qualified members are written
+// in no namespace, so that conversion is lossy.
+type opaqueXMLWriter struct {
+	enc    *xml.Encoder
+	dec    *json.Decoder
+	lookup func(module string) (string, bool)
+}
+
+func jsonPayloadToXML(
+	enc *xml.Encoder,
+	payload []byte,
+	ns string,
+	lookup func(module string) (string, bool),
+) error {
+	if !json.Valid(payload) {
+		return errors.New("invalid JSON payload")
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	w := &opaqueXMLWriter{enc: enc, dec: dec, lookup: lookup}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	return w.content(tok, ns)
+}
+
+func (w *opaqueXMLWriter) content(tok json.Token, ns string) error {
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			return w.object(ns)
+		}
+		for w.dec.More() {
+			item, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := w.content(item, ns); err != nil {
+				return err
+			}
+		}
+		_, err := w.dec.Token()
+		return err
+	case nil:
+	case string:
+		w.enc.EncodeToken(xml.CharData(t))
+	default:
+		w.enc.EncodeToken(xml.CharData(fmt.Sprint(t)))
+	}
+	return nil
+}
+
+func (w *opaqueXMLWriter) object(ns string) error {
+	for w.dec.More() {
+		tok, err := w.dec.Token()
+		if err != nil {
+			return err
+		}
+		start := w.startElement(tok.(string), ns)
+
+		tok, err = w.dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			if err := w.element(start, tok); err != nil {
+				return err
+			}
+			continue
+		}
+		// Repeated elements
+		for w.dec.More() {
+			entry, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := w.element(start, entry); err != nil {
+				return err
+			}
+		}
+		if _, err := w.dec.Token(); err != nil {
+			return err
+		}
+	}
+	_, err := w.dec.Token()
+	return err
+}
+
+func (w *opaqueXMLWriter) startElement(member, ns string) xml.StartElement {
+	idx := strings.Index(member, ":")
+	if idx == -1 {
+		return xml.StartElement{Name: xml.Name{Space: ns, Local: member}}
+	}
+	name := xml.Name{Local: member[idx+1:]}
+	if modNs, ok := w.lookup(member[:idx]); ok {
+		name.Space = modNs
+		return xml.StartElement{Name: name}
+	}
+	return xml.StartElement{Name: name,
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}}}}
+}
+
+func (w *opaqueXMLWriter) element(start xml.StartElement, tok json.Token) error {
+	w.enc.EncodeToken(start)
+	if err := w.content(tok, start.Name.Space); err != nil {
+		return err
+	}
+	return w.enc.EncodeToken(start.End())
+}
@@ -40,8 +40,9 @@ const (
 // TEMP - DELETE
 type unserialized interface {
 	name() string
-	values() ([]string, error)
+	values(schema.Node) ([]string, error)
 	unserializedChildren([]string, schema.Node) ([]unserialized, error)
+	payload() (datanode.PayloadFormat, []byte, error)
 }
 
 func getChildName(path []string, node unserialized, sn schema.Node) (string, error) {
@@ -61,9 +62,14 @@ func getChildName(path []string, node unserialized, sn schema.Node) (string, err
 			if ch.name() != key {
 				continue
 			}
+			vals, err := ch.values(sn.Child(key))
+			if err != nil {
+				return "", err
+			}
+			if len(vals) == 0 {
+				break
+			}
 			found = true
-
-			vals, _ := ch.values()
 			name = vals[0]
 
 			// Validate the value of the key
@@ -128,8 +134,16 @@ func convertToDataNode(path []string, name string, node unserialized, sn schema.
 	vals := []string{}
 
 	switch sn.(type) {
+	case schema.Anydata, schema.Anyxml:
+		// Content is not described by the schema, so carry it unvalidated
+		format, payload, err := node.payload()
+		if err != nil {
+			return nil, err
+		}
+		return datanode.CreateOpaqueDataNode(name, format, payload), nil
+
 	case schema.Leaf, schema.LeafList, schema.LeafValue:
-		values, err := node.values()
+		values, err := node.values(sn)
 		if err != nil {
 			return nil, err
 		}
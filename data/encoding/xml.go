@@ -24,6 +24,7 @@ package encoding
 import (
 	"bytes"
 	"encoding/xml"
+	"io"
 	"strings"
 
 	"github.com/danos/mgmterror"
@@ -37,13 +38,17 @@ type unmarshaledXML struct {
 	XMLAttr  []xml.Attr        `xml:",any,attr"`
 	Chardata string            `xml:",chardata"`
 	Children []*unmarshaledXML `xml:",any"`
+	InnerXML string            `xml:",innerxml"`
+
+	// Namespace declarations made by ancestor elements
+	scope []xml.Attr
 }
 
 func (xmlNode *unmarshaledXML) name() string {
 	return xmlNode.XMLName.Local
 }
 
-func (xmlNode *unmarshaledXML) values() ([]string, error) {
+func (xmlNode *unmarshaledXML) values(_ schema.Node) ([]string, error) {
 	if len(xmlNode.Children) > 0 {
 		if xmlNode.Chardata != "" {
 			return nil, mgmterror.NewUnknownElementApplicationError(xmlNode.Chardata)
@@ -60,6 +65,88 @@ func (xmlNode *unmarshaledXML) values() ([]string, error) {
 	return []string{xmlNode.Chardata}, nil
 }
 
+func isNamespaceDecl(name xml.Name) bool {
+	return name.Space == "xmlns" || (name.Space == "" && name.Local == "xmlns")
+}
+
+// Namespace declarations in scope for the children of this element
+func (xmlNode *unmarshaledXML) childScope() []xml.Attr {
+	scope := xmlNode.scope
+	for _, atr := range xmlNode.XMLAttr {
+		if !isNamespaceDecl(atr.Name) {
+			continue
+		}
+		next := make([]xml.Attr, 0, len(scope)+1)
+		for _, decl := range scope {
+			if decl.Name != atr.Name {
+				next = append(next, decl)
+			}
+		}
+		scope = append(next, atr)
+	}
+	return scope
+}
+
+// The payload is kept as received, except that namespace declarations
+// made by ancestors are added to its top-level elements so that the
+// payload stays well-formed on its own.
+func (xmlNode *unmarshaledXML) payload() (datanode.PayloadFormat, []byte, error) {
+	scope := xmlNode.childScope()
+	if len(scope) == 0 {
+		return datanode.PayloadXML, []byte(xmlNode.InnerXML), nil
+	}
+
+	inner := xmlNode.InnerXML
+	var b bytes.Buffer
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	last, depth := 0, 0
+	for {
+		off := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth != 1 {
+				continue
+			}
+			at := off + len("<") + len(t.Name.Local)
+			if t.Name.Space != "" {
+				at += len(t.Name.Space) + len(":")
+			}
+			b.WriteString(inner[last:at])
+			last = at
+			for _, decl := range scope {
+				if hasAttr(t.Attr, decl.Name) {
+					continue
+				}
+				b.WriteString(" xmlns")
+				if decl.Name.Space != "" {
+					b.WriteString(":" + decl.Name.Local)
+				}
+				b.WriteString(`="`)
+				xml.EscapeText(&b, []byte(decl.Value))
+				b.WriteString(`"`)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	b.WriteString(inner[last:])
+	return datanode.PayloadXML, b.Bytes(), nil
+}
+
+func hasAttr(attrs []xml.Attr, name xml.Name) bool {
+	for _, atr := range attrs {
+		if atr.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func locateIdentity(typ schema.Type, val, ns string) *schema.Identity {
 	switch t := typ.(type) {
 	case schema.Identityref:
@@ -98,8 +185,10 @@ func (xmlNode *unmarshaledXML) convertPrefixedValue(sn schema.Node) {
 func (xmlNode *unmarshaledXML) unserializedChildren(path []string, sn schema.Node) ([]unserialized, error) {
 	fields := make(map[string]*unmarshaledXML)
 	list := make([]unserialized, 0)
+	scope := xmlNode.childScope()
 
 	for _, c := range xmlNode.Children {
+		c.scope = scope
 		name := c.name()
 		cn := sn.Child(name)
 		if cn == nil {
@@ -113,7 +202,7 @@ func (xmlNode *unmarshaledXML) unserializedChildren(path []string, sn schema.Nod
 		case schema.LeafList:
 			c.convertPrefixedValue(cn)
 			if !ok {
-				v = &unmarshaledXML{c.XMLName, c.XMLAttr, "", make([]*unmarshaledXML, 0)}
+				v = &unmarshaledXML{c.XMLName, c.XMLAttr, "", make([]*unmarshaledXML, 0), "", c.scope}
 				fields[name] = v
 				list = append(list, v)
 			}
@@ -122,7 +211,7 @@ func (xmlNode *unmarshaledXML) unserializedChildren(path []string, sn schema.Nod
 			// We may validly have multiple list elements with the same
 			// name so no need to check ok.  For each element we create a
 			// List entry in <list>, with a single child for the listEntry.
-			v = &unmarshaledXML{c.XMLName, c.XMLAttr, "", make([]*unmarshaledXML, 0)}
+			v = &unmarshaledXML{c.XMLName, c.XMLAttr, "", make([]*unmarshaledXML, 0), "", c.scope}
 			fields[name] = v
 			list = append(list, v)
 			v.Children = append(v.Children, c)
@@ -226,7 +315,65 @@ func namespacePrefixes(sn schema.Node, value string) []xml.Attr {
 	return nsprefixes
 }
 
-func encodeXmlChildren(enc *xml.Encoder, sn schema.Node, n datanode.DataNode) {
+// moduleNamespaces maps module names to namespaces for the modules with
+// nodes in a schema tree. It is only built when needed to convert a JSON
+// opaque payload.
+type moduleNamespaces struct {
+	root schema.Node
+	ns   map[string]string
+}
+
+func (m *moduleNamespaces) lookup(module string) (string, bool) {
+	if m.ns == nil {
+		m.ns = make(map[string]string)
+		m.add(m.root)
+	}
+	ns, ok := m.ns[module]
+	return ns, ok
+}
+
+func (m *moduleNamespaces) add(sn schema.Node) {
+	if sn.Module() != "" {
+		m.ns[sn.Module()] = sn.Namespace()
+	}
+	for _, ch := range sn.Children() {
+		m.add(ch)
+	}
+}
+
+// Opaque content is written straight to the output, so the encoder must
+// be flushed first to keep the output in order.
+func encodeXmlOpaque(
+	enc *xml.Encoder,
+	w io.Writer,
+	sn schema.Node,
+	n datanode.DataNode,
+	mods *moduleNamespaces,
+) {
+	on, ok := n.(datanode.OpaqueDataNode)
+	if !ok || len(on.YangDataPayload()) == 0 {
+		return
+	}
+
+	switch on.YangDataPayloadFormat() {
+	case datanode.PayloadJSON:
+		if err := jsonPayloadToXML(enc, on.YangDataPayload(),
+			sn.Namespace(), mods.lookup); err != nil {
+			enc.EncodeToken(xml.CharData(on.YangDataPayload()))
+		}
+	default:
+		enc.Flush()
+		w.Write(on.YangDataPayload())
+	}
+}
+
+func encodeXmlChildren(
+	enc *xml.Encoder,
+	w io.Writer,
+	sn schema.Node,
+	n datanode.DataNode,
+	mods *moduleNamespaces,
+) {
 
 	for _, cn := range n.YangDataChildren() {
 		csn := sn.Child(cn.YangDataName())
@@ -234,11 +381,11 @@ func encodeXmlChildren(enc *xml.Encoder, sn schema.Node, n datanode.DataNode) {
 		switch csn.(type) {
 		case schema.Container, schema.ListEntry, schema.Tree:
 			enc.EncodeToken(xml.StartElement{Name: c_name})
-			encodeXmlChildren(enc, csn, cn)
+			encodeXmlChildren(enc, w, csn, cn, mods)
 			enc.EncodeToken(xml.EndElement{Name: c_name})
 
 		case schema.List:
-			encodeXmlChildren(enc, csn, cn)
+			encodeXmlChildren(enc, w, csn, cn, mods)
 
 		case schema.Leaf, schema.LeafList:
 			for _, v := range cn.YangDataValues() {
@@ -247,6 +394,11 @@ func encodeXmlChildren(enc *xml.Encoder, sn schema.Node, n datanode.DataNode) {
 				enc.EncodeToken(xml.CharData([]byte(v)))
 				enc.EncodeToken(xml.EndElement{Name: c_name})
 			}
+
+		case schema.Anydata, schema.Anyxml:
+			enc.EncodeToken(xml.StartElement{Name: c_name})
+			encodeXmlOpaque(enc, w, csn, cn, mods)
+			enc.EncodeToken(xml.EndElement{Name: c_name})
 		}
 	}
 }
@@ -256,7 +408,7 @@ func ToXML(sn schema.Node, node datanode.DataNode) []byte {
 	enc := xml.NewEncoder(&b)
 
 	enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: node.YangDataName()}})
-	encodeXmlChildren(enc, sn, node)
+	encodeXmlChildren(enc, &b, sn, node, &moduleNamespaces{root: sn})
 	enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: node.YangDataName()}})
 
 	enc.Flush()
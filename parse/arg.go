@@ -40,6 +40,7 @@ type HasArgument interface {
 	ArgDescendantSchema() []xml.Name
 	ArgFractionDigits() int
 	ArgIdRef() xml.Name
+	ArgIfFeature() IfFeatureExpr
 	ArgId() string
 	ArgInt() int
 	ArgKey() []string
@@ -83,6 +84,7 @@ func (h *hasArgument) ArgKey() []string                { return h.arg.(*KeyArg).
 func (h *hasArgument) ArgOrdBy() string                { return h.arg.(*OrdByArg).String() }
 func (h *hasArgument) ArgId() string                   { return h.arg.(*IdArg).String() }
 func (h *hasArgument) ArgIdRef() xml.Name              { return h.arg.(*IdRefArg).name }
+func (h *hasArgument) ArgIfFeature() IfFeatureExpr     { return h.arg.(*IfFeatureArg).expr }
 func (h *hasArgument) ArgBool() bool                   { return h.arg.(*BoolArg).b }
 func (h *hasArgument) ArgUnique() [][]xml.Name         { return h.arg.(*UniqueArg).paths }
 func (h *hasArgument) ArgPattern() *regexp.Regexp      { return h.arg.(*PatternArg).Regexp }
@@ -93,12 +95,6 @@ func (h *hasArgument) ArgWhen() string                 { return h.arg.(StringArg
 func (h *hasArgument) ArgMust() string                 { return h.arg.(StringArg).String() }
 func (h *hasArgument) ArgPath() string                 { return h.arg.(StringArg).String() }
 
-//Extra character classes from XML spec.
-//translate them to normal character classes
-var patternReplacements = map[string]string{
-	"\\p{IsBasicLatin}": "[\\x{0000}-\\x{007F}]",
-}
-
 type Argument interface {
 	String() string
 	Parse() error
@@ -313,7 +309,7 @@ type YangVersionArg struct {
 }
 
 func (a *YangVersionArg) Parse() error {
-	if a.arg == "1" {
+	if a.arg == "1" || a.arg == "1.1" {
 		return nil
 	}
 	return errors.New("invalid yang-version: " + string(a.arg))
@@ -412,17 +408,14 @@ type PatternArg struct {
 	*regexp.Regexp
 }
 
-// Go's regexp engine doesn't anchor regexps to start and end of line, whereas
-// YANG pattern statements use XSD regexps that do this implicitly.  So, we
-// need to explicitly anchor pattern regexps to get the correct behaviour.
-// As these patterns may be branched (contain '|' (or)), we need to
-// parenthesise the pattern before anchoring it.
+// YANG pattern statements use XSD regexps, which are translated to the
+// equivalent Go regexp, including the implicit anchoring to start and end
+// of the value.
 func (a *PatternArg) Parse() error {
-	s := a.String()
-	for k, v := range patternReplacements {
-		s = strings.Replace(s, k, v, -1)
+	s, err := translateXSDRegexp(a.String())
+	if err != nil {
+		return err
 	}
-	s = "^(" + s + ")$"
 	re, err := regexp.Compile(s)
 	if err != nil {
 		return err
@@ -451,6 +444,17 @@ func (a *OrdByArg) Parse() error {
 	return errors.New("invalid argument: " + string(a.arg))
 }
 
+type ModifierArg struct {
+	arg
+}
+
+func (a *ModifierArg) Parse() error {
+	if string(a.arg) == "invert-match" {
+		return nil
+	}
+	return errors.New("invalid argument: " + string(a.arg))
+}
+
 type DeviateArg struct {
 	arg
 }
@@ -797,17 +801,20 @@ func getArgByType(ntype NodeType, a string, interner *ArgInterner) (out Argument
 		return &BoolArg{arg: arg(a)}
 
 	// ID Arguments
-	case NodeGrouping, NodeList, NodeChoice, NodeCase, NodeAnyxml, NodeContainer,
+	case NodeGrouping, NodeList, NodeChoice, NodeCase, NodeAnyxml, NodeAnydata, NodeContainer,
 		NodeLeaf, NodeLeafList, NodeExtension, NodeArgument, NodeIdentity, NodeFeature,
-		NodeRpc, NodeNotification, NodeBit, NodeTypedef, NodeModule, NodeSubmodule,
+		NodeRpc, NodeAction, NodeNotification, NodeBit, NodeTypedef, NodeModule, NodeSubmodule,
 		NodeOpdCommand, NodeOpdOption, NodeOpdArgument,
 		NodeImport, NodeInclude, NodeBelongsTo:
 		return &IdArg{arg: arg(a)}
 
 	// ID Ref Arguments
-	case NodeBase, NodeIfFeature, NodeUses, NodeTyp:
+	case NodeBase, NodeUses, NodeTyp:
 		return &IdRefArg{arg: arg(a)}
 
+	case NodeIfFeature:
+		return &IfFeatureArg{arg: arg(a)}
+
 	// Date Arguments
 	case NodeRevision, NodeRevisionDate:
 		return &DateArg{arg: arg(a)}
@@ -827,6 +834,8 @@ func getArgByType(ntype NodeType, a string, interner *ArgInterner) (out Argument
 		return &StatusArg{arg: arg(a)}
 	case NodeOrderedBy:
 		return &OrdByArg{arg: arg(a)}
+	case NodeModifier:
+		return &ModifierArg{arg: arg(a)}
 	case NodeDeviate, NodeDeviateNotSupported, NodeDeviateAdd,
 		NodeDeviateDelete, NodeDeviateReplace:
 		return &DeviateArg{arg: arg(a)}
@@ -74,6 +74,7 @@ type Node interface {
 
 	// This is where file and line numbers are stored
 	ErrorContext() (location, context string)
+	Location() (file string, line, col int)
 
 	// Shortcuts for child values. Possibly not needed
 	Min() uint
@@ -84,6 +85,7 @@ type Node interface {
 	Presence() bool
 	Keys() []string
 	Def() string
+	Defs() []string
 	HasDef() bool
 	Config() bool
 	HasConfig() bool
@@ -94,6 +96,7 @@ type Node interface {
 	Cmsg() string
 	Value() int
 	Revision() string
+	RevisionDate() string
 	Prefix() string
 	Ns() string
 	Path() string
@@ -104,6 +107,7 @@ type Node interface {
 	Secret() bool
 	PassOpcArgs() bool
 	AppTag() string
+	InvertMatch() bool
 
 	// Internal build help
 	position() Pos
@@ -261,14 +265,14 @@ func (n *node) ChildrenByType(match NodeType) []Node {
 			ch = append(ch, v)
 		}
 	}
-	if len(ch) == 0 && n.NodeType == NodeRpc {
+	if len(ch) == 0 && (n.NodeType == NodeRpc || n.NodeType == NodeAction) {
 		ch = n.getImplicitRpcChildren(match)
 		n.AddChildren(ch...)
 	}
 	if n.NodeType == NodeChoice {
 		for _, nd := range n.Children() {
 			switch nd.Type() {
-			case NodeContainer, NodeLeaf, NodeLeafList, NodeList:
+			case NodeContainer, NodeLeaf, NodeLeafList, NodeList, NodeAnyxml, NodeAnydata:
 				newnd := newNodeByType(NodeCase,
 					n.tree,
 					item{pos: nd.position(), val: "case"},
@@ -413,6 +417,21 @@ func (n *node) Secret() bool      { return n.optBool(NodeOpdSecret, false) }
 func (n *node) PassOpcArgs() bool { return n.optBool(NodeOpdPassOpcArgs, false) }
 func (n *node) AppTag() string    { return n.optString(NodeErrorAppTag) }
 
+// 'invert-match' is the only modifier defined by RFC 7950
+func (n *node) InvertMatch() bool { return n.exists(NodeModifier) }
+
+// The revision of a module required by an import or include
+func (n *node) RevisionDate() string { return n.optDate(NodeRevisionDate) }
+
+// A leaf-list may have several defaults, which are kept in order
+func (n *node) Defs() []string {
+	var defs []string
+	for _, ch := range n.ChildrenByType(NodeDefault) {
+		defs = append(defs, ch.ArgString())
+	}
+	return defs
+}
+
 func (n *node) Min() uint {
 	ch := n.ChildByType(NodeMinElements)
 	if ch != nil {
@@ -629,3 +648,13 @@ func (n *node) ErrorContext() (location, context string) {
 	context = n.String()
 	return n.tree.ErrorContextPosition(pos, context)
 }
+
+// Location returns the file, line and column of the node in the input text,
+// as given by ErrorContext.
+func (n *node) Location() (file string, line, col int) {
+	if n.tree == nil {
+		return "", 0, 0
+	}
+	line, col = n.tree.Position(int(n.position()))
+	return n.tree.ParseName, line, col
+}
@@ -37,6 +37,7 @@ func yangCardinality(ntype NodeType) map[NodeType]Cardinality {
 //A table of cardinaliies from the rfc.
 var cardinalities = map[NodeType]map[NodeType]Cardinality{
 	NodeModule: {
+		NodeAnydata:      {'0', 'n'},
 		NodeAnyxml:       {'0', 'n'},
 		NodeAugment:      {'0', 'n'},
 		NodeChoice:       {'0', 'n'},
@@ -79,6 +80,7 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeReference:   {'0', '1'},
 	},
 	NodeSubmodule: {
+		NodeAnydata:      {'0', 'n'},
 		NodeAnyxml:       {'0', 'n'},
 		NodeAugment:      {'0', 'n'},
 		NodeBelongsTo:    {'1', '1'},
@@ -117,7 +119,7 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeUnits:       {'0', '1'},
 	},
 	NodeTyp: {
-		NodeBase:            {'0', '1'},
+		NodeBase:            {'0', 'n'},
 		NodeBit:             {'0', 'n'},
 		NodeEnum:            {'0', 'n'},
 		NodeFractionDigits:  {'0', '1'},
@@ -129,23 +131,26 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeTyp:             {'0', 'n'},
 	},
 	NodeContainer: {
-		NodeAnyxml:      {'0', 'n'},
-		NodeChoice:      {'0', 'n'},
-		NodeConfig:      {'0', '1'},
-		NodeContainer:   {'0', 'n'},
-		NodeDescription: {'0', '1'},
-		NodeGrouping:    {'0', 'n'},
-		NodeIfFeature:   {'0', 'n'},
-		NodeLeaf:        {'0', 'n'},
-		NodeLeafList:    {'0', 'n'},
-		NodeList:        {'0', 'n'},
-		NodeMust:        {'0', 'n'},
-		NodePresence:    {'0', '1'},
-		NodeReference:   {'0', '1'},
-		NodeStatus:      {'0', '1'},
-		NodeTypedef:     {'0', 'n'},
-		NodeUses:        {'0', 'n'},
-		NodeWhen:        {'0', '1'},
+		NodeAction:       {'0', 'n'},
+		NodeAnydata:      {'0', 'n'},
+		NodeAnyxml:       {'0', 'n'},
+		NodeChoice:       {'0', 'n'},
+		NodeConfig:       {'0', '1'},
+		NodeContainer:    {'0', 'n'},
+		NodeDescription:  {'0', '1'},
+		NodeGrouping:     {'0', 'n'},
+		NodeIfFeature:    {'0', 'n'},
+		NodeLeaf:         {'0', 'n'},
+		NodeLeafList:     {'0', 'n'},
+		NodeList:         {'0', 'n'},
+		NodeMust:         {'0', 'n'},
+		NodeNotification: {'0', 'n'},
+		NodePresence:     {'0', '1'},
+		NodeReference:    {'0', '1'},
+		NodeStatus:       {'0', '1'},
+		NodeTypedef:      {'0', 'n'},
+		NodeUses:         {'0', 'n'},
+		NodeWhen:         {'0', '1'},
 	},
 	NodeOpdCommand: {
 		NodeAnyxml:         {'0', 'n'},
@@ -239,6 +244,7 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 	NodeOpdPatternHelp: {},
 	NodeLeafList: {
 		NodeConfig:      {'0', '1'},
+		NodeDefault:     {'0', 'n'},
 		NodeDescription: {'0', '1'},
 		NodeIfFeature:   {'0', 'n'},
 		NodeMaxElements: {'0', '1'},
@@ -252,30 +258,34 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeWhen:        {'0', '1'},
 	},
 	NodeList: {
-		NodeAnyxml:      {'0', 'n'},
-		NodeChoice:      {'0', 'n'},
-		NodeConfig:      {'0', '1'},
-		NodeContainer:   {'0', 'n'},
-		NodeDescription: {'0', '1'},
-		NodeGrouping:    {'0', 'n'},
-		NodeIfFeature:   {'0', 'n'},
-		NodeKey:         {'1', '1'}, // 0..1 for config=false
-		NodeLeaf:        {'0', 'n'},
-		NodeLeafList:    {'0', 'n'},
-		NodeList:        {'0', 'n'},
-		NodeMaxElements: {'0', '1'},
-		NodeMinElements: {'0', '1'},
-		NodeMust:        {'0', 'n'},
-		NodeOrderedBy:   {'0', '1'},
-		NodeReference:   {'0', '1'},
-		NodeStatus:      {'0', '1'},
-		NodeTypedef:     {'0', 'n'},
-		NodeUnique:      {'0', 'n'},
-		NodeUses:        {'0', 'n'},
-		NodeWhen:        {'0', '1'},
-		NodeDataDef:     {'1', 'n'},
+		NodeAction:       {'0', 'n'},
+		NodeAnydata:      {'0', 'n'},
+		NodeAnyxml:       {'0', 'n'},
+		NodeChoice:       {'0', 'n'},
+		NodeConfig:       {'0', '1'},
+		NodeContainer:    {'0', 'n'},
+		NodeDescription:  {'0', '1'},
+		NodeGrouping:     {'0', 'n'},
+		NodeIfFeature:    {'0', 'n'},
+		NodeKey:          {'1', '1'}, // 0..1 for config=false
+		NodeLeaf:         {'0', 'n'},
+		NodeLeafList:     {'0', 'n'},
+		NodeList:         {'0', 'n'},
+		NodeMaxElements:  {'0', '1'},
+		NodeMinElements:  {'0', '1'},
+		NodeMust:         {'0', 'n'},
+		NodeNotification: {'0', 'n'},
+		NodeOrderedBy:    {'0', '1'},
+		NodeReference:    {'0', '1'},
+		NodeStatus:       {'0', '1'},
+		NodeTypedef:      {'0', 'n'},
+		NodeUnique:       {'0', 'n'},
+		NodeUses:         {'0', 'n'},
+		NodeWhen:         {'0', '1'},
+		NodeDataDef:      {'1', 'n'},
 	},
 	NodeChoice: {
+		NodeAnydata:     {'0', 'n'},
 		NodeAnyxml:      {'0', 'n'},
 		NodeCase:        {'0', 'n'},
 		NodeConfig:      {'0', '1'},
@@ -292,6 +302,7 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeWhen:        {'0', '1'},
 	},
 	NodeCase: {
+		NodeAnydata:     {'0', 'n'},
 		NodeAnyxml:      {'0', 'n'},
 		NodeChoice:      {'0', 'n'},
 		NodeContainer:   {'0', 'n'},
@@ -315,22 +326,35 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeStatus:      {'0', '1'},
 		NodeWhen:        {'0', '1'},
 	},
-	NodeGrouping: {
-		NodeAnyxml:      {'0', 'n'},
-		NodeChoice:      {'0', 'n'},
-		NodeContainer:   {'0', 'n'},
+	NodeAnydata: {
+		NodeConfig:      {'0', '1'},
 		NodeDescription: {'0', '1'},
-		NodeGrouping:    {'0', 'n'},
-		NodeLeaf:        {'0', 'n'},
-		NodeLeafList:    {'0', 'n'},
-		NodeList:        {'0', 'n'},
+		NodeIfFeature:   {'0', 'n'},
+		NodeMandatory:   {'0', '1'},
+		NodeMust:        {'0', 'n'},
 		NodeReference:   {'0', '1'},
 		NodeStatus:      {'0', '1'},
-		NodeTypedef:     {'0', 'n'},
-		NodeUses:        {'0', 'n'},
-		NodeOpdCommand:  {'0', 'n'},
-		NodeOpdOption:   {'0', 'n'},
-		NodeOpdArgument: {'0', 'n'},
+		NodeWhen:        {'0', '1'},
+	},
+	NodeGrouping: {
+		NodeAction:       {'0', 'n'},
+		NodeAnydata:      {'0', 'n'},
+		NodeAnyxml:       {'0', 'n'},
+		NodeChoice:       {'0', 'n'},
+		NodeContainer:    {'0', 'n'},
+		NodeDescription:  {'0', '1'},
+		NodeGrouping:     {'0', 'n'},
+		NodeLeaf:         {'0', 'n'},
+		NodeLeafList:     {'0', 'n'},
+		NodeList:         {'0', 'n'},
+		NodeNotification: {'0', 'n'},
+		NodeReference:    {'0', '1'},
+		NodeStatus:       {'0', '1'},
+		NodeTypedef:      {'0', 'n'},
+		NodeUses:         {'0', 'n'},
+		NodeOpdCommand:   {'0', 'n'},
+		NodeOpdOption:    {'0', 'n'},
+		NodeOpdArgument:  {'0', 'n'},
 	},
 	NodeUses: {
 		NodeAugment:     {'0', 'n'},
@@ -351,7 +375,18 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeStatus:      {'0', '1'},
 		NodeTypedef:     {'0', 'n'},
 	},
+	NodeAction: {
+		NodeDescription: {'0', '1'},
+		NodeGrouping:    {'0', 'n'},
+		NodeIfFeature:   {'0', 'n'},
+		NodeInput:       {'0', '1'},
+		NodeOutput:      {'0', '1'},
+		NodeReference:   {'0', '1'},
+		NodeStatus:      {'0', '1'},
+		NodeTypedef:     {'0', 'n'},
+	},
 	NodeInput: {
+		NodeAnydata:   {'0', 'n'},
 		NodeAnyxml:    {'0', 'n'},
 		NodeChoice:    {'0', 'n'},
 		NodeContainer: {'0', 'n'},
@@ -364,6 +399,7 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeDataDef:   {'0', 'n'},
 	},
 	NodeOutput: {
+		NodeAnydata:   {'0', 'n'},
 		NodeAnyxml:    {'0', 'n'},
 		NodeChoice:    {'0', 'n'},
 		NodeContainer: {'0', 'n'},
@@ -376,6 +412,7 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeDataDef:   {'0', 'n'},
 	},
 	NodeNotification: {
+		NodeAnydata:     {'0', 'n'},
 		NodeAnyxml:      {'0', 'n'},
 		NodeChoice:      {'0', 'n'},
 		NodeContainer:   {'0', 'n'},
@@ -391,19 +428,22 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeUses:        {'0', 'n'},
 	},
 	NodeAugment: {
-		NodeAnyxml:      {'0', 'n'},
-		NodeCase:        {'0', 'n'},
-		NodeChoice:      {'0', 'n'},
-		NodeContainer:   {'0', 'n'},
-		NodeDescription: {'0', '1'},
-		NodeIfFeature:   {'0', 'n'},
-		NodeLeaf:        {'0', 'n'},
-		NodeLeafList:    {'0', 'n'},
-		NodeList:        {'0', 'n'},
-		NodeReference:   {'0', '1'},
-		NodeStatus:      {'0', '1'},
-		NodeUses:        {'0', 'n'},
-		NodeWhen:        {'0', '1'},
+		NodeAction:       {'0', 'n'},
+		NodeAnydata:      {'0', 'n'},
+		NodeAnyxml:       {'0', 'n'},
+		NodeCase:         {'0', 'n'},
+		NodeChoice:       {'0', 'n'},
+		NodeContainer:    {'0', 'n'},
+		NodeDescription:  {'0', '1'},
+		NodeIfFeature:    {'0', 'n'},
+		NodeLeaf:         {'0', 'n'},
+		NodeLeafList:     {'0', 'n'},
+		NodeList:         {'0', 'n'},
+		NodeNotification: {'0', 'n'},
+		NodeReference:    {'0', '1'},
+		NodeStatus:       {'0', '1'},
+		NodeUses:         {'0', 'n'},
+		NodeWhen:         {'0', '1'},
 	},
 	NodeOpdAugment: {
 		NodeAnyxml:      {'0', 'n'},
@@ -418,8 +458,9 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeOpdArgument: {'0', '1'},
 	},
 	NodeIdentity: {
-		NodeBase:        {'0', '1'},
+		NodeBase:        {'0', 'n'},
 		NodeDescription: {'0', '1'},
+		NodeIfFeature:   {'0', 'n'},
 		NodeReference:   {'0', '1'},
 		NodeStatus:      {'0', '1'},
 	},
@@ -463,7 +504,7 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeUnits:       {'0', '1'},
 		NodeMust:        {'0', 'n'},
 		NodeUnique:      {'0', 'n'},
-		NodeDefault:     {'0', '1'},
+		NodeDefault:     {'0', 'n'},
 		NodeConfig:      {'0', '1'},
 		NodeMandatory:   {'0', '1'},
 		NodeMinElements: {'0', '1'},
@@ -473,7 +514,7 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeUnits:   {'0', '1'},
 		NodeMust:    {'0', 'n'},
 		NodeUnique:  {'0', 'n'},
-		NodeDefault: {'0', '1'},
+		NodeDefault: {'0', 'n'},
 	},
 	NodeDeviateReplace: {
 		NodeTyp:         {'0', '1'},
@@ -500,16 +541,19 @@ var cardinalities = map[NodeType]map[NodeType]Cardinality{
 		NodeDescription:  {'0', '1'},
 		NodeErrorAppTag:  {'0', '1'},
 		NodeErrorMessage: {'0', '1'},
+		NodeModifier:     {'0', '1'},
 		NodeReference:    {'0', '1'},
 	},
 	NodeEnum: {
 		NodeDescription: {'0', '1'},
+		NodeIfFeature:   {'0', 'n'},
 		NodeReference:   {'0', '1'},
 		NodeStatus:      {'0', '1'},
 		NodeValue:       {'0', '1'},
 	},
 	NodeBit: {
 		NodeDescription: {'0', '1'},
+		NodeIfFeature:   {'0', 'n'},
 		NodeReference:   {'0', '1'},
 		NodeStatus:      {'0', '1'},
 		NodePosition:    {'0', '1'},
@@ -0,0 +1,214 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parse
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// IfFeatureExpr is a parsed if-feature argument. RFC 7950 7.20.2 allows
+// feature references to be combined with 'not', 'and' and 'or', where
+// 'not' binds tightest and 'or' loosest, and parentheses group terms.
+// A YANG 1.0 argument is simply an expression with a single reference.
+type IfFeatureExpr interface {
+	// Evaluate the expression, using enabled to get the value of
+	// each referenced feature. All references are evaluated so that
+	// any error reporting from enabled is not short-circuited.
+	Eval(enabled func(xml.Name) bool) bool
+
+	// All feature references in the expression, in the order written
+	Features() []xml.Name
+
+	String() string
+}
+
+type ifFeatureRef struct {
+	name xml.Name
+}
+
+func (e *ifFeatureRef) Eval(enabled func(xml.Name) bool) bool {
+	return enabled(e.name)
+}
+
+func (e *ifFeatureRef) Features() []xml.Name { return []xml.Name{e.name} }
+
+func (e *ifFeatureRef) String() string {
+	if e.name.Space != "" {
+		return e.name.Space + ":" + e.name.Local
+	}
+	return e.name.Local
+}
+
+type ifFeatureNot struct {
+	expr IfFeatureExpr
+}
+
+func (e *ifFeatureNot) Eval(enabled func(xml.Name) bool) bool {
+	return !e.expr.Eval(enabled)
+}
+
+func (e *ifFeatureNot) Features() []xml.Name { return e.expr.Features() }
+
+func (e *ifFeatureNot) String() string { return "not " + e.expr.String() }
+
+type ifFeatureBinary struct {
+	op          string
+	left, right IfFeatureExpr
+}
+
+func (e *ifFeatureBinary) Eval(enabled func(xml.Name) bool) bool {
+	l := e.left.Eval(enabled)
+	r := e.right.Eval(enabled)
+	if e.op == "and" {
+		return l && r
+	}
+	return l || r
+}
+
+func (e *ifFeatureBinary) Features() []xml.Name {
+	return append(e.left.Features(), e.right.Features()...)
+}
+
+func (e *ifFeatureBinary) String() string {
+	return "(" + e.left.String() + " " + e.op + " " + e.right.String() + ")"
+}
+
+type IfFeatureArg struct {
+	arg
+	toks []string
+	pos  int
+	expr IfFeatureExpr
+}
+
+func (a *IfFeatureArg) tokenize() {
+	var tok strings.Builder
+	flush := func() {
+		if tok.Len() > 0 {
+			a.toks = append(a.toks, tok.String())
+			tok.Reset()
+		}
+	}
+	for _, r := range string(a.arg) {
+		switch {
+		case r == '(' || r == ')':
+			flush()
+			a.toks = append(a.toks, string(r))
+		case isSep(r):
+			flush()
+		default:
+			tok.WriteRune(r)
+		}
+	}
+	flush()
+}
+
+func (a *IfFeatureArg) peek() string {
+	if a.pos < len(a.toks) {
+		return a.toks[a.pos]
+	}
+	return ""
+}
+
+func (a *IfFeatureArg) next() string {
+	tok := a.peek()
+	a.pos++
+	return tok
+}
+
+// if-feature-expr = if-feature-term [sep or-keyword sep if-feature-expr]
+func (a *IfFeatureArg) parseExpr() (IfFeatureExpr, error) {
+	left, err := a.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	if a.peek() != "or" {
+		return left, nil
+	}
+	a.next()
+	right, err := a.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &ifFeatureBinary{op: "or", left: left, right: right}, nil
+}
+
+// if-feature-term = if-feature-factor [sep and-keyword sep if-feature-term]
+func (a *IfFeatureArg) parseTerm() (IfFeatureExpr, error) {
+	left, err := a.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	if a.peek() != "and" {
+		return left, nil
+	}
+	a.next()
+	right, err := a.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	return &ifFeatureBinary{op: "and", left: left, right: right}, nil
+}
+
+// if-feature-factor = not-keyword sep if-feature-factor /
+//
+//	"(" optsep if-feature-expr optsep ")" /
+//	identifier-ref-arg
+func (a *IfFeatureArg) parseFactor() (IfFeatureExpr, error) {
+	switch tok := a.next(); tok {
+	case "not":
+		expr, err := a.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &ifFeatureNot{expr: expr}, nil
+	case "(":
+		expr, err := a.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if a.next() != ")" {
+			return nil, fmt.Errorf("if-feature: missing ')' in %q", string(a.arg))
+		}
+		return expr, nil
+	case ")", "and", "or":
+		return nil, fmt.Errorf("if-feature: unexpected %q in %q", tok, string(a.arg))
+	default:
+		ref := &IdRefArg{arg: arg(tok)}
+		if err := ref.Parse(); err != nil {
+			return nil, err
+		}
+		return &ifFeatureRef{name: ref.name}, nil
+	}
+}
+
+func (a *IfFeatureArg) Parse() error {
+	a.toks, a.pos = nil, 0
+	a.tokenize()
+	expr, err := a.parseExpr()
+	if err != nil {
+		return err
+	}
+	if a.pos < len(a.toks) {
+		return fmt.Errorf("if-feature: unexpected %q in %q",
+			a.peek(), string(a.arg))
+	}
+	a.expr = expr
+	a.toks = nil
+	return nil
+}
+
+func (a *IfFeatureArg) Expr() IfFeatureExpr { return a.expr }
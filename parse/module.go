@@ -91,15 +91,25 @@ func checkRevisionOrder(n Node) error {
 }
 
 type Module struct {
-	tree  *Tree
-	mod   Node
-	smods map[string]Node
+	tree      *Tree
+	mod       Node
+	smods     map[string]Node
+	revisions []string
+	conflict  string
 }
 
 func (m *Module) GetTree() *Tree                 { return m.tree }
 func (m *Module) GetModule() Node                { return m.mod }
 func (m *Module) GetSubmodules() map[string]Node { return m.smods }
 
+// All revisions that were available, latest first, including the one
+// selected for this module.
+func (m *Module) Revisions() []string { return m.revisions }
+
+// RevisionConflict describes the different revisions of the module asked
+// for by imports or includes, or is empty if they agree.
+func (m *Module) RevisionConflict() string { return m.conflict }
+
 func (m *Module) Imports() (imports map[string]string) {
 	imports = make(map[string]string)
 	for _, i := range m.mod.ChildrenByType(NodeImport) {
@@ -129,19 +139,24 @@ func GetModulesAndSubmodules(
 ) {
 	modules := make(map[string]*Module)
 	submodules := make(map[string]*Module, 0)
-	for mn, m := range mods {
+	selected, revisions, conflicts := selectRevisions(mods)
+	for mn, m := range selected {
 		switch m.Root.Type() {
 		case NodeModule:
 			modules[mn] = &Module{
-				mod:   m.Root,
-				tree:  m,
-				smods: make(map[string]Node),
+				mod:       m.Root,
+				tree:      m,
+				smods:     make(map[string]Node),
+				revisions: revisions[mn],
+				conflict:  conflicts[mn],
 			}
 		case NodeSubmodule:
 			submodules[mn] = &Module{
-				mod:   m.Root,
-				tree:  m,
-				smods: nil,
+				mod:       m.Root,
+				tree:      m,
+				smods:     nil,
+				revisions: revisions[mn],
+				conflict:  conflicts[mn],
 			}
 		default:
 			continue
@@ -73,12 +73,14 @@ const (
 	NodeChoice
 	NodeUses
 	NodeAnyxml
+	NodeAnydata
 	NodeCase
 	NodeDataDefEnd
 	//Additional node for augment data-def
 	NodeGrouping
 	NodeMust
 	NodeRpc
+	NodeAction
 	NodeInput
 	NodeOutput
 	NodeNotification
@@ -124,6 +126,7 @@ const (
 	NodeWhen
 	NodeErrorAppTag
 	NodeErrorMessage
+	NodeModifier
 	NodeMandatory
 	NodeMinElements
 	NodeMaxElements
@@ -198,9 +201,11 @@ var nodeNames = [...]string{
 	NodeDataDef:             "data definition",
 	NodeDataDefEnd:          "data definition end",
 	NodeAnyxml:              "anyxml",
+	NodeAnydata:             "anydata",
 	NodeGrouping:            "grouping",
 	NodeUses:                "uses",
 	NodeRpc:                 "rpc",
+	NodeAction:              "action",
 	NodeInput:               "input",
 	NodeOutput:              "output",
 	NodeNotification:        "notification",
@@ -239,6 +244,7 @@ var nodeNames = [...]string{
 	NodeWhen:                "when",
 	NodeErrorAppTag:         "error-app-tag",
 	NodeErrorMessage:        "error-message",
+	NodeModifier:            "modifier",
 	NodeMandatory:           "mandatory",
 	NodeMinElements:         "min-elements",
 	NodeMaxElements:         "max-elements",
@@ -247,7 +253,7 @@ var nodeNames = [...]string{
 	NodeUnique:              "unique",
 	NodeRefine:              "refine",
 	NodeBase:                "base",
-	NodeYinElement:          "yin",
+	NodeYinElement:          "yin-element",
 	NodeValue:               "value",
 	NodePosition:            "position",
 	NodeFractionDigits:      "fraction-digits",
@@ -84,6 +84,7 @@ type Tree struct {
 	ParseName string
 	extCard   NodeCardinality // Function to provide cardinality of extensions
 	text      string          // text parsed to create the template (or its parent)
+	yin       bool            // text is YIN rather than YANG
 	lex       *lexer
 	token     [3]item // three-token lookahead for parser.
 	peekCount int
@@ -210,7 +211,9 @@ func NewWithInterners(
 	}
 }
 
-func (t *Tree) ErrorContextPosition(pos int, ctx string) (location, context string) {
+// Position returns the line, and the offset within that line, of the byte
+// at pos in the input text.
+func (t *Tree) Position(pos int) (line, col int) {
 	text := t.text[:pos]
 	byteNum := strings.LastIndex(text, "\n")
 	if byteNum == -1 {
@@ -219,7 +222,11 @@ func (t *Tree) ErrorContextPosition(pos int, ctx string) (location, context stri
 		byteNum++ // After the newline.
 		byteNum = pos - byteNum
 	}
-	lineNum := 1 + strings.Count(text, "\n")
+	return 1 + strings.Count(text, "\n"), byteNum
+}
+
+func (t *Tree) ErrorContextPosition(pos int, ctx string) (location, context string) {
+	lineNum, byteNum := t.Position(pos)
 	context = ctx
 	if len(context) > 20 {
 		context = fmt.Sprintf("%.20s...", context)
@@ -0,0 +1,184 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parse_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sdcio/yang-parser/parse"
+)
+
+// From ietf-inet-types (RFC 6991)
+const (
+	ipv6AddressPattern1 = `((:|[0-9a-fA-F]{0,4}):)([0-9a-fA-F]{0,4}:){0,5}` +
+		`((([0-9a-fA-F]{0,4}:)?(:|[0-9a-fA-F]{0,4}))|` +
+		`(((25[0-5]|2[0-4][0-9]|[01]?[0-9]?[0-9])\.){3}` +
+		`(25[0-5]|2[0-4][0-9]|[01]?[0-9]?[0-9])))` +
+		`(%[\p{N}\p{L}]+)?`
+	ipv6AddressPattern2 = `(([^:]+:){6}(([^:]+:[^:]+)|(.*\..*)))|` +
+		`((([^:]+:)*[^:]+)?::(([^:]+:)*[^:]+)?)` +
+		`(%.+)?`
+	domainNamePattern = `((([a-zA-Z0-9_]([a-zA-Z0-9\-_]){0,61})?[a-zA-Z0-9]\.)*` +
+		`([a-zA-Z0-9_]([a-zA-Z0-9\-_]){0,61})?[a-zA-Z0-9]\.?)` +
+		`|\.`
+)
+
+func parsePattern(pattern string) (*regexp.Regexp, error) {
+	text := `module test-pattern {
+		namespace "urn:test:pattern";
+		prefix test;
+		typedef t {
+			type string {
+				pattern '` + pattern + `';
+			}
+		}
+	}`
+	tree, err := parse.Parse("test-pattern", text, nil)
+	if err != nil {
+		return nil, err
+	}
+	typ := tree.Root.ChildByType(parse.NodeTypedef).ChildByType(parse.NodeTyp)
+	return typ.ChildByType(parse.NodePattern).ArgPattern(), nil
+}
+
+type patternMatch struct {
+	value string
+	match bool
+}
+
+func checkPattern(t *testing.T, pattern string, cases []patternMatch) {
+	re, err := parsePattern(pattern)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing pattern %s: %s", pattern, err)
+	}
+	for _, c := range cases {
+		if re.MatchString(c.value) != c.match {
+			t.Errorf("Pattern %s: expected match %v for %q",
+				pattern, c.match, c.value)
+		}
+	}
+}
+
+func TestPatternIPv6Address(t *testing.T) {
+	valid := []string{"::", "::1", "2001:db8::1", "fe80::1%eth0",
+		"2001:db8:0:0:0:0:0:1", "::ffff:192.0.2.1"}
+	for _, v := range valid {
+		checkPattern(t, ipv6AddressPattern1, []patternMatch{{v, true}})
+		checkPattern(t, ipv6AddressPattern2, []patternMatch{{v, true}})
+	}
+	checkPattern(t, ipv6AddressPattern1, []patternMatch{
+		{"2001:db8::g", false},
+		{"192.0.2.1", false},
+		{"::1\n", false},
+	})
+}
+
+func TestPatternDomainName(t *testing.T) {
+	checkPattern(t, domainNamePattern, []patternMatch{
+		{"example.com", true},
+		{"example.com.", true},
+		{".", true},
+		{"_sip._udp.example.com", true},
+		{"a-b.example", true},
+		{"-ab.example", false},
+		{"exa mple.com", false},
+		{"example..com", false},
+	})
+}
+
+func TestPatternAnchoring(t *testing.T) {
+	checkPattern(t, `a|b`, []patternMatch{
+		{"a", true}, {"b", true}, {"ab", false}, {"xa", false},
+	})
+	// '^' and '$' are ordinary characters in XSD regexps
+	checkPattern(t, `^a$`, []patternMatch{
+		{"^a$", true}, {"a", false},
+	})
+	checkPattern(t, `.`, []patternMatch{
+		{"x", true}, {"\n", false}, {"\r", false},
+	})
+}
+
+func TestPatternClassSubtraction(t *testing.T) {
+	checkPattern(t, `[a-z-[aeiou]]+`, []patternMatch{
+		{"bcd", true}, {"bad", false}, {"B", false},
+	})
+	checkPattern(t, `[\p{L}-[\p{Lu}]]+`, []patternMatch{
+		{"abcé", true}, {"aBc", false},
+	})
+	checkPattern(t, `[a-z-[b-y-[c]]]`, []patternMatch{
+		{"a", true}, {"z", true}, {"c", true}, {"b", false},
+	})
+	checkPattern(t, `[^a-z-[x]]`, []patternMatch{
+		{"A", true}, {"x", false}, {"a", false},
+	})
+}
+
+func TestPatternMultiCharEscapes(t *testing.T) {
+	checkPattern(t, `\i\c*`, []patternMatch{
+		{"ab-c.d", true}, {"_x:y", true}, {"1ab", false}, {"-ab", false},
+		{"ab c", false},
+	})
+	checkPattern(t, `\I\C`, []patternMatch{
+		{"1 ", true}, {"a ", false},
+	})
+	checkPattern(t, `\d+`, []patternMatch{
+		{"123", true}, {"١٢٣", true}, {"12a", false},
+	})
+	checkPattern(t, `\s\S`, []patternMatch{
+		{" a", true}, {"\ta", true}, {"\fa", false}, {"  ", false},
+	})
+	checkPattern(t, `\w+`, []patternMatch{
+		{"abc123", true}, {"a.b", false}, {"a b", false},
+	})
+	checkPattern(t, `[\w-[\d]]+`, []patternMatch{
+		{"abc", true}, {"a1", false},
+	})
+}
+
+func TestPatternBlockEscapes(t *testing.T) {
+	checkPattern(t, `\p{IsBasicLatin}+`, []patternMatch{
+		{"abc~", true}, {"abé", false},
+	})
+	checkPattern(t, `\P{IsBasicLatin}`, []patternMatch{
+		{"é", true}, {"e", false},
+	})
+	checkPattern(t, `[\p{IsGreek}\p{IsBasicLatin}]+`, []patternMatch{
+		{"aβc", true}, {"aщ", false},
+	})
+}
+
+func TestPatternInvalid(t *testing.T) {
+	for pattern, expErr := range map[string]string{
+		`\p{IsNoSuchBlock}`: "unknown block IsNoSuchBlock",
+		`\p{Xx}`:            "unknown category Xx",
+		`\b`:                "invalid escape \\b",
+		`a*?`:               "unexpected '?' after quantifier",
+		`(?i)a`:             "unexpected '?' after '('",
+		`[a-z`:              "missing ']'",
+		`[z-a]`:             "invalid range z-a",
+		`[a-[b]c]`:          "subtraction must be last in class",
+	} {
+		_, err := parsePattern(pattern)
+		if err == nil {
+			t.Errorf("Pattern %s: expected error", pattern)
+		} else if !strings.Contains(err.Error(), expErr) {
+			t.Errorf("Pattern %s: expected error containing %q, got: %s",
+				pattern, expErr, err)
+		}
+	}
+}
@@ -0,0 +1,472 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements printing of parse trees as canonical YANG, for
+// formatting modules or writing them out after they have been edited.
+
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	yangIndent    = "  "
+	yangLineWidth = 72
+)
+
+// The canonical order of substatements, from the grammar in RFC 7950
+// section 14, as recommended by RFC 8407 section 4.6. Statements in the
+// same group keep the order they were written in, as the order of data
+// definitions, enums and bits is significant. Statements not listed, such
+// as extensions, go in the "*" group, or last if there is none.
+var yangStatementOrder = map[string][][]string{
+	"module": {
+		{"yang-version"}, {"namespace"}, {"prefix"},
+		{"import", "include"},
+		{"organization"}, {"contact"}, {"description"}, {"reference"},
+		{"revision"},
+		{"*"},
+	},
+	"submodule": {
+		{"yang-version"}, {"belongs-to"},
+		{"import", "include"},
+		{"organization"}, {"contact"}, {"description"}, {"reference"},
+		{"revision"},
+		{"*"},
+	},
+	"import":     {{"prefix"}, {"revision-date"}, {"description"}, {"reference"}},
+	"include":    {{"revision-date"}, {"description"}, {"reference"}},
+	"revision":   {{"description"}, {"reference"}},
+	"belongs-to": {{"prefix"}},
+	"extension":  {{"argument"}, {"status"}, {"description"}, {"reference"}},
+	"argument":   {{"yin-element"}},
+	"identity": {
+		{"if-feature"}, {"base"}, {"status"}, {"description"}, {"reference"},
+	},
+	"feature": {{"if-feature"}, {"status"}, {"description"}, {"reference"}},
+	"typedef": {
+		{"type"}, {"units"}, {"default"}, {"status"}, {"description"},
+		{"reference"},
+	},
+	"type": {
+		{"fraction-digits"}, {"range"}, {"length"}, {"pattern"}, {"path"},
+		{"require-instance"}, {"base"}, {"bit", "enum", "type"},
+	},
+	"range":  {{"error-message"}, {"error-app-tag"}, {"description"}, {"reference"}},
+	"length": {{"error-message"}, {"error-app-tag"}, {"description"}, {"reference"}},
+	"must":   {{"error-message"}, {"error-app-tag"}, {"description"}, {"reference"}},
+	"when":   {{"description"}, {"reference"}},
+	"pattern": {
+		{"modifier"}, {"error-message"}, {"error-app-tag"}, {"description"},
+		{"reference"},
+	},
+	"enum": {{"if-feature"}, {"value"}, {"status"}, {"description"}, {"reference"}},
+	"bit": {
+		{"if-feature"}, {"position"}, {"status"}, {"description"}, {"reference"},
+	},
+	"container": {
+		{"when"}, {"if-feature"}, {"must"}, {"presence"}, {"config"},
+		{"status"}, {"description"}, {"reference"}, {"*"},
+	},
+	"leaf": {
+		{"when"}, {"if-feature"}, {"type"}, {"units"}, {"must"}, {"default"},
+		{"config"}, {"mandatory"}, {"status"}, {"description"}, {"reference"},
+	},
+	"leaf-list": {
+		{"when"}, {"if-feature"}, {"type"}, {"units"}, {"must"}, {"default"},
+		{"config"}, {"min-elements"}, {"max-elements"}, {"ordered-by"},
+		{"status"}, {"description"}, {"reference"},
+	},
+	"list": {
+		{"when"}, {"if-feature"}, {"must"}, {"key"}, {"unique"}, {"config"},
+		{"min-elements"}, {"max-elements"}, {"ordered-by"}, {"status"},
+		{"description"}, {"reference"}, {"*"},
+	},
+	"choice": {
+		{"when"}, {"if-feature"}, {"default"}, {"config"}, {"mandatory"},
+		{"status"}, {"description"}, {"reference"}, {"*"},
+	},
+	"case": {
+		{"when"}, {"if-feature"}, {"status"}, {"description"}, {"reference"},
+		{"*"},
+	},
+	"anydata": {
+		{"when"}, {"if-feature"}, {"must"}, {"config"}, {"mandatory"},
+		{"status"}, {"description"}, {"reference"},
+	},
+	"anyxml": {
+		{"when"}, {"if-feature"}, {"must"}, {"config"}, {"mandatory"},
+		{"status"}, {"description"}, {"reference"},
+	},
+	"grouping": {{"status"}, {"description"}, {"reference"}, {"*"}},
+	"uses": {
+		{"when"}, {"if-feature"}, {"status"}, {"description"}, {"reference"},
+		{"refine"}, {"augment"},
+	},
+	"refine": {
+		{"if-feature"}, {"must"}, {"presence"}, {"default"}, {"config"},
+		{"mandatory"}, {"min-elements"}, {"max-elements"}, {"description"},
+		{"reference"},
+	},
+	"augment": {
+		{"when"}, {"if-feature"}, {"status"}, {"description"}, {"reference"},
+		{"*"},
+	},
+	"rpc": {
+		{"if-feature"}, {"status"}, {"description"}, {"reference"},
+		{"typedef", "grouping"}, {"input"}, {"output"},
+	},
+	"action": {
+		{"if-feature"}, {"status"}, {"description"}, {"reference"},
+		{"typedef", "grouping"}, {"input"}, {"output"},
+	},
+	"input":  {{"must"}, {"*"}},
+	"output": {{"must"}, {"*"}},
+	"notification": {
+		{"if-feature"}, {"must"}, {"status"}, {"description"}, {"reference"},
+		{"*"},
+	},
+	"deviation": {{"description"}, {"reference"}, {"deviate"}},
+	"deviate": {
+		{"type"}, {"units"}, {"must"}, {"unique"}, {"default"}, {"config"},
+		{"mandatory"}, {"min-elements"}, {"max-elements"},
+	},
+}
+
+// The sections of a module, from RFC 7950 section 7.1.1, which are set
+// apart by blank lines. Other statements are in the body.
+var yangModuleSections = map[string]int{
+	"yang-version": 1, "namespace": 1, "prefix": 1, "belongs-to": 1,
+	"import": 2, "include": 2,
+	"organization": 3, "contact": 3, "description": 3, "reference": 3,
+	"revision": 4,
+}
+
+// Free text statements, which are always quoted and may be written on the
+// line after their keyword.
+var yangTextStatements = map[string]bool{
+	"contact":       true,
+	"description":   true,
+	"error-message": true,
+	"organization":  true,
+	"presence":      true,
+	"reference":     true,
+}
+
+// Statements with an expression or regular expression argument, which are
+// always quoted.
+var yangQuotedStatements = map[string]bool{
+	"must":    true,
+	"path":    true,
+	"pattern": true,
+	"when":    true,
+}
+
+func yangStatementRank(parent, keyword string) int {
+	order := yangStatementOrder[parent]
+	other := len(order)
+	for i, group := range order {
+		for _, kw := range group {
+			if kw == keyword {
+				return i
+			}
+			if kw == "*" {
+				other = i
+			}
+		}
+	}
+	return other
+}
+
+// implicitCase reports whether n is a case the parser added for a
+// shorthand case in a choice, which has the position of its only child.
+func implicitCase(n Node) bool {
+	children := n.Children()
+	return n.Type() == NodeCase && len(children) == 1 &&
+		children[0].position() == n.position() &&
+		children[0].Name() == n.Name()
+}
+
+// sortStatements returns the children of n in canonical order, with the
+// shorthand of implicit cases.
+func sortStatements(n Node) []Node {
+	children := make([]Node, 0, len(n.Children()))
+	for _, c := range n.Children() {
+		if n.Type() == NodeChoice && implicitCase(c) {
+			c = c.Children()[0]
+		}
+		children = append(children, c)
+	}
+	parent := n.Statement()
+	sort.SliceStable(children, func(i, j int) bool {
+		return yangStatementRank(parent, children[i].Statement()) <
+			yangStatementRank(parent, children[j].Statement())
+	})
+	return children
+}
+
+// yangComment is a comment in the YANG text, and the column it starts at.
+type yangComment struct {
+	pos  Pos
+	col  int
+	text string
+}
+
+// yangComments finds the comments in YANG text, skipping over quoted and
+// unquoted strings as the lexer does.
+func yangComments(text string) []yangComment {
+	var comments []yangComment
+	addComment := func(start, end int) {
+		lnBgn := strings.LastIndex(text[:start], "\n") + 1
+		col := 0
+		for _, c := range text[lnBgn:start] {
+			if c == '\t' {
+				col += tabSpaces
+			} else {
+				col += wsSpaces
+			}
+		}
+		comments = append(comments, yangComment{
+			pos:  Pos(start),
+			col:  col,
+			text: strings.TrimRight(text[start:end], " \t\r\n"),
+		})
+	}
+
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case strings.HasPrefix(text[i:], leftComment):
+			end := strings.Index(text[i+len(leftComment):], rightComment)
+			if end < 0 {
+				return comments
+			}
+			end += i + len(leftComment) + len(rightComment)
+			addComment(i, end)
+			i = end
+		case strings.HasPrefix(text[i:], lineComment):
+			end := strings.Index(text[i:], "\n")
+			if end < 0 {
+				end = len(text) - i
+			}
+			addComment(i, i+end)
+			i += end
+		case c == '"' || c == '\'':
+			i++
+			for i < len(text) && text[i] != c {
+				if c == '"' && text[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case isSep(rune(c)) || c == ';' || c == '{' || c == '}' || c == '+':
+			i++
+		default:
+			for i < len(text) && !isTerminator(rune(text[i])) {
+				i++
+			}
+		}
+	}
+	return comments
+}
+
+type yangPrinter struct {
+	buf      bytes.Buffer
+	leading  map[Node][]yangComment
+	trailing []yangComment
+}
+
+// attachComments keeps each comment with the statement that follows it,
+// so comments move with their statement when statements are reordered.
+func (p *yangPrinter) attachComments(root Node, comments []yangComment) {
+	var nodes []Node
+	var walk func(n Node)
+	walk = func(n Node) {
+		nodes = append(nodes, n)
+		for _, c := range n.Children() {
+			walk(c)
+		}
+	}
+	walk(root)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].position() < nodes[j].position()
+	})
+
+	p.leading = make(map[Node][]yangComment)
+	i := 0
+	for _, c := range comments {
+		for i < len(nodes) && nodes[i].position() < c.pos {
+			i++
+		}
+		if i == len(nodes) {
+			p.trailing = append(p.trailing, c)
+			continue
+		}
+		p.leading[nodes[i]] = append(p.leading[nodes[i]], c)
+	}
+}
+
+func (p *yangPrinter) comment(c yangComment, indent string) {
+	for i, line := range strings.Split(c.text, "\n") {
+		if i > 0 {
+			line = trimLeadWS(line, c.col)
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			p.buf.WriteString("\n")
+			continue
+		}
+		p.buf.WriteString(indent + line + "\n")
+	}
+}
+
+// yangNeedsQuotes reports whether an argument cannot be written unquoted.
+// RFC 7950 section 6.1.3 allows no comment delimiters anywhere in an
+// unquoted string.
+func yangNeedsQuotes(arg string) bool {
+	if arg == "" || strings.Contains(arg, lineComment) ||
+		strings.Contains(arg, leftComment) ||
+		strings.Contains(arg, rightComment) {
+		return true
+	}
+	return strings.HasPrefix(arg, "+") ||
+		strings.ContainsAny(arg, " \t\r\n;{}\"'")
+}
+
+// yangQuote quotes an argument, to start at the given column. Double
+// quotes are preferred, with lines after the first aligned after the
+// opening quote, as the parser trims whitespace up to there. Single quotes
+// keep text exactly, so are used for patterns and for text with trailing
+// whitespace on its lines, which double quotes would lose.
+func yangQuote(keyword, arg string, col int) string {
+	single := keyword == "pattern"
+	lines := strings.Split(arg, "\n")
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimRight(line, " \t") != line {
+			single = true
+		}
+	}
+	if single && !strings.ContainsRune(arg, '\'') {
+		return "'" + arg + "'"
+	}
+
+	var b strings.Builder
+	b.WriteString("\"")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\n")
+			if line != "" {
+				b.WriteString(strings.Repeat(" ", col+1))
+			}
+		}
+		line = strings.ReplaceAll(line, `\`, `\\`)
+		line = strings.ReplaceAll(line, `"`, `\"`)
+		b.WriteString(line)
+	}
+	b.WriteString("\"")
+	return b.String()
+}
+
+func yangHasArgument(n Node) bool {
+	switch n.Statement() {
+	case "input", "output":
+		return false
+	}
+	if n.Argument() == nil {
+		return false
+	}
+	// Extensions may have no argument
+	return n.Argument().String() != "" ||
+		!strings.Contains(n.Statement(), ":")
+}
+
+func (p *yangPrinter) stmt(n Node, depth int) {
+	indent := strings.Repeat(yangIndent, depth)
+	for _, c := range p.leading[n] {
+		p.comment(c, indent)
+	}
+
+	keyword := n.Statement()
+	line := indent + keyword
+	if yangHasArgument(n) {
+		arg := n.Argument().String()
+		switch {
+		case yangTextStatements[keyword] &&
+			(strings.Contains(arg, "\n") ||
+				len(line)+len(arg)+4 > yangLineWidth):
+			// The text goes on its own line
+			p.buf.WriteString(line + "\n")
+			line = indent + yangIndent
+			line += yangQuote(keyword, arg, len(line))
+		case yangTextStatements[keyword] || yangQuotedStatements[keyword] ||
+			yangNeedsQuotes(arg):
+			line += " " + yangQuote(keyword, arg, len(line)+1)
+		default:
+			line += " " + arg
+		}
+	}
+
+	children := sortStatements(n)
+	if len(children) == 0 {
+		p.buf.WriteString(line + ";\n")
+		return
+	}
+	p.buf.WriteString(line + " {\n")
+
+	top := keyword == "module" || keyword == "submodule"
+	for i, c := range children {
+		// Module level sections, and blocks within them, are separated by
+		// blank lines.
+		if top && i > 0 {
+			prev := children[i-1]
+			if yangModuleSections[prev.Statement()] !=
+				yangModuleSections[c.Statement()] ||
+				len(prev.Children()) > 0 || len(c.Children()) > 0 {
+				p.buf.WriteString("\n")
+			}
+		}
+		p.stmt(c, depth+1)
+	}
+	if top {
+		for _, c := range p.trailing {
+			p.buf.WriteString("\n")
+			p.comment(c, indent+yangIndent)
+		}
+		p.trailing = nil
+	}
+	p.buf.WriteString(indent + "}\n")
+}
+
+// WriteYang writes the module or submodule in t as canonical YANG: indented
+// by two spaces, with statements in the order of RFC 7950's grammar, and
+// arguments quoted only as needed. Comments are kept before the statement
+// that follows them, when the tree was parsed from YANG.
+func WriteYang(w io.Writer, t *Tree) error {
+	if t == nil || t.Root == nil {
+		return errors.New("yang: no module to write")
+	}
+	p := &yangPrinter{}
+	if !t.yin {
+		p.attachComments(t.Root, yangComments(t.text))
+	}
+	p.stmt(t.Root, 0)
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parse_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/sdcio/yang-parser/parse"
+)
+
+func writeYang(t *testing.T, tree *Tree) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteYang(&buf, tree); err != nil {
+		t.Fatalf("Unexpected WriteYang Error - %s", err)
+	}
+	return buf.String()
+}
+
+const unformattedModule = `// Header comment
+module fmt-test { prefix fmt ;
+namespace 'urn:fmt-test' ;
+	import other { prefix o; }
+  description "A module written
+               untidily";
+	revision 2024-01-01 { description "Initial revision."; }
+
+/* Block comment
+   over two lines */
+container top { description "Top container"; config false ;
+	leaf name { description
+	  "The name";
+		type string { pattern '[a-z]+\d*'; length "1 .. 10"; }
+		mandatory true;
+	}
+	o:ext "extension argument";
+	leaf-list tag { type string; default "a b"; units seconds; }
+	choice pick { leaf one { type empty; } case two { leaf two { type empty; } } }
+}
+   // Ignored: comments at the end stay at the end
+}
+`
+
+const formattedModule = `// Header comment
+module fmt-test {
+  namespace urn:fmt-test;
+  prefix fmt;
+
+  import other {
+    prefix o;
+  }
+
+  description
+    "A module written
+     untidily";
+
+  revision 2024-01-01 {
+    description "Initial revision.";
+  }
+
+  /* Block comment
+     over two lines */
+  container top {
+    config false;
+    description "Top container";
+    leaf name {
+      type string {
+        length "1 .. 10";
+        pattern '[a-z]+\d*';
+      }
+      mandatory true;
+      description "The name";
+    }
+    o:ext "extension argument";
+    leaf-list tag {
+      type string;
+      units seconds;
+      default "a b";
+    }
+    choice pick {
+      leaf one {
+        type empty;
+      }
+      case two {
+        leaf two {
+          type empty;
+        }
+      }
+    }
+  }
+
+  // Ignored: comments at the end stay at the end
+}
+`
+
+func TestWriteYang(t *testing.T) {
+	tree, err := Parse("fmt-test.yang", unformattedModule, nil)
+	if err != nil {
+		t.Fatalf("Unexpected Parse Error - %s", err)
+	}
+	if act := writeYang(t, tree); act != formattedModule {
+		t.Errorf("Unexpected YANG\nexpected:\n%s\nactual:\n%s",
+			formattedModule, act)
+	}
+}
+
+// Comment delimiters are not allowed anywhere in an unquoted string
+func TestWriteYangCommentDelimiters(t *testing.T) {
+	tree, err := Parse("quote-test.yang", `module quote-test {
+	namespace "http://example.com/quote-test";
+	prefix qt;
+	leaf l {
+		type string;
+		default "a/*b";
+		units "a*/b";
+	}
+}`, nil)
+	if err != nil {
+		t.Fatalf("Unexpected Parse Error - %s", err)
+	}
+	yang := writeYang(t, tree)
+	for _, exp := range []string{
+		`namespace "http://example.com/quote-test";`,
+		`default "a/*b";`,
+		`units "a*/b";`,
+	} {
+		if !strings.Contains(yang, exp) {
+			t.Errorf("Expected YANG to contain %s\n%s", exp, yang)
+		}
+	}
+	if _, err := Parse("quote-test.yang", yang, nil); err != nil {
+		t.Errorf("Unexpected Parse Error - %s\n%s", err, yang)
+	}
+}
+
+// dumpSortedStatements is dumpStatements, ignoring the order of statements
+func dumpSortedStatements(n Node) string {
+	children := make([]string, 0, len(n.Children()))
+	for _, c := range n.Children() {
+		children = append(children, dumpSortedStatements(c))
+	}
+	sort.Strings(children)
+	return fmt.Sprintf("%s %q {%s}",
+		n.Statement(), n.Argument().String(), strings.Join(children, " "))
+}
+
+// Formatting keeps every statement and argument, so gives the same tree
+// when parsed again, and formatted text is not changed by formatting.
+func TestWriteYangRoundTrip(t *testing.T) {
+	texts := map[string]string{
+		"yin-ext.yang":  yinImportedModule,
+		"yin-test.yang": yinTestModule,
+		"fmt-test.yang": formattedModule,
+	}
+	for _, file := range []string{
+		"testschemas/string_pass_concat.yang",
+		"testschemas/string_pass_esc_seq.yang",
+	} {
+		text, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		texts[file] = string(text)
+	}
+
+	for name, text := range texts {
+		t.Run(name, func(t *testing.T) {
+			tree, err := Parse(name, text, nil)
+			if err != nil {
+				t.Fatalf("Unexpected Parse Error - %s", err)
+			}
+			yang := writeYang(t, tree)
+
+			again, err := Parse(name, yang, nil)
+			if err != nil {
+				t.Fatalf("Unexpected Parse Error - %s\n%s", err, yang)
+			}
+			if exp, act := dumpSortedStatements(tree.Root),
+				dumpSortedStatements(again.Root); exp != act {
+				t.Errorf("Formatted tree differs\nexpected:\n%s\nactual:\n%s",
+					exp, act)
+			}
+			if formatted := writeYang(t, again); formatted != yang {
+				t.Errorf("Formatting not stable\nexpected:\n%s\nactual:\n%s",
+					yang, formatted)
+			}
+		})
+	}
+}
+
+func TestWriteYangFromYin(t *testing.T) {
+	modules := parseYinTestModules(t)
+	yin := writeYin(t, modules["yin-test"], modules)
+	tree, err := ParseYin("yin-test.yin", yin, nil)
+	if err != nil {
+		t.Fatalf("Unexpected ParseYin Error - %s", err)
+	}
+	if exp, act := writeYang(t, modules["yin-test"]), writeYang(t, tree); exp != act {
+		t.Errorf("YANG from YIN differs\nexpected:\n%s\nactual:\n%s", exp, act)
+	}
+}
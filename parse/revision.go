@@ -0,0 +1,121 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parse
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Several revisions of a module or submodule may be available, but only
+// one of them is compiled. An import or include with a revision-date
+// selects that revision, otherwise the latest revision is used.
+
+// RevisionKey is the key for a tree holding a revision of a module that is
+// not the only, or latest, revision available. It follows the RFC 7950
+// file naming convention.
+func RevisionKey(name, revision string) string {
+	return name + "@" + revision
+}
+
+// selectRevisions returns one tree for each module and submodule name,
+// the revisions available for each name, and for each name whose imports
+// or includes ask for different revisions, a description of the conflict.
+func selectRevisions(
+	trees map[string]*Tree,
+) (map[string]*Tree, map[string][]string, map[string]string) {
+	revisions := make(map[string]map[string]*Tree)
+	latest := make(map[string]*Tree)
+	for _, t := range trees {
+		name, rev := t.Root.Name(), t.Root.Revision()
+		if revisions[name] == nil {
+			revisions[name] = make(map[string]*Tree)
+		}
+		revisions[name][rev] = t
+
+		// Revision dates compare in date order as strings
+		if cur, ok := latest[name]; !ok || cur.Root.Revision() < rev {
+			latest[name] = t
+		}
+	}
+
+	// Selecting a revision may change the imports and includes that need
+	// to be satisfied, so repeat until the selection is stable. Modules are
+	// visited in order of name, so that the first of conflicting requests
+	// is always the one kept.
+	selected := latest
+	var conflicts map[string]string
+	for range trees {
+		type request struct{ rev, by string }
+		requests := make(map[string]request)
+		conflicts = make(map[string]string)
+		for _, name := range sortedTreeNames(selected) {
+			for _, ch := range selected[name].Root.Children() {
+				if ch.Type() != NodeImport && ch.Type() != NodeInclude {
+					continue
+				}
+				rev := ch.RevisionDate()
+				if rev == "" {
+					continue
+				}
+				req, ok := requests[ch.Name()]
+				if !ok {
+					requests[ch.Name()] = request{rev, name}
+					continue
+				}
+				if req.rev != rev && conflicts[ch.Name()] == "" {
+					conflicts[ch.Name()] = fmt.Sprintf(
+						"revision %s required by %s conflicts with revision %s required by %s",
+						rev, name, req.rev, req.by)
+				}
+			}
+		}
+
+		next := make(map[string]*Tree, len(latest))
+		changed := false
+		for name, t := range latest {
+			if want, ok := revisions[name][requests[name].rev]; ok {
+				t = want
+			}
+			next[name] = t
+			changed = changed || selected[name] != t
+		}
+		selected = next
+		if !changed {
+			break
+		}
+	}
+
+	available := make(map[string][]string, len(revisions))
+	for name, revs := range revisions {
+		for rev := range revs {
+			if rev != "" {
+				available[name] = append(available[name], rev)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(available[name])))
+	}
+
+	return selected, available, conflicts
+}
+
+func sortedTreeNames(trees map[string]*Tree) []string {
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
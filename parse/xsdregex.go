@@ -0,0 +1,628 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// YANG patterns use the regular expression dialect of XML Schema Part 2,
+// Appendix F, which differs from Go's RE2 syntax:
+//
+//   - the expression is implicitly anchored at both ends, and '^' and '$'
+//     are ordinary characters;
+//   - '.' matches anything except newline and carriage return;
+//   - \d, \w, \s and their negations are defined in terms of Unicode;
+//   - \i, \c, \I and \C match XML name characters;
+//   - \p{IsX} matches the Unicode block X;
+//   - character classes may subtract another class: [a-z-[aeiou]].
+//
+// translateXSDRegexp rewrites an XSD regexp into an equivalent RE2
+// expression, and rejects constructs that are not valid XSD.
+
+const maxRune = unicode.MaxRune
+
+type runeRange struct {
+	lo, hi rune
+}
+
+// A set of runes, held as sorted, non-overlapping, non-adjacent ranges
+type runeSet []runeRange
+
+func newRuneSet(ranges ...runeRange) runeSet {
+	return runeSet(nil).union(ranges)
+}
+
+func runeSetFromTable(tab *unicode.RangeTable) runeSet {
+	var ranges []runeRange
+	for _, r := range tab.R16 {
+		ranges = appendStrided(ranges, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range tab.R32 {
+		ranges = appendStrided(ranges, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return newRuneSet(ranges...)
+}
+
+func appendStrided(ranges []runeRange, lo, hi, stride rune) []runeRange {
+	if stride == 1 {
+		return append(ranges, runeRange{lo, hi})
+	}
+	for r := lo; r <= hi; r += stride {
+		ranges = append(ranges, runeRange{r, r})
+	}
+	return ranges
+}
+
+func (s runeSet) union(o []runeRange) runeSet {
+	all := make([]runeRange, 0, len(s)+len(o))
+	all = append(all, s...)
+	all = append(all, o...)
+	sort.Slice(all, func(i, j int) bool { return all[i].lo < all[j].lo })
+
+	out := make(runeSet, 0, len(all))
+	for _, r := range all {
+		if n := len(out); n > 0 && r.lo <= out[n-1].hi+1 {
+			if r.hi > out[n-1].hi {
+				out[n-1].hi = r.hi
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func (s runeSet) negate() runeSet {
+	out := make(runeSet, 0, len(s)+1)
+	next := rune(0)
+	for _, r := range s {
+		if r.lo > next {
+			out = append(out, runeRange{next, r.lo - 1})
+		}
+		next = r.hi + 1
+	}
+	if next <= maxRune {
+		out = append(out, runeRange{next, maxRune})
+	}
+	return out
+}
+
+func (s runeSet) subtract(o runeSet) runeSet {
+	// A - B == not(not(A) or B)
+	return s.negate().union(o).negate()
+}
+
+func quoteClassRune(r rune) string {
+	if r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return string(r)
+	}
+	return fmt.Sprintf(`\x{%X}`, r)
+}
+
+func (s runeSet) String() string {
+	if len(s) == 0 {
+		// Nothing can match an empty class
+		return `[^\x{0}-\x{10FFFF}]`
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	for _, r := range s {
+		b.WriteString(quoteClassRune(r.lo))
+		if r.hi != r.lo {
+			b.WriteByte('-')
+			b.WriteString(quoteClassRune(r.hi))
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// Part of a character class. native is the RE2 form of the item for use
+// inside a class, if there is one; set is always available, and is used
+// when the class needs to be computed, as for subtraction.
+type classItem struct {
+	native string
+	set    func() runeSet
+}
+
+func runeItem(r rune) classItem {
+	return classItem{
+		native: quoteClassRune(r),
+		set:    func() runeSet { return newRuneSet(runeRange{r, r}) },
+	}
+}
+
+func rangeItem(lo, hi rune) classItem {
+	return classItem{
+		native: quoteClassRune(lo) + "-" + quoteClassRune(hi),
+		set:    func() runeSet { return newRuneSet(runeRange{lo, hi}) },
+	}
+}
+
+func setItem(set func() runeSet) classItem {
+	return classItem{set: set}
+}
+
+func categoryItem(cat string, negated bool) classItem {
+	tab := unicode.Categories[cat]
+	native := `\p{` + cat + `}`
+	set := func() runeSet { return runeSetFromTable(tab) }
+	if negated {
+		native = `\P{` + cat + `}`
+		set = func() runeSet { return runeSetFromTable(tab).negate() }
+	}
+	return classItem{native: native, set: set}
+}
+
+func unionItem(items ...classItem) classItem {
+	var native strings.Builder
+	for _, it := range items {
+		if it.native == "" {
+			native.Reset()
+			break
+		}
+		native.WriteString(it.native)
+	}
+	return classItem{
+		native: native.String(),
+		set: func() runeSet {
+			var s runeSet
+			for _, it := range items {
+				s = s.union(it.set())
+			}
+			return s
+		},
+	}
+}
+
+func negateItem(it classItem) classItem {
+	return setItem(func() runeSet { return it.set().negate() })
+}
+
+// XML 1.0 (5th edition) NameStartChar, without ':' which is added below
+var xmlNameStartRanges = []runeRange{
+	{'A', 'Z'}, {'_', '_'}, {'a', 'z'}, {0xC0, 0xD6}, {0xD8, 0xF6},
+	{0xF8, 0x2FF}, {0x370, 0x37D}, {0x37F, 0x1FFF}, {0x200C, 0x200D},
+	{0x2070, 0x218F}, {0x2C00, 0x2FEF}, {0x3001, 0xD7FF}, {0xF900, 0xFDCF},
+	{0xFDF0, 0xFFFD}, {0x10000, 0xEFFFF},
+}
+
+// XML 1.0 (5th edition) NameChar, in addition to NameStartChar
+var xmlNameRanges = []runeRange{
+	{'-', '.'}, {'0', '9'}, {0xB7, 0xB7}, {0x300, 0x36F}, {0x203F, 0x2040},
+}
+
+func xmlNameStartSet() runeSet {
+	return newRuneSet(xmlNameStartRanges...).union([]runeRange{{':', ':'}})
+}
+
+func xmlNameSet() runeSet {
+	return xmlNameStartSet().union(xmlNameRanges)
+}
+
+// XSD \s is exactly space, tab, newline and carriage return
+var xsdSpaceItem = unionItem(runeItem(' '), runeItem('\t'),
+	runeItem('\n'), runeItem('\r'))
+
+// XSD \w is [#x0000-#x10FFFF]-[\p{P}\p{Z}\p{C}]
+var xsdNonWordItem = unionItem(categoryItem("P", false),
+	categoryItem("Z", false), categoryItem("C", false))
+
+func multiCharEscItem(c byte) (classItem, bool) {
+	switch c {
+	case 's':
+		return xsdSpaceItem, true
+	case 'S':
+		return negateItem(xsdSpaceItem), true
+	case 'i':
+		return setItem(xmlNameStartSet), true
+	case 'I':
+		return setItem(func() runeSet { return xmlNameStartSet().negate() }), true
+	case 'c':
+		return setItem(xmlNameSet), true
+	case 'C':
+		return setItem(func() runeSet { return xmlNameSet().negate() }), true
+	case 'd':
+		return categoryItem("Nd", false), true
+	case 'D':
+		return categoryItem("Nd", true), true
+	case 'w':
+		return negateItem(xsdNonWordItem), true
+	case 'W':
+		return xsdNonWordItem, true
+	}
+	return classItem{}, false
+}
+
+func singleCharEsc(c byte) (rune, bool) {
+	switch c {
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	case '\\', '|', '.', '?', '*', '+', '(', ')', '{', '}', '-', '[', ']', '^':
+		return rune(c), true
+	}
+	return 0, false
+}
+
+type xsdRegexp struct {
+	src string
+	pos int
+}
+
+func (x *xsdRegexp) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid pattern %q: %s", x.src,
+		fmt.Sprintf(format, args...))
+}
+
+func (x *xsdRegexp) eof() bool { return x.pos >= len(x.src) }
+
+func (x *xsdRegexp) peek() byte {
+	if x.eof() {
+		return 0
+	}
+	return x.src[x.pos]
+}
+
+func (x *xsdRegexp) nextRune() rune {
+	r, size := utf8.DecodeRuneInString(x.src[x.pos:])
+	x.pos += size
+	return r
+}
+
+// Parse \p{...} or \P{...}, with the escape character already consumed
+func (x *xsdRegexp) propertyItem(negated bool) (classItem, error) {
+	if x.peek() != '{' {
+		return classItem{}, x.errorf("missing '{' after \\p")
+	}
+	end := strings.IndexByte(x.src[x.pos:], '}')
+	if end < 0 {
+		return classItem{}, x.errorf("missing '}' after \\p")
+	}
+	name := x.src[x.pos+1 : x.pos+end]
+	x.pos += end + 1
+
+	if strings.HasPrefix(name, "Is") {
+		ranges, ok := xsdBlocks[name[2:]]
+		if !ok {
+			return classItem{}, x.errorf("unknown block %s", name)
+		}
+		if negated {
+			return setItem(func() runeSet {
+				return newRuneSet(ranges...).negate()
+			}), nil
+		}
+		return setItem(func() runeSet { return newRuneSet(ranges...) }), nil
+	}
+	if _, ok := unicode.Categories[name]; !ok {
+		return classItem{}, x.errorf("unknown category %s", name)
+	}
+	return categoryItem(name, negated), nil
+}
+
+// Parse an escape, returning either a single rune or a class item. The
+// leading '\' has been consumed.
+func (x *xsdRegexp) escape() (rune, *classItem, error) {
+	if x.eof() {
+		return 0, nil, x.errorf("trailing '\\'")
+	}
+	c := x.src[x.pos]
+	x.pos++
+	if r, ok := singleCharEsc(c); ok {
+		return r, nil, nil
+	}
+	if item, ok := multiCharEscItem(c); ok {
+		return 0, &item, nil
+	}
+	if c == 'p' || c == 'P' {
+		item, err := x.propertyItem(c == 'P')
+		if err != nil {
+			return 0, nil, err
+		}
+		return 0, &item, nil
+	}
+	return 0, nil, x.errorf("invalid escape \\%c", c)
+}
+
+// Parse a single character or single character escape within a class
+func (x *xsdRegexp) classChar() (rune, *classItem, error) {
+	switch c := x.peek(); c {
+	case '\\':
+		x.pos++
+		return x.escape()
+	case '[', ']':
+		return 0, nil, x.errorf("unescaped '%c' in character class", c)
+	}
+	return x.nextRune(), nil, nil
+}
+
+// A parsed character class; subtract is the class removed from it, if any
+type charClass struct {
+	negated  bool
+	items    []classItem
+	subtract *charClass
+}
+
+func (cc *charClass) set() runeSet {
+	set := unionItem(cc.items...).set()
+	if cc.negated {
+		set = set.negate()
+	}
+	if cc.subtract != nil {
+		set = set.subtract(cc.subtract.set())
+	}
+	return set
+}
+
+// RE2 form of the class, only computing it where RE2 has no equivalent
+func (cc *charClass) String() string {
+	all := unionItem(cc.items...)
+	if cc.subtract != nil || all.native == "" {
+		return cc.set().String()
+	}
+	if cc.negated {
+		return "[^" + all.native + "]"
+	}
+	return "[" + all.native + "]"
+}
+
+// Parse a character class, with the opening '[' already consumed
+func (x *xsdRegexp) class() (*charClass, error) {
+	cc := &charClass{}
+	if x.peek() == '^' {
+		cc.negated = true
+		x.pos++
+	}
+
+	for {
+		if x.eof() {
+			return nil, x.errorf("missing ']'")
+		}
+		c := x.peek()
+		if c == ']' && len(cc.items) > 0 {
+			x.pos++
+			return cc, nil
+		}
+		if c == '-' && len(cc.items) > 0 && x.pos+1 < len(x.src) {
+			switch x.src[x.pos+1] {
+			case '[':
+				x.pos += 2
+				sub, err := x.class()
+				if err != nil {
+					return nil, err
+				}
+				if x.peek() != ']' {
+					return nil, x.errorf("subtraction must be last in class")
+				}
+				x.pos++
+				cc.subtract = sub
+				return cc, nil
+			case ']':
+			default:
+				return nil, x.errorf("unescaped '-' in character class")
+			}
+		}
+
+		lo, item, err := x.classChar()
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			cc.items = append(cc.items, *item)
+			continue
+		}
+
+		// Range, unless '-' ends the class or starts a subtraction
+		if x.peek() == '-' && x.pos+1 < len(x.src) &&
+			x.src[x.pos+1] != ']' && x.src[x.pos+1] != '[' {
+			x.pos++
+			hi, hiItem, err := x.classChar()
+			if err != nil {
+				return nil, err
+			}
+			if hiItem != nil {
+				return nil, x.errorf("invalid range end")
+			}
+			if hi < lo {
+				return nil, x.errorf("invalid range %c-%c", lo, hi)
+			}
+			cc.items = append(cc.items, rangeItem(lo, hi))
+			continue
+		}
+		cc.items = append(cc.items, runeItem(lo))
+	}
+}
+
+func (x *xsdRegexp) translate() (string, error) {
+	var b strings.Builder
+	quantified := false
+	for !x.eof() {
+		c := x.peek()
+		isQuantifier := false
+		switch c {
+		case '\\':
+			x.pos++
+			r, item, err := x.escape()
+			if err != nil {
+				return "", err
+			}
+			if item == nil {
+				b.WriteString("[" + quoteClassRune(r) + "]")
+			} else if item.native != "" {
+				b.WriteString("[" + item.native + "]")
+			} else {
+				b.WriteString(item.set().String())
+			}
+		case '[':
+			x.pos++
+			cc, err := x.class()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(cc.String())
+		case ']':
+			return "", x.errorf("unexpected ']'")
+		case '.':
+			x.pos++
+			b.WriteString(`[^\n\r]`)
+		case '^', '$':
+			x.pos++
+			b.WriteString(`\` + string(c))
+		case '(':
+			x.pos++
+			if x.peek() == '?' {
+				return "", x.errorf("unexpected '?' after '('")
+			}
+			b.WriteString("(?:")
+		case '?', '*', '+':
+			if quantified {
+				return "", x.errorf("unexpected '%c' after quantifier", c)
+			}
+			x.pos++
+			isQuantifier = true
+			b.WriteByte(c)
+		case '{':
+			if quantified {
+				return "", x.errorf("unexpected '{' after quantifier")
+			}
+			end := strings.IndexByte(x.src[x.pos:], '}')
+			if end < 0 {
+				return "", x.errorf("missing '}'")
+			}
+			b.WriteString(x.src[x.pos : x.pos+end+1])
+			x.pos += end + 1
+			isQuantifier = true
+		default:
+			b.WriteString(string(x.nextRune()))
+		}
+		quantified = isQuantifier
+	}
+	return "^(?:" + b.String() + ")$", nil
+}
+
+// Translate a YANG (XSD) pattern into an anchored RE2 expression
+func translateXSDRegexp(pattern string) (string, error) {
+	x := &xsdRegexp{src: pattern}
+	return x.translate()
+}
+
+// Unicode blocks that may be referenced as \p{IsX}, as listed in XML
+// Schema Part 2, Appendix F.1.1
+var xsdBlocks = map[string][]runeRange{
+	"BasicLatin":                           {{0x0000, 0x007F}},
+	"Latin-1Supplement":                    {{0x0080, 0x00FF}},
+	"LatinExtended-A":                      {{0x0100, 0x017F}},
+	"LatinExtended-B":                      {{0x0180, 0x024F}},
+	"IPAExtensions":                        {{0x0250, 0x02AF}},
+	"SpacingModifierLetters":               {{0x02B0, 0x02FF}},
+	"CombiningDiacriticalMarks":            {{0x0300, 0x036F}},
+	"Greek":                                {{0x0370, 0x03FF}},
+	"Cyrillic":                             {{0x0400, 0x04FF}},
+	"Armenian":                             {{0x0530, 0x058F}},
+	"Hebrew":                               {{0x0590, 0x05FF}},
+	"Arabic":                               {{0x0600, 0x06FF}},
+	"Syriac":                               {{0x0700, 0x074F}},
+	"Thaana":                               {{0x0780, 0x07BF}},
+	"Devanagari":                           {{0x0900, 0x097F}},
+	"Bengali":                              {{0x0980, 0x09FF}},
+	"Gurmukhi":                             {{0x0A00, 0x0A7F}},
+	"Gujarati":                             {{0x0A80, 0x0AFF}},
+	"Oriya":                                {{0x0B00, 0x0B7F}},
+	"Tamil":                                {{0x0B80, 0x0BFF}},
+	"Telugu":                               {{0x0C00, 0x0C7F}},
+	"Kannada":                              {{0x0C80, 0x0CFF}},
+	"Malayalam":                            {{0x0D00, 0x0D7F}},
+	"Sinhala":                              {{0x0D80, 0x0DFF}},
+	"Thai":                                 {{0x0E00, 0x0E7F}},
+	"Lao":                                  {{0x0E80, 0x0EFF}},
+	"Tibetan":                              {{0x0F00, 0x0FFF}},
+	"Myanmar":                              {{0x1000, 0x109F}},
+	"Georgian":                             {{0x10A0, 0x10FF}},
+	"HangulJamo":                           {{0x1100, 0x11FF}},
+	"Ethiopic":                             {{0x1200, 0x137F}},
+	"Cherokee":                             {{0x13A0, 0x13FF}},
+	"UnifiedCanadianAboriginalSyllabics":   {{0x1400, 0x167F}},
+	"Ogham":                                {{0x1680, 0x169F}},
+	"Runic":                                {{0x16A0, 0x16FF}},
+	"Khmer":                                {{0x1780, 0x17FF}},
+	"Mongolian":                            {{0x1800, 0x18AF}},
+	"LatinExtendedAdditional":              {{0x1E00, 0x1EFF}},
+	"GreekExtended":                        {{0x1F00, 0x1FFF}},
+	"GeneralPunctuation":                   {{0x2000, 0x206F}},
+	"SuperscriptsandSubscripts":            {{0x2070, 0x209F}},
+	"CurrencySymbols":                      {{0x20A0, 0x20CF}},
+	"CombiningMarksforSymbols":             {{0x20D0, 0x20FF}},
+	"LetterlikeSymbols":                    {{0x2100, 0x214F}},
+	"NumberForms":                          {{0x2150, 0x218F}},
+	"Arrows":                               {{0x2190, 0x21FF}},
+	"MathematicalOperators":                {{0x2200, 0x22FF}},
+	"MiscellaneousTechnical":               {{0x2300, 0x23FF}},
+	"ControlPictures":                      {{0x2400, 0x243F}},
+	"OpticalCharacterRecognition":          {{0x2440, 0x245F}},
+	"EnclosedAlphanumerics":                {{0x2460, 0x24FF}},
+	"BoxDrawing":                           {{0x2500, 0x257F}},
+	"BlockElements":                        {{0x2580, 0x259F}},
+	"GeometricShapes":                      {{0x25A0, 0x25FF}},
+	"MiscellaneousSymbols":                 {{0x2600, 0x26FF}},
+	"Dingbats":                             {{0x2700, 0x27BF}},
+	"BraillePatterns":                      {{0x2800, 0x28FF}},
+	"CJKRadicalsSupplement":                {{0x2E80, 0x2EFF}},
+	"KangxiRadicals":                       {{0x2F00, 0x2FDF}},
+	"IdeographicDescriptionCharacters":     {{0x2FF0, 0x2FFF}},
+	"CJKSymbolsandPunctuation":             {{0x3000, 0x303F}},
+	"Hiragana":                             {{0x3040, 0x309F}},
+	"Katakana":                             {{0x30A0, 0x30FF}},
+	"Bopomofo":                             {{0x3100, 0x312F}},
+	"HangulCompatibilityJamo":              {{0x3130, 0x318F}},
+	"Kanbun":                               {{0x3190, 0x319F}},
+	"BopomofoExtended":                     {{0x31A0, 0x31BF}},
+	"EnclosedCJKLettersandMonths":          {{0x3200, 0x32FF}},
+	"CJKCompatibility":                     {{0x3300, 0x33FF}},
+	"CJKUnifiedIdeographsExtensionA":       {{0x3400, 0x4DB5}},
+	"CJKUnifiedIdeographs":                 {{0x4E00, 0x9FFF}},
+	"YiSyllables":                          {{0xA000, 0xA48F}},
+	"YiRadicals":                           {{0xA490, 0xA4CF}},
+	"HangulSyllables":                      {{0xAC00, 0xD7A3}},
+	"HighSurrogates":                       {{0xD800, 0xDB7F}},
+	"HighPrivateUseSurrogates":             {{0xDB80, 0xDBFF}},
+	"LowSurrogates":                        {{0xDC00, 0xDFFF}},
+	"PrivateUse":                           {{0xE000, 0xF8FF}, {0xF0000, 0x10FFFD}},
+	"CJKCompatibilityIdeographs":           {{0xF900, 0xFAFF}},
+	"AlphabeticPresentationForms":          {{0xFB00, 0xFB4F}},
+	"ArabicPresentationForms-A":            {{0xFB50, 0xFDFF}},
+	"CombiningHalfMarks":                   {{0xFE20, 0xFE2F}},
+	"CJKCompatibilityForms":                {{0xFE30, 0xFE4F}},
+	"SmallFormVariants":                    {{0xFE50, 0xFE6F}},
+	"ArabicPresentationForms-B":            {{0xFE70, 0xFEFE}},
+	"Specials":                             {{0xFEFF, 0xFEFF}, {0xFFF0, 0xFFFD}},
+	"HalfwidthandFullwidthForms":           {{0xFF00, 0xFFEF}},
+	"OldItalic":                            {{0x10300, 0x1032F}},
+	"Gothic":                               {{0x10330, 0x1034F}},
+	"Deseret":                              {{0x10400, 0x1044F}},
+	"ByzantineMusicalSymbols":              {{0x1D000, 0x1D0FF}},
+	"MusicalSymbols":                       {{0x1D100, 0x1D1FF}},
+	"MathematicalAlphanumericSymbols":      {{0x1D400, 0x1D7FF}},
+	"CJKUnifiedIdeographsExtensionB":       {{0x20000, 0x2A6D6}},
+	"CJKCompatibilityIdeographsSupplement": {{0x2F800, 0x2FA1F}},
+	"Tags":                                 {{0xE0000, 0xE007F}},
+}
@@ -0,0 +1,707 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements YIN, the XML syntax of YANG (RFC 7950; Sec 13).
+// A YIN module parses to the same tree as the YANG module it maps to, so
+// the rest of the compiler doesn't need to know which syntax was used.
+
+package parse
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const YinNamespace = "urn:ietf:params:xml:ns:yang:yin:1"
+
+// yinArgument is how the argument of a YANG statement is mapped to YIN:
+// the name of the attribute, or of the child element if yinElement is set.
+// Statements without an argument have no name.
+type yinArgument struct {
+	name       string
+	yinElement bool
+}
+
+// RFC 7950; Sec 13.1, Table 1
+var yinArguments = map[string]yinArgument{
+	"action":           {name: "name"},
+	"anydata":          {name: "name"},
+	"anyxml":           {name: "name"},
+	"argument":         {name: "name"},
+	"augment":          {name: "target-node"},
+	"base":             {name: "name"},
+	"belongs-to":       {name: "module"},
+	"bit":              {name: "name"},
+	"case":             {name: "name"},
+	"choice":           {name: "name"},
+	"config":           {name: "value"},
+	"contact":          {name: "text", yinElement: true},
+	"container":        {name: "name"},
+	"default":          {name: "value"},
+	"description":      {name: "text", yinElement: true},
+	"deviate":          {name: "value"},
+	"deviation":        {name: "target-node"},
+	"enum":             {name: "name"},
+	"error-app-tag":    {name: "value"},
+	"error-message":    {name: "value", yinElement: true},
+	"extension":        {name: "name"},
+	"feature":          {name: "name"},
+	"fraction-digits":  {name: "value"},
+	"grouping":         {name: "name"},
+	"identity":         {name: "name"},
+	"if-feature":       {name: "name"},
+	"import":           {name: "module"},
+	"include":          {name: "module"},
+	"input":            {},
+	"key":              {name: "value"},
+	"leaf":             {name: "name"},
+	"leaf-list":        {name: "name"},
+	"length":           {name: "value"},
+	"list":             {name: "name"},
+	"mandatory":        {name: "value"},
+	"max-elements":     {name: "value"},
+	"min-elements":     {name: "value"},
+	"modifier":         {name: "value"},
+	"module":           {name: "name"},
+	"must":             {name: "condition"},
+	"namespace":        {name: "uri"},
+	"notification":     {name: "name"},
+	"ordered-by":       {name: "value"},
+	"organization":     {name: "text", yinElement: true},
+	"output":           {},
+	"path":             {name: "value"},
+	"pattern":          {name: "value"},
+	"position":         {name: "value"},
+	"prefix":           {name: "value"},
+	"presence":         {name: "value"},
+	"range":            {name: "value"},
+	"reference":        {name: "text", yinElement: true},
+	"refine":           {name: "target-node"},
+	"require-instance": {name: "value"},
+	"revision":         {name: "date"},
+	"revision-date":    {name: "date"},
+	"rpc":              {name: "name"},
+	"status":           {name: "value"},
+	"submodule":        {name: "name"},
+	"type":             {name: "name"},
+	"typedef":          {name: "name"},
+	"unique":           {name: "tag"},
+	"units":            {name: "name"},
+	"uses":             {name: "name"},
+	"value":            {name: "value"},
+	"when":             {name: "condition"},
+	"yang-version":     {name: "value"},
+	"yin-element":      {name: "value"},
+}
+
+// yinElement is an element of a YIN document, with its namespace resolved
+// but the raw prefix kept, as that is the prefix of extension statements.
+type yinElement struct {
+	pos      Pos
+	prefix   string
+	space    string
+	local    string
+	attrs    []xml.Attr
+	children []*yinElement
+	text     strings.Builder
+	ns       map[string]string
+}
+
+func (e *yinElement) attr(name string) (string, bool) {
+	for _, a := range e.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// child returns the first child element with the given name, in the
+// namespace of e.
+func (e *yinElement) child(local string) *yinElement {
+	for _, c := range e.children {
+		if c.space == e.space && c.local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+func (e *yinElement) rawName() string {
+	if e.prefix == "" {
+		return e.local
+	}
+	return e.prefix + ":" + e.local
+}
+
+// ParseYin parses a module in YIN syntax to the same tree that Parse gives
+// for the equivalent YANG module.
+func ParseYin(name, text string, extCard NodeCardinality) (*Tree, error) {
+	return ParseYinWithInterners(name, text, extCard, NewStringInterner(), NewArgInterner())
+}
+
+func ParseYinWithInterners(
+	name, text string,
+	extCard NodeCardinality,
+	stringInterner *StringInterner,
+	argInterner *ArgInterner,
+) (*Tree, error) {
+	return ParseYinWithModules(name, text, extCard, nil, stringInterner, argInterner)
+}
+
+// ParseYinWithModules parses a module in YIN syntax, looking up the modules
+// it imports, and the module a submodule belongs to, by name in modules, as
+// WriteYin does. The arguments of their extensions are mapped as those
+// modules define. For a module not found, the argument is worked out from
+// the elements: it is taken to be the only attribute, or failing that a
+// child element in the same namespace with only text.
+func ParseYinWithModules(
+	name, text string,
+	extCard NodeCardinality,
+	modules map[string]*Tree,
+	stringInterner *StringInterner,
+	argInterner *ArgInterner,
+) (*Tree, error) {
+	t := NewWithInterners(name, extCard, stringInterner, argInterner)
+	t.text = text
+	t.yin = true
+	defer t.done()
+	err := t.parseYin(modules)
+	if err != nil {
+		t.Root = nil
+	}
+	return t, err
+}
+
+// YinImports returns the revision date, or an empty string, of each module
+// imported by a module in YIN syntax, or that a submodule belongs to. These
+// are the modules ParseYinWithModules needs.
+func YinImports(name, text string) (map[string]string, error) {
+	t := New(name, nil)
+	t.text = text
+	root, err := t.readYin()
+	if err != nil {
+		return nil, err
+	}
+	imports := make(map[string]string)
+	for _, c := range root.children {
+		if c.space != YinNamespace {
+			continue
+		}
+		switch c.local {
+		case "import":
+			mod, _ := c.attr("module")
+			var rev string
+			if r := c.child("revision-date"); r != nil {
+				rev, _ = r.attr("date")
+			}
+			imports[mod] = rev
+		case "belongs-to":
+			mod, _ := c.attr("module")
+			imports[mod] = ""
+		}
+	}
+	return imports, nil
+}
+
+func (t *Tree) yinErrorf(pos int, format string, args ...interface{}) error {
+	line, col := t.Position(pos)
+	format = fmt.Sprintf("yin: %s:%d:%d: %s", t.ParseName, line, col, format)
+	return fmt.Errorf(format, args...)
+}
+
+func (t *Tree) parseYin(modules map[string]*Tree) error {
+	root, err := t.readYin()
+	if err != nil {
+		return err
+	}
+	if root.space != YinNamespace {
+		return t.yinErrorf(int(root.pos),
+			"%s is not in the YIN namespace", root.rawName())
+	}
+
+	y := &yinReader{
+		tree:       t,
+		modules:    modules,
+		extensions: make(map[string]yinArgument),
+		imports:    make(map[string]string),
+	}
+	y.findExtensions(root)
+
+	s := OpenScope(nil)
+	if t.Root, err = y.stmt(root, s); err != nil {
+		return err
+	}
+
+	//Fill out symbol tables top down, as for YANG
+	err, pos := t.Root.buildSymbols()
+	if err != nil {
+		s, _ := t.ErrorContextPosition(int(pos), "")
+		return fmt.Errorf("%s: %s", s, err)
+	}
+	return nil
+}
+
+// readYin reads the document into a tree of elements, so that extensions
+// defined by the module are known before their statements are built.
+func (t *Tree) readYin() (*yinElement, error) {
+	d := xml.NewDecoder(strings.NewReader(t.text))
+	var root *yinElement
+	var stack []*yinElement
+
+	for {
+		pos := int(d.InputOffset())
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var serr *xml.SyntaxError
+			if errors.As(err, &serr) {
+				return nil, t.yinErrorf(int(d.InputOffset()), "%s", serr.Msg)
+			}
+			return nil, t.yinErrorf(pos, "%s", err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			e := &yinElement{
+				pos:    Pos(pos),
+				prefix: tok.Name.Space,
+				local:  tok.Name.Local,
+				ns:     make(map[string]string),
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				for k, v := range parent.ns {
+					e.ns[k] = v
+				}
+				parent.children = append(parent.children, e)
+			} else if root != nil {
+				return nil, t.yinErrorf(pos, "more than one top-level element")
+			} else {
+				root = e
+			}
+			for _, a := range tok.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					e.ns[""] = a.Value
+				case a.Name.Space == "xmlns":
+					e.ns[a.Name.Local] = a.Value
+				default:
+					e.attrs = append(e.attrs, a)
+				}
+			}
+			space, ok := e.ns[e.prefix]
+			if !ok && e.prefix != "" {
+				return nil, t.yinErrorf(pos, "undeclared prefix %s", e.prefix)
+			}
+			e.space = space
+			stack = append(stack, e)
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, t.yinErrorf(pos, "unexpected </%s>", tok.Name.Local)
+			}
+			e := stack[len(stack)-1]
+			end := &yinElement{prefix: tok.Name.Space, local: tok.Name.Local}
+			if end.rawName() != e.rawName() {
+				return nil, t.yinErrorf(pos,
+					"element <%s> closed by </%s>", e.rawName(), end.rawName())
+			}
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(tok)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, t.yinErrorf(len(t.text), "no module found")
+	}
+	if len(stack) > 0 {
+		return nil, t.yinErrorf(len(t.text),
+			"unexpected EOF in element <%s>", stack[len(stack)-1].rawName())
+	}
+	return root, nil
+}
+
+type yinReader struct {
+	tree    *Tree
+	modules map[string]*Tree
+
+	// The namespace and prefix of the module, and how the arguments of the
+	// extensions it defines are mapped.
+	space      string
+	prefix     string
+	extensions map[string]yinArgument
+
+	// The modules imported, or belonged to, by namespace
+	imports map[string]string
+}
+
+func (y *yinReader) findExtensions(root *yinElement) {
+	prefixStmt := root.child("prefix")
+	if b := root.child("belongs-to"); b != nil {
+		prefixStmt = b.child("prefix")
+	}
+	if prefixStmt != nil {
+		y.prefix, _ = prefixStmt.attr("value")
+		y.space = root.ns[y.prefix]
+	}
+	if b := root.child("belongs-to"); b != nil && y.space != "" {
+		y.imports[y.space], _ = b.attr("module")
+	}
+
+	for _, ext := range root.children {
+		if ext.space == YinNamespace && ext.local == "import" {
+			if p := ext.child("prefix"); p != nil {
+				prefix, _ := p.attr("value")
+				if space := root.ns[prefix]; space != "" {
+					y.imports[space], _ = ext.attr("module")
+				}
+			}
+		}
+		if ext.space != YinNamespace || ext.local != "extension" {
+			continue
+		}
+		name, _ := ext.attr("name")
+		var arg yinArgument
+		if a := ext.child("argument"); a != nil {
+			arg.name, _ = a.attr("name")
+			if yin := a.child("yin-element"); yin != nil {
+				v, _ := yin.attr("value")
+				arg.yinElement = v == "true"
+			}
+		}
+		y.extensions[name] = arg
+	}
+}
+
+// extensionArgument returns how the argument of an extension statement is
+// mapped. The extension is looked up in the module that defines it, if
+// known, or else its argument is worked out from the elements.
+func (y *yinReader) extensionArgument(e *yinElement) (yinArgument, error) {
+	if y.space != "" && e.space == y.space {
+		if arg, ok := y.extensions[e.local]; ok {
+			return arg, nil
+		}
+	}
+	if name, ok := y.imports[e.space]; ok {
+		if m := y.modules[name]; m != nil && m.Root != nil {
+			ext := m.Root.LookupChild(NodeExtension, e.local)
+			if ext == nil {
+				return yinArgument{}, y.tree.yinErrorf(int(e.pos),
+					"extension %s not found in module %s", e.local, name)
+			}
+			return extensionYinArgument(ext), nil
+		}
+	}
+	if len(e.attrs) == 1 && e.attrs[0].Name.Space == "" {
+		return yinArgument{name: e.attrs[0].Name.Local}, nil
+	}
+	if len(e.children) > 0 {
+		c := e.children[0]
+		if c.space == e.space && len(c.attrs) == 0 && len(c.children) == 0 {
+			return yinArgument{name: c.local, yinElement: true}, nil
+		}
+	}
+	return yinArgument{}, nil
+}
+
+func (y *yinReader) stmt(e *yinElement, s *Scope) (Node, error) {
+	t := y.tree
+	var keyword string
+	var spec yinArgument
+
+	switch {
+	case e.space == YinNamespace:
+		var ok bool
+		if spec, ok = yinArguments[e.local]; !ok {
+			return nil, t.yinErrorf(int(e.pos), "unknown statement %s", e.local)
+		}
+		keyword = e.local
+	case e.space == "":
+		return nil, t.yinErrorf(int(e.pos),
+			"statement %s is not in a namespace", e.rawName())
+	default:
+		prefix := e.prefix
+		if e.space == y.space && y.prefix != "" {
+			prefix = y.prefix
+		}
+		if prefix == "" {
+			return nil, t.yinErrorf(int(e.pos),
+				"extension %s has no prefix", e.local)
+		}
+		keyword = prefix + ":" + e.local
+		var err error
+		if spec, err = y.extensionArgument(e); err != nil {
+			return nil, err
+		}
+	}
+
+	var arg string
+	var argElem *yinElement
+	switch {
+	case spec.name == "":
+	case spec.yinElement:
+		argElem = e.child(spec.name)
+		if argElem == nil {
+			return nil, t.yinErrorf(int(e.pos),
+				"missing <%s> element in %s", spec.name, keyword)
+		}
+		arg = argElem.text.String()
+	default:
+		var ok bool
+		if arg, ok = e.attr(spec.name); !ok {
+			return nil, t.yinErrorf(int(e.pos),
+				"missing %s attribute in %s", spec.name, keyword)
+		}
+	}
+
+	//Link scopes as we walk the tree, as for YANG
+	ns := OpenScope(s)
+	var body []Node
+	for _, c := range e.children {
+		if c == argElem {
+			continue
+		}
+		n, err := y.stmt(c, ns)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, n)
+	}
+
+	id := item{typ: itemString, pos: e.pos, val: t.stringInterner.Intern(keyword)}
+	n := t.NewNode(id, arg, body, s)
+
+	//Validate cardinality, ordering, and argument syntax
+	if err := n.check(); err != nil {
+		s, _ := n.ErrorContext()
+		return nil, fmt.Errorf("%s: %s", s, err)
+	}
+	return n, nil
+}
+
+// WriteYin writes the module or submodule parsed in t as YIN. The modules
+// it imports, and the module a submodule belongs to, are looked up by name
+// in modules for their namespaces and extensions; they are needed only
+// when the tree has statements of their extensions.
+//
+// The tree should be as parsed, as the compiler adds nodes to it.
+func WriteYin(w io.Writer, t *Tree, modules map[string]*Tree) error {
+	if t == nil || t.Root == nil {
+		return errors.New("yin: no module to write")
+	}
+	y := &yinWriter{
+		modules:  modules,
+		prefixes: make(map[string]Node),
+		spaces:   make(map[string]string),
+	}
+	root := t.Root
+
+	var prefix, space string
+	if b := root.ChildByType(NodeBelongsTo); b != nil {
+		prefix = b.Prefix()
+		if m := modules[b.Name()]; m != nil && m.Root != nil {
+			space = m.Root.Ns()
+		}
+	} else {
+		prefix = root.Prefix()
+		space = root.Ns()
+	}
+	y.prefixes[prefix] = root
+	if space != "" {
+		y.spaces[prefix] = space
+	}
+
+	xmlns := []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: YinNamespace}}
+	if space != "" {
+		xmlns = append(xmlns,
+			xml.Attr{Name: xml.Name{Space: "xmlns", Local: prefix}, Value: space})
+	}
+	for _, imp := range root.ChildrenByType(NodeImport) {
+		m := modules[imp.Name()]
+		if m == nil || m.Root == nil {
+			continue
+		}
+		y.prefixes[imp.Prefix()] = m.Root
+		y.spaces[imp.Prefix()] = m.Root.Ns()
+		xmlns = append(xmlns, xml.Attr{
+			Name:  xml.Name{Space: "xmlns", Local: imp.Prefix()},
+			Value: m.Root.Ns()})
+	}
+
+	y.buf.WriteString(xml.Header)
+	if err := y.stmt(root, 0, xmlns); err != nil {
+		return err
+	}
+	_, err := w.Write(y.buf.Bytes())
+	return err
+}
+
+type yinWriter struct {
+	buf      bytes.Buffer
+	modules  map[string]*Tree
+	prefixes map[string]Node   // Module (or submodule) root by prefix
+	spaces   map[string]string // Namespace by prefix
+}
+
+func (y *yinWriter) extensionArgument(
+	n Node,
+	prefix, name string,
+) (yinArgument, error) {
+
+	root, ok := y.prefixes[prefix]
+	if !ok || y.spaces[prefix] == "" {
+		return yinArgument{}, fmt.Errorf(
+			"yin: %s: namespace of prefix %s not known", n.Statement(), prefix)
+	}
+	ext := root.LookupChild(NodeExtension, name)
+	if ext == nil {
+		return yinArgument{}, fmt.Errorf(
+			"yin: %s: extension %s not found", n.Statement(), name)
+	}
+	return extensionYinArgument(ext), nil
+}
+
+// extensionYinArgument returns how the argument of a parsed extension
+// statement is mapped.
+func extensionYinArgument(ext Node) yinArgument {
+	var arg yinArgument
+	if a := ext.ChildByType(NodeArgument); a != nil {
+		arg.name = a.Name()
+		if yin := a.ChildByType(NodeYinElement); yin != nil {
+			arg.yinElement = yin.ArgBool()
+		}
+	}
+	return arg
+}
+
+func (y *yinWriter) stmt(n Node, depth int, xmlns []xml.Attr) error {
+	keyword := n.Statement()
+	spec, ok := yinArguments[keyword]
+	if !ok {
+		prefix, name, found := strings.Cut(keyword, ":")
+		if !found {
+			return fmt.Errorf("yin: unknown statement %s", keyword)
+		}
+		var err error
+		if spec, err = y.extensionArgument(n, prefix, name); err != nil {
+			return err
+		}
+	}
+
+	var arg string
+	if a := n.Argument(); a != nil {
+		arg = a.String()
+	}
+
+	indent := strings.Repeat("  ", depth)
+	y.buf.WriteString(indent + "<" + keyword)
+	if spec.name != "" && !spec.yinElement {
+		y.buf.WriteString(" " + spec.name + "=\"")
+		writeYinAttr(&y.buf, arg)
+		y.buf.WriteString("\"")
+	}
+	// Namespace declarations are aligned under the first attribute
+	attrIndent := "\n" + indent + strings.Repeat(" ", len(keyword)+2)
+	for i, a := range xmlns {
+		if i > 0 || spec.name != "" && !spec.yinElement {
+			y.buf.WriteString(attrIndent)
+		} else {
+			y.buf.WriteString(" ")
+		}
+		if a.Name.Space != "" {
+			y.buf.WriteString(a.Name.Space + ":")
+		}
+		y.buf.WriteString(a.Name.Local + "=\"")
+		writeYinAttr(&y.buf, a.Value)
+		y.buf.WriteString("\"")
+	}
+
+	children := n.Children()
+	if len(children) == 0 && !spec.yinElement {
+		y.buf.WriteString("/>\n")
+		return nil
+	}
+	y.buf.WriteString(">\n")
+
+	if spec.yinElement {
+		argName := spec.name
+		if prefix, _, found := strings.Cut(keyword, ":"); found {
+			argName = prefix + ":" + argName
+		}
+		y.buf.WriteString(indent + "  <" + argName + ">")
+		writeYinText(&y.buf, arg)
+		y.buf.WriteString("</" + argName + ">\n")
+	}
+	for _, c := range children {
+		if n.Type() == NodeChoice && implicitCase(c) {
+			c = c.Children()[0]
+		}
+		if err := y.stmt(c, depth+1, nil); err != nil {
+			return err
+		}
+	}
+	y.buf.WriteString(indent + "</" + keyword + ">\n")
+	return nil
+}
+
+// Arguments are written exactly, so text keeps its whitespace but
+// attributes must escape it.
+func writeYinText(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+func writeYinAttr(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '"':
+			buf.WriteString("&quot;")
+		case '\n':
+			buf.WriteString("&#xA;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		case '\t':
+			buf.WriteString("&#x9;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
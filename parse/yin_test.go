@@ -0,0 +1,412 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parse_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/sdcio/yang-parser/parse"
+)
+
+const yinImportedModule = `module yin-ext {
+	namespace "urn:yin-ext";
+	prefix ext;
+
+	extension note {
+		argument text {
+			yin-element true;
+		}
+	}
+	extension tag {
+		argument name;
+	}
+	extension marker;
+}`
+
+const yinTestModule = `module yin-test {
+	yang-version 1.1;
+	namespace "urn:yin-test";
+	prefix yt;
+
+	import yin-ext {
+		prefix ext;
+	}
+
+	organization "Test & <Co>";
+	contact "Someone
+		 with a \"quoted\" address";
+	description
+		"Multi-line
+		 description";
+
+	revision 2024-01-01 {
+		description "Initial revision.";
+	}
+
+	extension local {
+		argument value;
+	}
+
+	feature fancy;
+
+	typedef name-type {
+		type string {
+			length "1..32";
+			pattern '[a-z]+' {
+				error-message "Only <lower> case";
+			}
+		}
+	}
+
+	container top {
+		presence "Top level";
+		yt:local "on top";
+		ext:note "A note
+			over two lines";
+		ext:tag "tagged";
+		ext:marker;
+		must "count(item) < 5" {
+			error-app-tag too-many;
+		}
+		list item {
+			key "name";
+			unique "value";
+			ordered-by user;
+			leaf name {
+				type name-type;
+			}
+			leaf value {
+				type uint32 {
+					range "1..100";
+				}
+				units "seconds";
+				default 10;
+			}
+		}
+		choice pick {
+			if-feature fancy;
+			case one {
+				leaf a {
+					type empty;
+				}
+			}
+			leaf b {
+				type boolean;
+			}
+		}
+	}
+
+	rpc reset {
+		input {
+			leaf force {
+				type boolean;
+			}
+		}
+		output {
+			leaf done {
+				type boolean;
+			}
+		}
+	}
+}`
+
+// dumpStatements lists the statements of the tree, with their arguments
+func dumpStatements(n Node) string {
+	var b strings.Builder
+	var dump func(n Node, depth int)
+	dump = func(n Node, depth int) {
+		fmt.Fprintf(&b, "%s%s %q\n",
+			strings.Repeat("  ", depth), n.Statement(), n.Argument().String())
+		for _, c := range n.Children() {
+			dump(c, depth+1)
+		}
+	}
+	dump(n, 0)
+	return b.String()
+}
+
+func parseYinTestModules(t *testing.T) map[string]*Tree {
+	t.Helper()
+	modules := make(map[string]*Tree)
+	for _, text := range []string{yinImportedModule, yinTestModule} {
+		tree, err := Parse("test", text, nil)
+		if err != nil {
+			t.Fatalf("Unexpected Parse Error - %s", err)
+		}
+		modules[tree.Root.Name()] = tree
+	}
+	return modules
+}
+
+func writeYin(t *testing.T, tree *Tree, modules map[string]*Tree) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteYin(&buf, tree, modules); err != nil {
+		t.Fatalf("Unexpected WriteYin Error - %s", err)
+	}
+	return buf.String()
+}
+
+func TestYinRoundTrip(t *testing.T) {
+	modules := parseYinTestModules(t)
+
+	for _, name := range []string{"yin-ext", "yin-test"} {
+		t.Run(name, func(t *testing.T) {
+			yang := modules[name]
+			yin := writeYin(t, yang, modules)
+
+			tree, err := ParseYin(name+".yin", yin, nil)
+			if err != nil {
+				t.Fatalf("Unexpected ParseYin Error - %s\n%s", err, yin)
+			}
+			if exp, act := dumpStatements(yang.Root), dumpStatements(tree.Root); exp != act {
+				t.Errorf("YIN tree differs from YANG tree\nexpected:\n%s\nactual:\n%s",
+					exp, act)
+			}
+			if again := writeYin(t, tree, modules); again != yin {
+				t.Errorf("YIN not written back the same\nexpected:\n%s\nactual:\n%s",
+					yin, again)
+			}
+		})
+	}
+}
+
+func TestWriteYin(t *testing.T) {
+	modules := parseYinTestModules(t)
+	yin := writeYin(t, modules["yin-test"], modules)
+
+	for _, exp := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>
+<module name="yin-test"
+        xmlns="urn:ietf:params:xml:ns:yang:yin:1"
+        xmlns:yt="urn:yin-test"
+        xmlns:ext="urn:yin-ext">
+  <yang-version value="1.1"/>
+`,
+		"  <organization>\n    <text>Test &amp; &lt;Co&gt;</text>\n  </organization>\n",
+		"  <contact>\n    <text>Someone\nwith a \"quoted\" address</text>\n",
+		"    <yt:local value=\"on top\"/>\n",
+		"    <ext:note>\n      <ext:text>A note\nover two lines</ext:text>\n    </ext:note>\n",
+		"    <ext:tag name=\"tagged\"/>\n",
+		"    <ext:marker/>\n",
+		"    <must condition=\"count(item) &lt; 5\">\n",
+		"        <error-message>\n          <value>Only &lt;lower&gt; case</value>\n",
+		"    <input>\n",
+	} {
+		if !strings.Contains(yin, exp) {
+			t.Errorf("Expected YIN to contain:\n%s\nYIN:\n%s", exp, yin)
+		}
+	}
+}
+
+func TestWriteYinUnknownExtension(t *testing.T) {
+	modules := parseYinTestModules(t)
+	delete(modules, "yin-ext")
+
+	var buf bytes.Buffer
+	err := WriteYin(&buf, modules["yin-test"], modules)
+	checkError(t, err, "yin: ext:note: namespace of prefix ext not known")
+}
+
+// Extensions of other modules are not known when reading, so their
+// argument is worked out from the elements.
+func TestParseYin(t *testing.T) {
+	yin := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- A YIN module -->
+<module name="yin-read"
+        xmlns="urn:ietf:params:xml:ns:yang:yin:1"
+        xmlns:yr="urn:yin-read"
+        xmlns:ext="urn:yin-ext">
+  <namespace uri="urn:yin-read"/>
+  <prefix value="yr"/>
+  <import module="yin-ext">
+    <prefix value="ext"/>
+  </import>
+  <description>
+    <text>Text with &lt;markup&gt;</text>
+  </description>
+  <revision date="2024-01-01"/>
+  <extension name="flag">
+    <argument name="on">
+      <yin-element value="true"/>
+    </argument>
+  </extension>
+  <leaf name="l">
+    <type name="string"/>
+    <yr:flag>
+      <yr:on>yes</yr:on>
+    </yr:flag>
+    <ext:tag name="tagged"/>
+    <ext:note>
+      <ext:text>noted</ext:text>
+    </ext:note>
+    <ext:marker/>
+  </leaf>
+</module>
+`
+	yang := `module yin-read {
+	namespace "urn:yin-read";
+	prefix yr;
+	import yin-ext {
+		prefix ext;
+	}
+	description "Text with <markup>";
+	revision 2024-01-01;
+	extension flag {
+		argument on {
+			yin-element true;
+		}
+	}
+	leaf l {
+		type string;
+		yr:flag yes;
+		ext:tag tagged;
+		ext:note noted;
+		ext:marker;
+	}
+}`
+
+	exp, err := Parse("yin-read.yang", yang, nil)
+	if err != nil {
+		t.Fatalf("Unexpected Parse Error - %s", err)
+	}
+	act, err := ParseYin("yin-read.yin", yin, nil)
+	if err != nil {
+		t.Fatalf("Unexpected ParseYin Error - %s", err)
+	}
+	if e, a := dumpStatements(exp.Root), dumpStatements(act.Root); e != a {
+		t.Errorf("YIN tree differs from YANG tree\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	leaf := act.Root.LookupChild(NodeLeaf, "l")
+	if leaf == nil {
+		t.Fatalf("leaf l not found")
+	}
+	if _, line, col := leaf.Location(); line != 21 || col != 2 {
+		t.Errorf("Expected leaf l at 21:2, found %d:%d", line, col)
+	}
+}
+
+// An argument-less extension with a substatement of its own module looks
+// like one with its argument in an element, unless the module is known.
+func TestParseYinWithModules(t *testing.T) {
+	modules := parseYinTestModules(t)
+	yang := `module yin-nested {
+	namespace "urn:yin-nested";
+	prefix yn;
+	import yin-ext {
+		prefix ext;
+	}
+	leaf l {
+		type string;
+		ext:marker {
+			ext:marker;
+		}
+	}
+}`
+	exp, err := Parse("yin-nested.yang", yang, nil)
+	if err != nil {
+		t.Fatalf("Unexpected Parse Error - %s", err)
+	}
+	yin := writeYin(t, exp, modules)
+
+	imports, err := YinImports("yin-nested.yin", yin)
+	if err != nil {
+		t.Fatalf("Unexpected YinImports Error - %s", err)
+	}
+	if len(imports) != 1 || imports["yin-ext"] != "" {
+		t.Errorf("Unexpected imports %v", imports)
+	}
+
+	act, err := ParseYinWithModules("yin-nested.yin", yin, nil, modules,
+		NewStringInterner(), NewArgInterner())
+	if err != nil {
+		t.Fatalf("Unexpected ParseYin Error - %s", err)
+	}
+	if e, a := dumpStatements(exp.Root), dumpStatements(act.Root); e != a {
+		t.Errorf("YIN tree differs from YANG tree\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	guessed, err := ParseYin("yin-nested.yin", yin, nil)
+	if err != nil {
+		t.Fatalf("Unexpected ParseYin Error - %s", err)
+	}
+	if dumpStatements(exp.Root) == dumpStatements(guessed.Root) {
+		t.Errorf("Expected argument to be guessed without the imported module")
+	}
+
+	missing := strings.Replace(yin, "<ext:marker/>", "<ext:missing/>", 1)
+	_, err = ParseYinWithModules("yin-nested.yin", missing, nil, modules,
+		NewStringInterner(), NewArgInterner())
+	checkError(t, err, "extension missing not found in module yin-ext")
+}
+
+func TestParseYinErrors(t *testing.T) {
+	const header = `<module name="bad" xmlns="urn:ietf:params:xml:ns:yang:yin:1">
+  <namespace uri="urn:bad"/>
+  <prefix value="bad"/>
+  <revision date="2024-01-01"/>
+`
+	tests := []struct {
+		name string
+		yin  string
+		err  string
+	}{
+		{
+			name: "not YIN",
+			yin:  `<module name="bad"/>`,
+			err:  "yin: bad.yin:1:0: module is not in the YIN namespace",
+		},
+		{
+			name: "unknown statement",
+			yin:  header + "  <leafy name=\"l\"/>\n</module>",
+			err:  "yin: bad.yin:5:2: unknown statement leafy",
+		},
+		{
+			name: "missing attribute",
+			yin:  header + "  <leaf/>\n</module>",
+			err:  "yin: bad.yin:5:2: missing name attribute in leaf",
+		},
+		{
+			name: "missing element",
+			yin:  header + "  <description/>\n</module>",
+			err:  "yin: bad.yin:5:2: missing <text> element in description",
+		},
+		{
+			name: "mismatched element",
+			yin:  header + "</modules>",
+			err:  "yin: bad.yin:5:0: element <module> closed by </modules>",
+		},
+		{
+			name: "cardinality",
+			yin: header + `  <leaf name="l">
+    <type name="string"/>
+    <type name="uint8"/>
+  </leaf>
+</module>`,
+			err: "bad.yin:5:2: leaf l: cardinality mismatch: only one 'type' statement is allowed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseYin("bad.yin", test.yin, nil)
+			checkError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,1271 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements encoding of a compiled model set, so that it may be
+// saved and loaded again without compiling its modules.
+//
+// The model set is a graph rather than a tree: nodes within choices and
+// cases are also children of the enclosing node, and the merged tree of
+// the model set shares its nodes with the trees of the models. So each
+// node, type, model and so on is encoded once as a record, and referred to
+// by its index in the records, starting from one as zero means nil.
+
+package schema
+
+import (
+	"bufio"
+	encbinary "encoding/binary"
+	"encoding/gob"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/sdcio/yang-parser/xpath"
+)
+
+// ModelSetFormatVersion is the version of the encoding written by
+// EncodeModelSet. It changes whenever the encoding does, and
+// DecodeModelSet only reads its own version.
+const ModelSetFormatVersion = 2
+
+const modelSetMagic = "YANGMS"
+
+// ErrModelSetFormat is returned, wrapped, when decoding a model set
+// encoded with a different format version.
+var ErrModelSetFormat = errors.New("unsupported model set format version")
+
+// A DecorationCodec encodes and decodes the values that compile
+// extensions wrap around nodes, types, models, rpcs and so on. Any value
+// of a type not defined by this package is taken to be a decoration.
+type DecorationCodec interface {
+	// Undecorate returns the value v decorates, and the data needed to
+	// decorate it again.
+	Undecorate(v interface{}) (inner interface{}, data []byte, err error)
+
+	// Decorate decorates inner as described by data. Values are decoded
+	// before they are filled in, so Decorate must only keep inner, not
+	// call its methods.
+	Decorate(inner interface{}, data []byte) (interface{}, error)
+}
+
+type recordKind uint8
+
+const (
+	recordDecorated recordKind = iota + 1
+	recordTree
+	recordModelSet
+	recordContainer
+	recordList
+	recordLeaf
+	recordLeafList
+	recordAnydata
+	recordAnyxml
+	recordChoice
+	recordCase
+	recordOpdCommand
+	recordOpdArgument
+	recordOpdOption
+	recordModel
+	recordSubmodule
+	recordRpc
+	recordAction
+	recordNotification
+	recordBinary
+	recordBoolean
+	recordDecimal64
+	recordEmpty
+	recordEnumeration
+	recordInteger
+	recordUinteger
+	recordString
+	recordUnion
+	recordIdentityref
+	recordInstanceId
+	recordLeafref
+	recordBits
+)
+
+// Records and their parts have exported fields for encoding/gob, which
+// leaves out those that are empty.
+
+type namedRef struct {
+	Name string
+	Ref  int
+}
+
+type namespaceRefs struct {
+	Namespace string
+	Refs      []namedRef
+}
+
+type nodeRecord struct {
+	Name        xml.Name
+	Module      string
+	Submodule   string
+	Desc        string
+	Ref         string
+	Config      bool
+	Status      Status
+	IfFeatures  []string
+	Arguments   []string
+	Whens       []WhenContext
+	Musts       []MustContext
+	Children    []namedRef
+	DefChildren []namedRef
+	Choices     []int
+	Actions     []namedRef
+	Notifs      []namedRef
+	Parent      int
+}
+
+type opdRecord struct {
+	OnEnter     string
+	Priv        bool
+	Local       bool
+	Secret      bool
+	Repeatable  bool
+	PassOpcArgs bool
+	OpdHelp     string
+	OpdAllowed  string
+}
+
+type typeRecord struct {
+	Name       xml.Name
+	Default    string
+	HasDefault bool
+}
+
+type enumRecord struct {
+	Val    string
+	Desc   string
+	Ref    string
+	Status Status
+	Value  int
+}
+
+type bitRecord struct {
+	Name   string
+	Desc   string
+	Ref    string
+	Status Status
+	Pos    uint32
+}
+
+type identityRecord struct {
+	Val       string
+	Desc      string
+	Ref       string
+	Status    Status
+	Value     string
+	Module    string
+	Namespace string
+	Bases     []string
+}
+
+type patternRecord struct {
+	Pattern     string
+	Regexp      string
+	HasRegexp   bool
+	Msg         string
+	AppTag      string
+	InvertMatch bool
+}
+
+type record struct {
+	Kind recordKind
+
+	// Decorations
+	Inner      int
+	Decoration []byte
+
+	// Schema nodes
+	Node      *nodeRecord
+	Opd       *opdRecord
+	Presence  bool
+	Mandatory bool
+	Units     string
+	OrderedBy string
+	Default   string
+	Defaults  []string
+	Limit     Limit
+	Keys      []string
+	Uniques   [][][]xml.Name
+	Type      int
+
+	// Models, rpcs and notifications
+	Schema          schemaDetails
+	Text            string
+	Tree            int
+	Output          int
+	Features        []string
+	Deviations      []string
+	Rpcs            []namedRef
+	Notifications   []namedRef
+	Modules         []namedRef
+	Submodules      []namedRef
+	ModelSetRpcs    []namespaceRefs
+	ModelSetNotifys []namespaceRefs
+
+	// Types
+	Typ          *typeRecord
+	Length       *Length
+	Fracdigit    Fracdigit
+	BitWidth     BitWidth
+	Rbs          RbSlice
+	Urbs         UrbSlice
+	Drbs         DrbSlice
+	Msg          string
+	AppTag       string
+	Enums        []enumRecord
+	Bits         []bitRecord
+	Identities   []identityRecord
+	Patterns     [][]patternRecord
+	PatternHelps [][]string
+	Types        []int
+	Require      bool
+	Machine      *xpath.Machine
+}
+
+type encodedModelSet struct {
+	Root    int
+	Records []record
+}
+
+// EncodeModelSet writes ms to w, to be read by DecodeModelSet. The codec
+// may be nil if ms was compiled without extensions.
+func EncodeModelSet(w io.Writer, ms ModelSet, codec DecorationCodec) error {
+	e := &encoder{
+		codec: codec,
+		refs:  make(map[interface{}]int),
+		bases: make(map[*node]int),
+	}
+	root, err := e.ref(ms)
+	if err != nil {
+		return err
+	}
+
+	header := encbinary.AppendUvarint([]byte(modelSetMagic), ModelSetFormatVersion)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(&encodedModelSet{
+		Root:    root,
+		Records: e.records,
+	})
+}
+
+// DecodeModelSet reads a model set written by EncodeModelSet, using the
+// same codec. Any custom xpath functions used by the model set must be
+// registered first.
+func DecodeModelSet(r io.Reader, codec DecorationCodec) (ModelSet, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(modelSetMagic))
+	if _, err := io.ReadFull(br, magic); err != nil ||
+		string(magic) != modelSetMagic {
+		return nil, errors.New("not an encoded model set")
+	}
+	version, err := encbinary.ReadUvarint(br)
+	if err != nil {
+		return nil, errors.New("not an encoded model set")
+	}
+	if version != ModelSetFormatVersion {
+		return nil, fmt.Errorf("%w: found %d, expected %d",
+			ErrModelSetFormat, version, ModelSetFormatVersion)
+	}
+
+	var enc encodedModelSet
+	if err := gob.NewDecoder(br).Decode(&enc); err != nil {
+		return nil, err
+	}
+	d := &decoder{
+		codec:      codec,
+		records:    enc.Records,
+		values:     make([]interface{}, len(enc.Records)),
+		bases:      make([]*node, len(enc.Records)),
+		decorating: make([]bool, len(enc.Records)),
+	}
+	if err := d.decode(); err != nil {
+		return nil, err
+	}
+	v, err := d.value(enc.Root)
+	if err != nil {
+		return nil, err
+	}
+	ms, ok := v.(ModelSet)
+	if !ok {
+		return nil, fmt.Errorf("encoded %T is not a model set", v)
+	}
+	return ms, nil
+}
+
+type encoder struct {
+	codec   DecorationCodec
+	refs    map[interface{}]int
+	bases   map[*node]int
+	records []record
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func (e *encoder) ref(v interface{}) (int, error) {
+	if isNil(v) {
+		return 0, nil
+	}
+	// Values of uncomparable types can't be shared, so are always new
+	comparable := reflect.TypeOf(v).Comparable()
+	if comparable {
+		if id, ok := e.refs[v]; ok {
+			return id, nil
+		}
+	}
+	id := len(e.records) + 1
+	e.records = append(e.records, record{})
+	if comparable {
+		e.refs[v] = id
+	}
+
+	rec, err := e.encode(id, v)
+	if err != nil {
+		return 0, err
+	}
+	e.records[id-1] = rec
+	return id, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (e *encoder) namedRefs(m interface{}) ([]namedRef, error) {
+	rv := reflect.ValueOf(m)
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	refs := make([]namedRef, 0, len(keys))
+	for _, k := range keys {
+		id, err := e.ref(rv.MapIndex(k).Interface())
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, namedRef{Name: k.String(), Ref: id})
+	}
+	return refs, nil
+}
+
+func (e *encoder) encodeNode(id int, n *node) (*nodeRecord, error) {
+	e.bases[n] = id
+	r := &nodeRecord{
+		Name:       n.name,
+		Module:     n.module,
+		Submodule:  n.submodule,
+		Desc:       n.Desc,
+		Ref:        n.Ref,
+		Config:     n.config,
+		Status:     n.status,
+		IfFeatures: n.ifFeatures,
+		Arguments:  n.arguments,
+		Whens:      n.whenContexts,
+		Musts:      n.mustContexts,
+	}
+	if n.parent != nil {
+		parent, ok := e.bases[n.parent]
+		if !ok {
+			return nil, fmt.Errorf("parent of %s not encoded", n.name.Local)
+		}
+		r.Parent = parent
+	}
+
+	var err error
+	if r.Children, err = e.namedRefs(n.children); err != nil {
+		return nil, err
+	}
+	if r.DefChildren, err = e.namedRefs(n.defChildren); err != nil {
+		return nil, err
+	}
+	if r.Actions, err = e.namedRefs(n.actions); err != nil {
+		return nil, err
+	}
+	if r.Notifs, err = e.namedRefs(n.notifs); err != nil {
+		return nil, err
+	}
+	for _, ch := range n.choices {
+		chID, err := e.ref(ch)
+		if err != nil {
+			return nil, err
+		}
+		r.Choices = append(r.Choices, chID)
+	}
+	return r, nil
+}
+
+func encodeOpd(
+	onEnter string,
+	priv, local, secret, repeatable, passOpcArgs bool,
+	opdHelp, opdAllowed string,
+) *opdRecord {
+	return &opdRecord{
+		OnEnter:     onEnter,
+		Priv:        priv,
+		Local:       local,
+		Secret:      secret,
+		Repeatable:  repeatable,
+		PassOpcArgs: passOpcArgs,
+		OpdHelp:     opdHelp,
+		OpdAllowed:  opdAllowed,
+	}
+}
+
+func encodeType(t *ytyp) *typeRecord {
+	return &typeRecord{Name: t.name, Default: t.def, HasDefault: t.hasDefault}
+}
+
+func (e *encoder) encode(id int, v interface{}) (record, error) {
+	var err error
+	var r record
+
+	switch v := v.(type) {
+	case *tree:
+		r.Kind = recordTree
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *modelSet:
+		r.Kind = recordModelSet
+		if r.Node, err = e.encodeNode(id, v.node); err != nil {
+			break
+		}
+		if r.Modules, err = e.namedRefs(v.modules); err != nil {
+			break
+		}
+		if r.Submodules, err = e.namedRefs(v.submodules); err != nil {
+			break
+		}
+		for _, ns := range sortedKeys(v.rpcs) {
+			refs, err := e.namedRefs(v.rpcs[ns])
+			if err != nil {
+				return r, err
+			}
+			r.ModelSetRpcs = append(r.ModelSetRpcs, namespaceRefs{ns, refs})
+		}
+		for _, ns := range sortedKeys(v.notifications) {
+			refs, err := e.namedRefs(v.notifications[ns])
+			if err != nil {
+				return r, err
+			}
+			r.ModelSetNotifys = append(r.ModelSetNotifys,
+				namespaceRefs{ns, refs})
+		}
+
+	case *container:
+		r.Kind = recordContainer
+		r.Presence = v.presence
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *list:
+		r.Kind = recordList
+		r.OrderedBy = v.orderedBy
+		r.Limit = v.limit
+		r.Keys = v.keys
+		r.Uniques = v.uniques
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *leaf:
+		r.Kind = recordLeaf
+		r.Units = v.units
+		r.Mandatory = v.mandatory
+		if r.Type, err = e.ref(v.typ); err != nil {
+			break
+		}
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *leafList:
+		r.Kind = recordLeafList
+		r.Defaults = v.defs
+		r.Units = v.units
+		r.Limit = v.limit
+		r.OrderedBy = v.orderedBy
+		if r.Type, err = e.ref(v.typ); err != nil {
+			break
+		}
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *anydata:
+		r.Kind = recordAnydata
+		r.Mandatory = v.mandatory
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *anyxml:
+		r.Kind = recordAnyxml
+		r.Mandatory = v.mandatory
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *choice:
+		r.Kind = recordChoice
+		r.Mandatory = v.mandatory
+		r.Default = v.def
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *ycase:
+		r.Kind = recordCase
+		r.Mandatory = v.mandatory
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *opdCommand:
+		r.Kind = recordOpdCommand
+		r.Opd = encodeOpd(v.onEnter, v.priv, v.local, v.secret,
+			v.repeatable, v.passOpcArgs, "", "")
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *opdArgument:
+		r.Kind = recordOpdArgument
+		r.Units = v.units
+		r.Mandatory = v.mandatory
+		r.Opd = encodeOpd(v.onEnter, v.priv, v.local, v.secret,
+			v.repeatable, v.passOpcArgs, v.opdHelp, v.opdAllowed)
+		if r.Type, err = e.ref(v.typ); err != nil {
+			break
+		}
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *opdOption:
+		r.Kind = recordOpdOption
+		r.Units = v.units
+		r.Mandatory = v.mandatory
+		r.Opd = encodeOpd(v.onEnter, v.priv, v.local, v.secret,
+			v.repeatable, v.passOpcArgs, v.opdHelp, v.opdAllowed)
+		if r.Type, err = e.ref(v.typ); err != nil {
+			break
+		}
+		r.Node, err = e.encodeNode(id, v.node)
+
+	case *model:
+		r.Kind = recordModel
+		r.Schema = v.schema
+		r.Text = v.data
+		r.Features = v.features
+		r.Deviations = v.deviations
+		if r.Tree, err = e.ref(v.Tree); err != nil {
+			break
+		}
+		if r.Rpcs, err = e.namedRefs(v.rpcs); err != nil {
+			break
+		}
+		r.Notifications, err = e.namedRefs(v.notifications)
+
+	case *submodule:
+		r.Kind = recordSubmodule
+		r.Schema = v.schema
+		r.Text = v.data
+
+	case *rpc:
+		r.Kind = recordRpc
+		if r.Tree, err = e.ref(v.input); err != nil {
+			break
+		}
+		r.Output, err = e.ref(v.output)
+
+	case *action:
+		r.Kind = recordAction
+		if r.Tree, err = e.ref(v.input); err != nil {
+			break
+		}
+		r.Output, err = e.ref(v.output)
+
+	case *notification:
+		r.Kind = recordNotification
+		r.Tree, err = e.ref(v.notification)
+
+	case *binary:
+		r.Kind = recordBinary
+		r.Typ = encodeType(&v.ytyp)
+		r.Length = v.len
+
+	case *boolean:
+		r.Kind = recordBoolean
+		r.Typ = encodeType(&v.ytyp)
+
+	case *decimal64:
+		r.Kind = recordDecimal64
+		r.Typ = encodeType(&v.ytyp)
+		r.Fracdigit = v.fd
+		r.Drbs = v.rbs
+		r.Msg = v.msg
+		r.AppTag = v.appTag
+
+	case *empty:
+		r.Kind = recordEmpty
+		r.Typ = encodeType(&v.ytyp)
+
+	case *enumeration:
+		r.Kind = recordEnumeration
+		r.Typ = encodeType(&v.ytyp)
+		for _, en := range v.enums {
+			r.Enums = append(r.Enums, enumRecord{
+				en.Val, en.Desc, en.Ref, en.status, en.Value})
+		}
+
+	case *integer:
+		r.Kind = recordInteger
+		r.Typ = encodeType(&v.ytyp)
+		r.BitWidth = v.t
+		r.Rbs = v.rbs
+		r.Msg = v.msg
+		r.AppTag = v.appTag
+
+	case *uinteger:
+		r.Kind = recordUinteger
+		r.Typ = encodeType(&v.ytyp)
+		r.BitWidth = v.t
+		r.Urbs = v.rbs
+		r.Msg = v.msg
+		r.AppTag = v.appTag
+
+	case *ystring:
+		r.Kind = recordString
+		r.Typ = encodeType(&v.ytyp)
+		r.Length = v.len
+		r.PatternHelps = v.pathelps
+		for _, pats := range v.pats {
+			recs := make([]patternRecord, 0, len(pats))
+			for _, pat := range pats {
+				rec := patternRecord{
+					Pattern:     pat.Pattern,
+					Msg:         pat.Msg,
+					AppTag:      pat.AppTag,
+					InvertMatch: pat.InvertMatch,
+				}
+				if pat.Regexp != nil {
+					rec.Regexp, rec.HasRegexp = pat.Regexp.String(), true
+				}
+				recs = append(recs, rec)
+			}
+			r.Patterns = append(r.Patterns, recs)
+		}
+
+	case *union:
+		r.Kind = recordUnion
+		r.Typ = encodeType(&v.ytyp)
+		for _, typ := range v.typs {
+			typID, err := e.ref(typ)
+			if err != nil {
+				return r, err
+			}
+			r.Types = append(r.Types, typID)
+		}
+
+	case *identityref:
+		r.Kind = recordIdentityref
+		r.Typ = encodeType(&v.ytyp)
+		for _, ident := range v.identities {
+			r.Identities = append(r.Identities, identityRecord{
+				ident.Val, ident.Desc, ident.Ref, ident.status,
+				ident.Value, ident.Module, ident.Namespace, ident.bases})
+		}
+
+	case *instanceId:
+		r.Kind = recordInstanceId
+		r.Typ = encodeType(&v.ytyp)
+		r.Require = v.require
+
+	case *leafref:
+		r.Kind = recordLeafref
+		r.Typ = encodeType(&v.ytyp)
+		r.Machine = v.mach
+
+	case *bits:
+		r.Kind = recordBits
+		r.Typ = encodeType(&v.ytyp)
+		for _, b := range v.Bs {
+			r.Bits = append(r.Bits, bitRecord{
+				b.Name, b.Desc, b.Ref, b.status, b.Pos})
+		}
+
+	default:
+		if e.codec == nil {
+			return r, fmt.Errorf("cannot encode %T without a DecorationCodec", v)
+		}
+		var inner interface{}
+		inner, r.Decoration, err = e.codec.Undecorate(v)
+		if err != nil {
+			return r, err
+		}
+		if isNil(inner) {
+			return r, fmt.Errorf("%T does not decorate a value", v)
+		}
+		r.Kind = recordDecorated
+		r.Inner, err = e.ref(inner)
+	}
+	return r, err
+}
+
+type decoder struct {
+	codec      DecorationCodec
+	records    []record
+	values     []interface{}
+	bases      []*node
+	decorating []bool
+}
+
+// decode makes each value in three passes: first the values of this
+// package, so that decorations and references have something to refer to,
+// then the decorations, and finally the contents of the first values.
+func (d *decoder) decode() error {
+	for i := range d.records {
+		d.allocate(i)
+	}
+	for i, r := range d.records {
+		if r.Kind == recordDecorated {
+			if _, err := d.value(i + 1); err != nil {
+				return err
+			}
+		} else if d.values[i] == nil {
+			return fmt.Errorf("unknown record kind %d", r.Kind)
+		}
+	}
+	for i := range d.records {
+		if err := d.fill(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) allocate(i int) {
+	base := &node{}
+	switch d.records[i].Kind {
+	case recordTree:
+		d.values[i] = &tree{node: base}
+	case recordModelSet:
+		d.values[i] = &modelSet{tree: tree{node: base}}
+	case recordContainer:
+		d.values[i] = &container{node: base}
+	case recordList:
+		d.values[i] = &list{node: base}
+	case recordLeaf:
+		d.values[i] = &leaf{node: base}
+	case recordLeafList:
+		d.values[i] = &leafList{node: base}
+	case recordAnydata:
+		d.values[i] = &anydata{node: base}
+	case recordAnyxml:
+		d.values[i] = &anyxml{node: base}
+	case recordChoice:
+		d.values[i] = &choice{node: base}
+	case recordCase:
+		d.values[i] = &ycase{node: base}
+	case recordOpdCommand:
+		d.values[i] = &opdCommand{node: base}
+	case recordOpdArgument:
+		d.values[i] = &opdArgument{node: base}
+	case recordOpdOption:
+		d.values[i] = &opdOption{node: base}
+	case recordModel:
+		d.values[i] = &model{}
+	case recordSubmodule:
+		d.values[i] = &submodule{}
+	case recordRpc:
+		d.values[i] = &rpc{}
+	case recordAction:
+		d.values[i] = &action{}
+	case recordNotification:
+		d.values[i] = &notification{}
+	case recordBinary:
+		d.values[i] = &binary{}
+	case recordBoolean:
+		d.values[i] = &boolean{}
+	case recordDecimal64:
+		d.values[i] = &decimal64{}
+	case recordEmpty:
+		d.values[i] = &empty{}
+	case recordEnumeration:
+		d.values[i] = &enumeration{}
+	case recordInteger:
+		d.values[i] = &integer{}
+	case recordUinteger:
+		d.values[i] = &uinteger{}
+	case recordString:
+		d.values[i] = &ystring{}
+	case recordUnion:
+		d.values[i] = &union{}
+	case recordIdentityref:
+		d.values[i] = &identityref{}
+	case recordInstanceId:
+		d.values[i] = &instanceId{}
+	case recordLeafref:
+		d.values[i] = &leafref{}
+	case recordBits:
+		d.values[i] = &bits{}
+	default:
+		return
+	}
+	if d.records[i].Node != nil {
+		d.bases[i] = base
+	}
+}
+
+// value returns the value for a reference, decorating it if need be.
+func (d *decoder) value(id int) (interface{}, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	if id < 0 || id > len(d.records) {
+		return nil, fmt.Errorf("invalid reference %d", id)
+	}
+	i := id - 1
+	if d.values[i] != nil {
+		return d.values[i], nil
+	}
+
+	r := d.records[i]
+	if r.Kind != recordDecorated {
+		return nil, fmt.Errorf("invalid reference %d", id)
+	}
+	if d.codec == nil {
+		return nil, errors.New(
+			"cannot decode decorations without a DecorationCodec")
+	}
+	if d.decorating[i] {
+		return nil, fmt.Errorf("decoration %d decorates itself", id)
+	}
+	d.decorating[i] = true
+	inner, err := d.value(r.Inner)
+	if err != nil {
+		return nil, err
+	}
+	v, err := d.codec.Decorate(inner, r.Decoration)
+	if err != nil {
+		return nil, err
+	}
+	d.values[i] = v
+	return v, nil
+}
+
+func (d *decoder) node(id int) (Node, error) {
+	v, err := d.value(id)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	n, ok := v.(Node)
+	if !ok {
+		return nil, fmt.Errorf("decoded %T is not a schema node", v)
+	}
+	return n, nil
+}
+
+func (d *decoder) tree(id int) (Tree, error) {
+	v, err := d.value(id)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	t, ok := v.(Tree)
+	if !ok {
+		return nil, fmt.Errorf("decoded %T is not a schema tree", v)
+	}
+	return t, nil
+}
+
+func (d *decoder) typ(id int) (Type, error) {
+	v, err := d.value(id)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	t, ok := v.(Type)
+	if !ok {
+		return nil, fmt.Errorf("decoded %T is not a type", v)
+	}
+	return t, nil
+}
+
+func (d *decoder) nodes(refs []namedRef) (map[string]Node, error) {
+	m := make(map[string]Node, len(refs))
+	for _, ref := range refs {
+		n, err := d.node(ref.Ref)
+		if err != nil {
+			return nil, err
+		}
+		m[ref.Name] = n
+	}
+	return m, nil
+}
+
+func (d *decoder) rpcs(refs []namedRef) (map[string]Rpc, error) {
+	m := make(map[string]Rpc, len(refs))
+	for _, ref := range refs {
+		v, err := d.value(ref.Ref)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := v.(Rpc)
+		if !ok {
+			return nil, fmt.Errorf("decoded %T is not an rpc", v)
+		}
+		m[ref.Name] = r
+	}
+	return m, nil
+}
+
+func (d *decoder) actions(refs []namedRef) (map[string]Action, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]Action, len(refs))
+	for _, ref := range refs {
+		v, err := d.value(ref.Ref)
+		if err != nil {
+			return nil, err
+		}
+		a, ok := v.(Action)
+		if !ok {
+			return nil, fmt.Errorf("decoded %T is not an action", v)
+		}
+		m[ref.Name] = a
+	}
+	return m, nil
+}
+
+func (d *decoder) notifications(
+	refs []namedRef,
+) (map[string]Notification, error) {
+	m := make(map[string]Notification, len(refs))
+	for _, ref := range refs {
+		v, err := d.value(ref.Ref)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := v.(Notification)
+		if !ok {
+			return nil, fmt.Errorf("decoded %T is not a notification", v)
+		}
+		m[ref.Name] = n
+	}
+	return m, nil
+}
+
+func (d *decoder) fillNode(n *node, r *nodeRecord) error {
+	n.name = r.Name
+	n.module = r.Module
+	n.submodule = r.Submodule
+	n.Desc = r.Desc
+	n.Ref = r.Ref
+	n.config = r.Config
+	n.status = r.Status
+	n.ifFeatures = r.IfFeatures
+	n.arguments = r.Arguments
+	n.whenContexts = r.Whens
+	n.mustContexts = r.Musts
+	if r.Parent != 0 {
+		if r.Parent > len(d.bases) || d.bases[r.Parent-1] == nil {
+			return fmt.Errorf("invalid parent of %s", r.Name.Local)
+		}
+		n.parent = d.bases[r.Parent-1]
+	}
+
+	var err error
+	if n.children, err = d.nodes(r.Children); err != nil {
+		return err
+	}
+	if n.defChildren, err = d.nodes(r.DefChildren); err != nil {
+		return err
+	}
+	if n.actions, err = d.actions(r.Actions); err != nil {
+		return err
+	}
+	if len(r.Notifs) > 0 {
+		if n.notifs, err = d.notifications(r.Notifs); err != nil {
+			return err
+		}
+	}
+	for _, id := range r.Choices {
+		ch, err := d.node(id)
+		if err != nil {
+			return err
+		}
+		n.choices = append(n.choices, ch)
+	}
+	return nil
+}
+
+func decodeType(r *typeRecord) ytyp {
+	if r == nil {
+		return ytyp{}
+	}
+	return newType(r.Name, r.Default, r.HasDefault)
+}
+
+func (d *decoder) fill(i int) error {
+	r := &d.records[i]
+	if r.Node != nil {
+		if err := d.fillNode(d.bases[i], r.Node); err != nil {
+			return err
+		}
+	}
+	var opd opdRecord
+	if r.Opd != nil {
+		opd = *r.Opd
+	}
+
+	var err error
+	switch v := d.values[i].(type) {
+	case *modelSet:
+		v.modules = make(map[string]Model, len(r.Modules))
+		for _, ref := range r.Modules {
+			m, err := d.value(ref.Ref)
+			if err != nil {
+				return err
+			}
+			model, ok := m.(Model)
+			if !ok {
+				return fmt.Errorf("decoded %T is not a model", m)
+			}
+			v.modules[ref.Name] = model
+		}
+		v.submodules = make(map[string]Submodule, len(r.Submodules))
+		for _, ref := range r.Submodules {
+			m, err := d.value(ref.Ref)
+			if err != nil {
+				return err
+			}
+			sub, ok := m.(Submodule)
+			if !ok {
+				return fmt.Errorf("decoded %T is not a submodule", m)
+			}
+			v.submodules[ref.Name] = sub
+		}
+		v.rpcs = make(map[string]map[string]Rpc, len(r.ModelSetRpcs))
+		for _, ns := range r.ModelSetRpcs {
+			if v.rpcs[ns.Namespace], err = d.rpcs(ns.Refs); err != nil {
+				return err
+			}
+		}
+		v.notifications = make(map[string]map[string]Notification,
+			len(r.ModelSetNotifys))
+		for _, ns := range r.ModelSetNotifys {
+			v.notifications[ns.Namespace], err = d.notifications(ns.Refs)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *container:
+		v.presence = r.Presence
+
+	case *list:
+		v.orderedBy = r.OrderedBy
+		v.limit = r.Limit
+		v.keys = r.Keys
+		v.uniques = r.Uniques
+		v.entry = &listEntry{v.node, v}
+
+	case *leaf:
+		v.units = r.Units
+		v.mandatory = r.Mandatory
+		v.typ, err = d.typ(r.Type)
+
+	case *leafList:
+		v.defs = r.Defaults
+		v.units = r.Units
+		v.limit = r.Limit
+		v.orderedBy = r.OrderedBy
+		v.typ, err = d.typ(r.Type)
+
+	case *anydata:
+		v.mandatory = r.Mandatory
+
+	case *anyxml:
+		v.mandatory = r.Mandatory
+
+	case *choice:
+		v.mandatory = r.Mandatory
+		v.def = r.Default
+
+	case *ycase:
+		v.mandatory = r.Mandatory
+
+	case *opdCommand:
+		v.onEnter, v.priv, v.local, v.secret = opd.OnEnter, opd.Priv,
+			opd.Local, opd.Secret
+		v.repeatable, v.passOpcArgs = opd.Repeatable, opd.PassOpcArgs
+
+	case *opdArgument:
+		v.units = r.Units
+		v.mandatory = r.Mandatory
+		v.onEnter, v.priv, v.local, v.secret = opd.OnEnter, opd.Priv,
+			opd.Local, opd.Secret
+		v.repeatable, v.passOpcArgs = opd.Repeatable, opd.PassOpcArgs
+		v.opdHelp, v.opdAllowed = opd.OpdHelp, opd.OpdAllowed
+		v.typ, err = d.typ(r.Type)
+
+	case *opdOption:
+		v.units = r.Units
+		v.mandatory = r.Mandatory
+		v.onEnter, v.priv, v.local, v.secret = opd.OnEnter, opd.Priv,
+			opd.Local, opd.Secret
+		v.repeatable, v.passOpcArgs = opd.Repeatable, opd.PassOpcArgs
+		v.opdHelp, v.opdAllowed = opd.OpdHelp, opd.OpdAllowed
+		v.typ, err = d.typ(r.Type)
+
+	case *model:
+		v.schema = r.Schema
+		v.data = r.Text
+		v.features = r.Features
+		v.deviations = r.Deviations
+		if v.Tree, err = d.tree(r.Tree); err != nil {
+			return err
+		}
+		if v.rpcs, err = d.rpcs(r.Rpcs); err != nil {
+			return err
+		}
+		v.notifications, err = d.notifications(r.Notifications)
+
+	case *submodule:
+		v.schema = r.Schema
+		v.data = r.Text
+
+	case *rpc:
+		if v.input, err = d.tree(r.Tree); err != nil {
+			return err
+		}
+		v.output, err = d.tree(r.Output)
+
+	case *action:
+		if v.input, err = d.tree(r.Tree); err != nil {
+			return err
+		}
+		v.output, err = d.tree(r.Output)
+
+	case *notification:
+		v.notification, err = d.tree(r.Tree)
+
+	case *binary:
+		v.ytyp = decodeType(r.Typ)
+		v.len = r.Length
+
+	case *boolean:
+		v.ytyp = decodeType(r.Typ)
+
+	case *decimal64:
+		v.ytyp = decodeType(r.Typ)
+		v.fd = r.Fracdigit
+		v.rbs = r.Drbs
+		v.msg = r.Msg
+		v.appTag = r.AppTag
+
+	case *empty:
+		v.ytyp = decodeType(r.Typ)
+
+	case *enumeration:
+		v.ytyp = decodeType(r.Typ)
+		v.enums = make([]*Enum, 0, len(r.Enums))
+		for _, en := range r.Enums {
+			v.enums = append(v.enums,
+				NewEnum(en.Val, en.Desc, en.Ref, en.Status, en.Value))
+		}
+
+	case *integer:
+		v.ytyp = decodeType(r.Typ)
+		v.t = r.BitWidth
+		v.rbs = r.Rbs
+		v.msg = r.Msg
+		v.appTag = r.AppTag
+
+	case *uinteger:
+		v.ytyp = decodeType(r.Typ)
+		v.t = r.BitWidth
+		v.rbs = r.Urbs
+		v.msg = r.Msg
+		v.appTag = r.AppTag
+
+	case *ystring:
+		v.ytyp = decodeType(r.Typ)
+		v.len = r.Length
+		v.pathelps = r.PatternHelps
+		if v.pathelps == nil {
+			v.pathelps = make([][]string, 0)
+		}
+		v.pats = make([][]Pattern, 0, len(r.Patterns))
+		for _, recs := range r.Patterns {
+			pats := make([]Pattern, 0, len(recs))
+			for _, rec := range recs {
+				pat := Pattern{
+					Pattern:     rec.Pattern,
+					Msg:         rec.Msg,
+					AppTag:      rec.AppTag,
+					InvertMatch: rec.InvertMatch,
+				}
+				if rec.HasRegexp {
+					if pat.Regexp, err = regexp.Compile(rec.Regexp); err != nil {
+						return err
+					}
+				}
+				pats = append(pats, pat)
+			}
+			v.pats = append(v.pats, pats)
+		}
+
+	case *union:
+		v.ytyp = decodeType(r.Typ)
+		v.typs = make([]Type, 0, len(r.Types))
+		for _, id := range r.Types {
+			typ, err := d.typ(id)
+			if err != nil {
+				return err
+			}
+			v.typs = append(v.typs, typ)
+		}
+
+	case *identityref:
+		v.ytyp = decodeType(r.Typ)
+		v.identities = make([]*Identity, 0, len(r.Identities))
+		for _, id := range r.Identities {
+			v.identities = append(v.identities, NewIdentity(
+				id.Module, id.Namespace, id.Val, id.Desc, id.Ref,
+				id.Status, id.Value, id.Bases))
+		}
+
+	case *instanceId:
+		v.ytyp = decodeType(r.Typ)
+		v.require = r.Require
+
+	case *leafref:
+		v.ytyp = decodeType(r.Typ)
+		v.mach = r.Machine
+
+	case *bits:
+		v.ytyp = decodeType(r.Typ)
+		v.Bs = make([]*Bit, 0, len(r.Bits))
+		for _, b := range r.Bits {
+			v.Bs = append(v.Bs, NewBit(b.Name, b.Desc, b.Ref, b.Status, b.Pos))
+		}
+	}
+	return err
+}
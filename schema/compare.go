@@ -0,0 +1,261 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file walks two compiled schemas side by side, as YANG describes
+// them, for the schema differences here and the checks of compatibility
+// in the compile package.
+
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// SchemaChildren returns the children of n as they are in the YANG schema,
+// in order of name. Nodes within a choice are also children of the
+// choice's parent in the compiled schema, but are returned under their
+// case, and the choice is returned instead.
+func SchemaChildren(n Node) []Node {
+	var children []Node
+	switch n.(type) {
+	case Choice, Case:
+		children = append(children, n.Choices()...)
+	default:
+		inChoice := make(map[string]bool)
+		for _, ch := range n.Choices() {
+			for _, desc := range ch.Children() {
+				inChoice[desc.Name()] = true
+			}
+		}
+		children = append(children, n.Choices()...)
+		for _, ch := range n.Children() {
+			if !inChoice[ch.Name()] {
+				children = append(children, ch)
+			}
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name() < children[j].Name()
+	})
+	return children
+}
+
+// NodeKind returns the keyword of the statement defining n.
+func NodeKind(n Node) string {
+	switch n.(type) {
+	case Container:
+		return "container"
+	case List:
+		return "list"
+	case Leaf:
+		return "leaf"
+	case LeafList:
+		return "leaf-list"
+	case Choice:
+		return "choice"
+	case Case:
+		return "case"
+	case Anydata:
+		return "anydata"
+	case Anyxml:
+		return "anyxml"
+	}
+	return "node"
+}
+
+// SchemaPathElem is a node on a SchemaPath, and the module defining it.
+type SchemaPathElem struct {
+	Module, Name string
+}
+
+// SchemaPath is the path to a schema node, including choices and cases.
+type SchemaPath []SchemaPathElem
+
+// String gives the path with a module prefix on the first node, and on
+// each node defined by a different module from its parent.
+func (p SchemaPath) String() string {
+	var b strings.Builder
+	parent := ""
+	for _, e := range p {
+		b.WriteString("/")
+		if e.Module != parent {
+			b.WriteString(e.Module + ":")
+			parent = e.Module
+		}
+		b.WriteString(e.Name)
+	}
+	return b.String()
+}
+
+func (p SchemaPath) Child(module, name string) SchemaPath {
+	return append(append(SchemaPath{}, p...), SchemaPathElem{module, name})
+}
+
+// ComparePath locates a node found by CompareNodes, giving its parents in
+// the old and new schemas, from which config and status are inherited. The
+// parents are nil for an rpc, action or notification, and for the input
+// and output of an operation. Operation is set for nodes within one.
+type ComparePath struct {
+	Path                 SchemaPath
+	OldParent, NewParent Node
+	Operation            bool
+}
+
+func (p ComparePath) child(module, name string, old, new Node) ComparePath {
+	return ComparePath{
+		Path:      p.Path.Child(module, name),
+		OldParent: old,
+		NewParent: new,
+		Operation: p.Operation,
+	}
+}
+
+// SchemaComparison is told of the nodes found by CompareNodes, in order of
+// schema path.
+type SchemaComparison interface {
+	// Added is called for a node, rpc, action or notification found only
+	// in the new schema, and Removed for one found only in the old. The
+	// node is nil for an rpc, action or notification.
+	Added(p ComparePath, kind string, n Node)
+	Removed(p ComparePath, kind string, o Node)
+
+	// Compare is called for a node found in both schemas, though it may be
+	// of a different kind in each, and returns whether to compare their
+	// children.
+	Compare(p ComparePath, o, n Node) bool
+}
+
+// CompareNodes compares the children, actions and notifications of old
+// and new, the nodes at p. A nil node has none.
+func CompareNodes(c SchemaComparison, p ComparePath, old, new Node) {
+	oldChildren, newChildren := childrenByKey(old), childrenByKey(new)
+	for _, key := range unionKeys(oldChildren, newChildren) {
+		o, n := oldChildren[key], newChildren[key]
+		switch {
+		case n == nil:
+			c.Removed(p.child(o.Module(), o.Name(), old, new), NodeKind(o), o)
+		case o == nil:
+			c.Added(p.child(n.Module(), n.Name(), old, new), NodeKind(n), n)
+		default:
+			cp := p.child(n.Module(), n.Name(), old, new)
+			if c.Compare(cp, o, n) {
+				CompareNodes(c, cp, o, n)
+			}
+		}
+	}
+	if old == nil || new == nil {
+		return
+	}
+	compareOperations(c, p, new.Module(), "action", old.Actions(), new.Actions())
+	compareNotifications(c, p, new.Module(), old.NestedNotifications(),
+		new.NestedNotifications())
+}
+
+// CompareRpcs compares the rpcs of a module in the old and new schemas.
+func CompareRpcs(c SchemaComparison, module string, old, new map[string]Rpc) {
+	compareOperations(c, ComparePath{}, module, "rpc", old, new)
+}
+
+// CompareNotifications compares the notifications of a module in the old
+// and new schemas.
+func CompareNotifications(
+	c SchemaComparison,
+	module string,
+	old, new map[string]Notification,
+) {
+	compareNotifications(c, ComparePath{}, module, old, new)
+}
+
+func childrenByKey(n Node) map[string]Node {
+	children := make(map[string]Node)
+	if n == nil {
+		return children
+	}
+	for _, ch := range SchemaChildren(n) {
+		children[ch.Module()+":"+ch.Name()] = ch
+	}
+	return children
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make(map[string]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// Rpcs and actions have the same methods
+type operation interface {
+	Input() Tree
+	Output() Tree
+}
+
+// Operations are looked up by whether they are in the maps, as a missing
+// one would otherwise be a typed nil.
+func compareOperations[O operation](
+	c SchemaComparison,
+	p ComparePath,
+	module, kind string,
+	old, new map[string]O,
+) {
+	for _, name := range unionKeys(old, new) {
+		o, inOld := old[name]
+		n, inNew := new[name]
+		op := p.child(module, name, nil, nil)
+		op.Operation = true
+		switch {
+		case !inNew:
+			c.Removed(op, kind, nil)
+		case !inOld:
+			c.Added(op, kind, nil)
+		default:
+			CompareNodes(c, op.child(module, "input", nil, nil),
+				o.Input(), n.Input())
+			CompareNodes(c, op.child(module, "output", nil, nil),
+				o.Output(), n.Output())
+		}
+	}
+}
+
+func compareNotifications(
+	c SchemaComparison,
+	p ComparePath,
+	module string,
+	old, new map[string]Notification,
+) {
+	for _, name := range unionKeys(old, new) {
+		o, inOld := old[name]
+		n, inNew := new[name]
+		np := p.child(module, name, nil, nil)
+		np.Operation = true
+		switch {
+		case !inNew:
+			c.Removed(np, "notification", nil)
+		case !inOld:
+			c.Added(np, "notification", nil)
+		default:
+			CompareNodes(c, np, o.Schema(), n.Schema())
+		}
+	}
+}
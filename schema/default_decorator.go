@@ -189,10 +189,11 @@ func createDefault(sch Node) datanode.DataNode {
 
 	switch v := sch.(type) {
 
-	// Note that LeafLists do not currently support defaults
 	case Leaf:
 		val, _ := v.Default()
 		return datanode.CreateDataNode(v.Name(), nil, []string{val})
+	case LeafList:
+		return datanode.CreateDataNode(v.Name(), nil, v.Defaults())
 	}
 
 	var children []datanode.DataNode
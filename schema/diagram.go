@@ -0,0 +1,440 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file renders the tree diagrams of RFC 8340, as 'pyang -f tree' does,
+// from a compiled model set. So the diagram shows the schema after
+// features, deviations and augments have been applied.
+
+package schema
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TreeDiagramOptions selects the part of a model set to draw.
+type TreeDiagramOptions struct {
+	// Only draw these modules. All modules are drawn if empty.
+	Modules []string
+
+	// Number of levels of nodes to draw, or zero for all. Nodes left out
+	// are shown as '...'.
+	Depth int
+
+	// Only draw the subtree at this schema path, such as /top/list, and
+	// the nodes leading to it. Prefixes on the names are ignored. The
+	// path may also name an rpc or notification.
+	Path string
+}
+
+// Modes of the nodes being drawn, giving their flags
+type diagramMode int
+
+const (
+	diagramData diagramMode = iota
+	diagramInput
+	diagramOutput
+	diagramNotification
+)
+
+type diagramKind int
+
+const (
+	diagramNode diagramKind = iota
+	diagramChoice
+	diagramCase
+)
+
+// A diagramEntry is a line of the diagram and the entries drawn under it.
+// Rpcs, actions, notifications and their input and output have no schema
+// node of their own.
+type diagramEntry struct {
+	node     Node
+	kind     diagramKind
+	name     string
+	module   string
+	flags    string
+	status   Status
+	features []string
+	key      bool
+	children func() []*diagramEntry
+}
+
+type treeDiagram struct {
+	w      *bufio.Writer
+	opts   TreeDiagramOptions
+	module string
+}
+
+// WriteTreeDiagram writes the tree diagram for each module in ms, in order
+// of module name. Children are drawn in order of name, as the compiled
+// schema does not keep the order of the YANG statements. Nodes from other
+// modules, added by augment, are prefixed with the name of their module.
+func WriteTreeDiagram(w io.Writer, ms ModelSet, opts TreeDiagramOptions) error {
+	d := &treeDiagram{w: bufio.NewWriter(w), opts: opts}
+
+	names := opts.Modules
+	if len(names) == 0 {
+		names = sortedKeys(ms.Modules())
+	}
+
+	first := true
+	for _, name := range names {
+		m, ok := ms.Modules()[name]
+		if !ok {
+			return fmt.Errorf("module %s not found", name)
+		}
+		if d.writeModule(name, m, first) {
+			first = false
+		}
+	}
+	return d.w.Flush()
+}
+
+func diagramPath(path string) []string {
+	var elems []string
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "" {
+			continue
+		}
+		if idx := strings.Index(elem, ":"); idx != -1 {
+			elem = elem[idx+1:]
+		}
+		elems = append(elems, elem)
+	}
+	return elems
+}
+
+// writeModule writes the diagram of a module, returning false if there
+// was nothing to write for the path selected.
+func (d *treeDiagram) writeModule(name string, m Model, first bool) bool {
+	d.module = name
+	path := diagramPath(d.opts.Path)
+
+	data := nodeEntries(m, diagramData)
+	rpcs := rpcEntries(m.Rpcs())
+	notifs := notificationEntries(m.Notifications())
+	if len(path) > 0 {
+		data = selectEntries(data, path[0])
+		rpcs = selectEntries(rpcs, path[0])
+		notifs = selectEntries(notifs, path[0])
+		if len(data) == 0 && len(rpcs) == 0 && len(notifs) == 0 {
+			return false
+		}
+	}
+
+	if !first {
+		d.w.WriteString("\n")
+	}
+	fmt.Fprintf(d.w, "module: %s\n", name)
+	d.writeEntries(data, "", path, d.opts.Depth, 0)
+	if len(rpcs) > 0 {
+		d.w.WriteString("\n  rpcs:\n")
+		d.writeEntries(rpcs, "  ", path, d.opts.Depth, 0)
+	}
+	if len(notifs) > 0 {
+		d.w.WriteString("\n  notifications:\n")
+		d.writeEntries(notifs, "  ", path, d.opts.Depth, 0)
+	}
+	return true
+}
+
+func nodeEntries(n Node, mode diagramMode) []*diagramEntry {
+	var keys map[string]bool
+	if l, ok := n.(List); ok {
+		keys = make(map[string]bool)
+		for _, k := range l.Keys() {
+			keys[k] = true
+		}
+	}
+	_, inChoice := n.(Choice)
+
+	var entries []*diagramEntry
+	for _, ch := range SchemaChildren(n) {
+		e := nodeEntry(ch, mode)
+		e.key = keys[ch.Name()]
+		if inChoice && e.kind != diagramCase {
+			e = shorthandCaseEntry(e)
+		}
+		entries = append(entries, e)
+	}
+	entries = append(entries, actionEntries(n.Actions())...)
+	entries = append(entries,
+		notificationEntries(n.NestedNotifications())...)
+	return entries
+}
+
+func nodeEntry(n Node, mode diagramMode) *diagramEntry {
+	e := &diagramEntry{
+		node:     n,
+		name:     n.Name(),
+		module:   n.Module(),
+		status:   n.Status(),
+		features: n.IfFeatures(),
+		children: func() []*diagramEntry {
+			return nodeEntries(n, mode)
+		},
+	}
+	switch n.(type) {
+	case Choice:
+		e.kind = diagramChoice
+	case Case:
+		e.kind = diagramCase
+	}
+	switch {
+	case mode == diagramInput:
+		e.flags = "-w"
+	case mode != diagramData || !n.Config():
+		e.flags = "ro"
+	default:
+		e.flags = "rw"
+	}
+	return e
+}
+
+// A node directly within a choice is drawn within a case of the same name
+func shorthandCaseEntry(e *diagramEntry) *diagramEntry {
+	return &diagramEntry{
+		node:   e.node,
+		kind:   diagramCase,
+		name:   e.name,
+		module: e.module,
+		status: e.status,
+		children: func() []*diagramEntry {
+			return []*diagramEntry{e}
+		},
+	}
+}
+
+func operationEntry(
+	name, flags string,
+	input, output Tree,
+) *diagramEntry {
+	return &diagramEntry{
+		name:  name,
+		flags: flags,
+		children: func() []*diagramEntry {
+			var entries []*diagramEntry
+			if input != nil && len(input.Children()) > 0 {
+				entries = append(entries,
+					treeEntry("input", "-w", input, diagramInput))
+			}
+			if output != nil && len(output.Children()) > 0 {
+				entries = append(entries,
+					treeEntry("output", "ro", output, diagramOutput))
+			}
+			return entries
+		},
+	}
+}
+
+func treeEntry(name, flags string, t Tree, mode diagramMode) *diagramEntry {
+	return &diagramEntry{
+		name:  name,
+		flags: flags,
+		children: func() []*diagramEntry {
+			return nodeEntries(t, mode)
+		},
+	}
+}
+
+func rpcEntries(rpcs map[string]Rpc) []*diagramEntry {
+	var entries []*diagramEntry
+	for _, name := range sortedKeys(rpcs) {
+		r := rpcs[name]
+		entries = append(entries,
+			operationEntry(name, "-x", r.Input(), r.Output()))
+	}
+	return entries
+}
+
+func actionEntries(actions map[string]Action) []*diagramEntry {
+	var entries []*diagramEntry
+	for _, name := range sortedKeys(actions) {
+		a := actions[name]
+		entries = append(entries,
+			operationEntry(name, "-x", a.Input(), a.Output()))
+	}
+	return entries
+}
+
+func notificationEntries(
+	notifs map[string]Notification,
+) []*diagramEntry {
+	var entries []*diagramEntry
+	for _, name := range sortedKeys(notifs) {
+		entries = append(entries,
+			treeEntry(name, "-n", notifs[name].Schema(), diagramNotification))
+	}
+	return entries
+}
+
+// selectEntries returns the entries leading to the named node
+func selectEntries(entries []*diagramEntry, name string) []*diagramEntry {
+	var selected []*diagramEntry
+	for _, e := range entries {
+		switch {
+		case e.name == name && e.kind != diagramChoice:
+			selected = append(selected, e)
+		case e.kind != diagramNode && e.node.Child(name) != nil:
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+func (d *treeDiagram) displayName(e *diagramEntry) string {
+	if e.module != "" && e.module != d.module {
+		return e.module + ":" + e.name
+	}
+	return e.name
+}
+
+// The width of the names of the entries, as for 'pyang -f tree'. Choices
+// and cases are drawn with their children, indented by three.
+func (d *treeDiagram) nameWidth(entries []*diagramEntry) int {
+	width := 0
+	for _, e := range entries {
+		w := len(d.displayName(e))
+		if e.kind != diagramNode {
+			w = 3 + d.nameWidth(e.children())
+		}
+		if w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+func statusMarker(s Status) string {
+	switch s {
+	case Deprecated:
+		return "x"
+	case Obsolete:
+		return "o"
+	}
+	return "+"
+}
+
+func (d *treeDiagram) writeEntries(
+	entries []*diagramEntry,
+	prefix string,
+	path []string,
+	depth, width int,
+) {
+	if width == 0 {
+		width = d.nameWidth(entries)
+	}
+	for i, e := range entries {
+		childPrefix := prefix + "  |"
+		if i == len(entries)-1 {
+			childPrefix = prefix + "   "
+		}
+		d.writeEntry(e, childPrefix, path, depth, width)
+	}
+}
+
+func (d *treeDiagram) writeEntry(
+	e *diagramEntry,
+	prefix string,
+	path []string,
+	depth, width int,
+) {
+	line := prefix[:len(prefix)-1] + statusMarker(e.status) + "--"
+	switch e.kind {
+	case diagramChoice:
+		line += e.flags + " (" + d.displayName(e) + ")"
+		if !e.node.Mandatory() {
+			line += "?"
+		}
+	case diagramCase:
+		line += ":(" + d.displayName(e) + ")"
+	default:
+		line += e.flags + " " + d.nodeText(e, width)
+	}
+	if len(e.features) > 0 {
+		line += " {" + strings.Join(e.features, ",") + "}?"
+	}
+	d.w.WriteString(line + "\n")
+
+	// Choices and cases are not part of the path
+	transparent := e.kind != diagramNode
+	if len(path) > 0 && !transparent {
+		path = path[1:]
+	}
+
+	children := e.children()
+	if len(path) > 0 {
+		children = selectEntries(children, path[0])
+	}
+	if len(children) == 0 {
+		return
+	}
+	if depth > 0 && len(path) == 0 {
+		if depth == 1 {
+			d.w.WriteString(prefix + "     ...\n")
+			return
+		}
+		depth--
+	}
+
+	childWidth := 0
+	if transparent {
+		childWidth = width - 3
+	}
+	d.writeEntries(children, prefix, path, depth, childWidth)
+}
+
+// The name of a node, with its options and type
+func (d *treeDiagram) nodeText(e *diagramEntry, width int) string {
+	name := d.displayName(e)
+	switch n := e.node.(type) {
+	case Container:
+		if n.Presence() {
+			return name + "!"
+		}
+	case List:
+		if len(n.Keys()) > 0 {
+			return name + "* [" + strings.Join(n.Keys(), " ") + "]"
+		}
+		return name + "*"
+	case LeafList:
+		return fmt.Sprintf("%-*s   %s", width+1, name+"*", diagramType(n))
+	case Leaf, Anydata, Anyxml:
+		if !n.Mandatory() && !e.key {
+			name += "?"
+		}
+		return fmt.Sprintf("%-*s   %s", width+1, name, diagramType(n))
+	}
+	return name
+}
+
+func diagramType(n Node) string {
+	switch n.(type) {
+	case Anydata:
+		return "<anydata>"
+	case Anyxml:
+		return "<anyxml>"
+	}
+	t := n.Type()
+	if t == nil {
+		return ""
+	}
+	if lref, ok := t.(Leafref); ok && lref.Mach() != nil {
+		return "-> " + lref.Mach().GetExpr()
+	}
+	return t.Name().Local
+}
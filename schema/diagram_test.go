@@ -0,0 +1,262 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+const diagramModule = `
+module diagram {
+	namespace "urn:diagram";
+	prefix d;
+
+	feature fancy;
+
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf-list tags {
+			type string;
+		}
+		list entry {
+			key "id";
+			leaf id {
+				type uint32;
+			}
+			leaf value {
+				type string;
+				mandatory true;
+			}
+			leaf ref {
+				type leafref {
+					path "../id";
+				}
+			}
+		}
+		choice pick {
+			case one {
+				leaf first {
+					type int8;
+				}
+				leaf also {
+					type int8;
+				}
+			}
+			leaf second {
+				type boolean;
+			}
+		}
+		container state {
+			config false;
+			leaf counter {
+				type uint64;
+				status deprecated;
+			}
+		}
+		container fancy {
+			if-feature fancy;
+			presence "fancy";
+			anydata blob;
+		}
+	}
+	rpc reset {
+		input {
+			leaf force {
+				type boolean;
+			}
+		}
+		output {
+			leaf done {
+				type string;
+			}
+		}
+	}
+	notification changed {
+		leaf what {
+			type string;
+		}
+	}
+}`
+
+const diagramAugment = `
+module diagram-aug {
+	namespace "urn:diagram-aug";
+	prefix da;
+	import diagram {
+		prefix d;
+	}
+	augment /d:top {
+		leaf extra {
+			type string;
+		}
+	}
+}`
+
+func diagramSchema(t *testing.T) schema.ModelSet {
+	ms, err := compile.CompileDir(nil, &compile.Config{
+		Repository: compile.MapRepository(map[string][]byte{
+			"diagram.yang":     []byte(diagramModule),
+			"diagram-aug.yang": []byte(diagramAugment),
+		}),
+		Features: compile.FeaturesFromNames(true, "diagram:fancy"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected compilation error: %s", err)
+	}
+	return ms
+}
+
+const diagramTop = `module: diagram
+  +--rw top
+     +--rw entry* [id]
+     |  +--rw id       uint32
+     |  +--rw ref?     -> ../id
+     |  +--rw value    string
+     +--rw diagram-aug:extra?   string
+     +--rw fancy! {fancy}?
+     |  +--rw blob?   <anydata>
+     +--rw name?                string
+     +--rw (pick)?
+     |  +--:(one)
+     |  |  +--rw also?          int8
+     |  |  +--rw first?         int8
+     |  +--:(second)
+     |     +--rw second?        boolean
+     +--ro state
+     |  x--ro counter?   uint64
+     +--rw tags*                string
+`
+
+const diagramOperations = `
+  rpcs:
+    +---x reset
+       +---w input
+       |  +---w force?   boolean
+       +--ro output
+          +--ro done?   string
+
+  notifications:
+    +---n changed
+       +--ro what?   string
+`
+
+func TestWriteTreeDiagram(t *testing.T) {
+	ms := diagramSchema(t)
+	for _, test := range []struct {
+		name     string
+		opts     schema.TreeDiagramOptions
+		expected string
+	}{
+		{
+			name:     "all",
+			expected: diagramTop + diagramOperations + "\nmodule: diagram-aug\n",
+		},
+		{
+			name:     "module",
+			opts:     schema.TreeDiagramOptions{Modules: []string{"diagram-aug"}},
+			expected: "module: diagram-aug\n",
+		},
+		{
+			name: "depth",
+			opts: schema.TreeDiagramOptions{
+				Modules: []string{"diagram"},
+				Depth:   2,
+			},
+			expected: `module: diagram
+  +--rw top
+     +--rw entry* [id]
+     |     ...
+     +--rw diagram-aug:extra?   string
+     +--rw fancy! {fancy}?
+     |     ...
+     +--rw name?                string
+     +--rw (pick)?
+     |     ...
+     +--ro state
+     |     ...
+     +--rw tags*                string
+
+  rpcs:
+    +---x reset
+       +---w input
+       |     ...
+       +--ro output
+             ...
+
+  notifications:
+    +---n changed
+       +--ro what?   string
+`,
+		},
+		{
+			name: "subtree",
+			opts: schema.TreeDiagramOptions{Path: "/d:top/entry"},
+			expected: `module: diagram
+  +--rw top
+     +--rw entry* [id]
+        +--rw id       uint32
+        +--rw ref?     -> ../id
+        +--rw value    string
+`,
+		},
+		{
+			name: "subtree in choice",
+			opts: schema.TreeDiagramOptions{Path: "/top/second"},
+			expected: `module: diagram
+  +--rw top
+     +--rw (pick)?
+        +--:(second)
+           +--rw second?   boolean
+`,
+		},
+		{
+			name: "rpc",
+			opts: schema.TreeDiagramOptions{Path: "/reset/output"},
+			expected: `module: diagram
+
+  rpcs:
+    +---x reset
+       +--ro output
+          +--ro done?   string
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := schema.WriteTreeDiagram(&buf, ms, test.opts); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if buf.String() != test.expected {
+				t.Errorf("Unexpected diagram\nExpected:\n%s\nActual:\n%s",
+					test.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteTreeDiagramUnknownModule(t *testing.T) {
+	var buf bytes.Buffer
+	err := schema.WriteTreeDiagram(&buf, diagramSchema(t),
+		schema.TreeDiagramOptions{Modules: []string{"missing"}})
+	if err == nil || err.Error() != "module missing not found" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
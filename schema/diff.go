@@ -0,0 +1,374 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file finds the structural differences between two compiled
+// schemas, such as two releases of a set of models. Unlike the checks of
+// compatibility in the compile package, it makes no judgement on the
+// changes, so can be used to describe them in release notes.
+
+package schema
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A DiffKind says whether a node or property was added, removed or
+// modified.
+type DiffKind int
+
+const (
+	DiffAdded DiffKind = iota
+	DiffRemoved
+	DiffModified
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffModified:
+		return "modified"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+func (k DiffKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// A PropertyChange is a change to one property of a node, such as its
+// type or default. Properties which may be given more than once, such as
+// must, have a change for each value added or removed.
+type PropertyChange struct {
+	Property string   `json:"property"`
+	Kind     DiffKind `json:"kind"`
+	Old      string   `json:"old,omitempty"`
+	New      string   `json:"new,omitempty"`
+}
+
+// A NodeDiff is a node added, removed or modified. Only the top of a
+// subtree added or removed is given. Nodes whose kind has changed, such
+// as from leaf to leaf-list, are given as removed and then added.
+type NodeDiff struct {
+	Kind DiffKind `json:"kind"`
+
+	// Schema path of the node. As for JSON (RFC 7951), names are prefixed
+	// by their module where it differs from their parent's, and choices
+	// and cases are included.
+	Path string `json:"path"`
+
+	// Keyword of the statement defining the node, such as 'container'
+	Node string `json:"node"`
+
+	// Changes to the node's properties, if modified
+	Changes []PropertyChange `json:"changes,omitempty"`
+}
+
+// A SchemaDiff is the differences between two schemas, in order of path.
+type SchemaDiff []NodeDiff
+
+// DiffSchemas returns the differences between the nodes under old and new.
+// The paths are relative to them.
+func DiffSchemas(old, new Node) SchemaDiff {
+	d := &schemaDiff{}
+	CompareNodes(d, ComparePath{}, old, new)
+	return d.diffs
+}
+
+// DiffModelSets returns the differences between two model sets, including
+// their rpcs and notifications.
+func DiffModelSets(old, new ModelSet) SchemaDiff {
+	d := &schemaDiff{}
+	CompareNodes(d, ComparePath{}, old, new)
+
+	oldModules, newModules := old.Modules(), new.Modules()
+	for _, module := range unionKeys(oldModules, newModules) {
+		var oldRpcs, newRpcs map[string]Rpc
+		var oldNotifs, newNotifs map[string]Notification
+		if m, ok := oldModules[module]; ok {
+			oldRpcs, oldNotifs = m.Rpcs(), m.Notifications()
+		}
+		if m, ok := newModules[module]; ok {
+			newRpcs, newNotifs = m.Rpcs(), m.Notifications()
+		}
+		CompareRpcs(d, module, oldRpcs, newRpcs)
+		CompareNotifications(d, module, oldNotifs, newNotifs)
+	}
+	return d.diffs
+}
+
+// WriteText writes the differences for people to read, with each node on
+// a line, followed by the changes to its properties.
+func (d SchemaDiff) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, n := range d {
+		fmt.Fprintf(bw, "%s %s %s\n", n.Kind, n.Node, n.Path)
+		for _, c := range n.Changes {
+			switch c.Kind {
+			case DiffAdded:
+				fmt.Fprintf(bw, "  %s added: %q\n", c.Property, c.New)
+			case DiffRemoved:
+				fmt.Fprintf(bw, "  %s removed: %q\n", c.Property, c.Old)
+			default:
+				fmt.Fprintf(bw, "  %s: %q -> %q\n", c.Property, c.Old, c.New)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteJSON writes the differences as a JSON array of NodeDiffs
+func (d SchemaDiff) WriteJSON(w io.Writer) error {
+	if d == nil {
+		d = SchemaDiff{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+type schemaDiff struct {
+	diffs SchemaDiff
+}
+
+func (d *schemaDiff) add(kind DiffKind, p SchemaPath, node string, changes []PropertyChange) {
+	d.diffs = append(d.diffs, NodeDiff{
+		Kind: kind, Path: p.String(), Node: node, Changes: changes})
+}
+
+func (d *schemaDiff) Added(p ComparePath, kind string, _ Node) {
+	d.add(DiffAdded, p.Path, kind, nil)
+}
+
+func (d *schemaDiff) Removed(p ComparePath, kind string, _ Node) {
+	d.add(DiffRemoved, p.Path, kind, nil)
+}
+
+type propertyDiff struct {
+	changes []PropertyChange
+}
+
+func (pd *propertyDiff) value(property, old, new string) {
+	pd.optional(property, old, true, new, true)
+}
+
+func (pd *propertyDiff) optional(property, old string, oldHas bool, new string, newHas bool) {
+	switch {
+	case oldHas == newHas && old == new:
+	case !oldHas:
+		pd.changes = append(pd.changes,
+			PropertyChange{Property: property, Kind: DiffAdded, New: new})
+	case !newHas:
+		pd.changes = append(pd.changes,
+			PropertyChange{Property: property, Kind: DiffRemoved, Old: old})
+	default:
+		pd.changes = append(pd.changes,
+			PropertyChange{Property: property, Kind: DiffModified, Old: old, New: new})
+	}
+}
+
+func (pd *propertyDiff) set(property string, old, new []string) {
+	oldSet, newSet := make(map[string]bool), make(map[string]bool)
+	for _, v := range old {
+		oldSet[v] = true
+	}
+	for _, v := range new {
+		newSet[v] = true
+	}
+	for _, v := range unionKeys(oldSet, newSet) {
+		pd.optional(property, v, oldSet[v], v, newSet[v])
+	}
+}
+
+func limitString(max uint) string {
+	if max == ^uint(0) {
+		return "unbounded"
+	}
+	return fmt.Sprint(max)
+}
+
+func (pd *propertyDiff) limit(old, new Limit) {
+	pd.value("min-elements", fmt.Sprint(old.Min), fmt.Sprint(new.Min))
+	pd.value("max-elements", limitString(old.Max), limitString(new.Max))
+}
+
+// Compare compares the properties of o and n. A node that changes kind is
+// given as removed and added. Config is inherited, so is only given where
+// it changes differently from the parent.
+func (d *schemaDiff) Compare(p ComparePath, o, n Node) bool {
+	if NodeKind(o) != NodeKind(n) {
+		d.add(DiffRemoved, p.Path, NodeKind(o), nil)
+		d.add(DiffAdded, p.Path, NodeKind(n), nil)
+		return false
+	}
+
+	var pd propertyDiff
+	if o.Config() != n.Config() && (p.OldParent == nil ||
+		p.OldParent.Config() != o.Config() || p.NewParent.Config() != n.Config()) {
+		pd.value("config", fmt.Sprint(o.Config()), fmt.Sprint(n.Config()))
+	}
+	pd.value("description", o.Description(), n.Description())
+
+	var oldMusts, newMusts, oldWhens, newWhens []string
+	for _, m := range o.Musts() {
+		oldMusts = append(oldMusts, m.Mach.GetExpr())
+	}
+	for _, m := range n.Musts() {
+		newMusts = append(newMusts, m.Mach.GetExpr())
+	}
+	for _, w := range o.Whens() {
+		oldWhens = append(oldWhens, w.Mach.GetExpr())
+	}
+	for _, w := range n.Whens() {
+		newWhens = append(newWhens, w.Mach.GetExpr())
+	}
+	pd.set("must", oldMusts, newMusts)
+	pd.set("when", oldWhens, newWhens)
+
+	switch nv := n.(type) {
+	case Leaf:
+		ov := o.(Leaf)
+		pd.value("type", typeString(ov.Type()), typeString(nv.Type()))
+		od, oHas := ov.Default()
+		nd, nHas := nv.Default()
+		pd.optional("default", od, oHas, nd, nHas)
+		pd.optional("units", ov.Units(), ov.Units() != "", nv.Units(), nv.Units() != "")
+		pd.value("mandatory", fmt.Sprint(ov.Mandatory()), fmt.Sprint(nv.Mandatory()))
+	case LeafList:
+		ov := o.(LeafList)
+		pd.value("type", typeString(ov.Type()), typeString(nv.Type()))
+		pd.set("default", ov.Defaults(), nv.Defaults())
+		pd.optional("units", ov.Units(), ov.Units() != "", nv.Units(), nv.Units() != "")
+		pd.value("ordered-by", ov.OrdBy(), nv.OrdBy())
+		pd.limit(ov.Limit(), nv.Limit())
+	case List:
+		ov := o.(List)
+		pd.value("ordered-by", ov.OrdBy(), nv.OrdBy())
+		pd.limit(ov.Limit(), nv.Limit())
+	case Choice:
+		ov := o.(Choice)
+		od, nd := ov.DefaultCase(), nv.DefaultCase()
+		pd.optional("default", od, od != "", nd, nd != "")
+		pd.value("mandatory", fmt.Sprint(ov.Mandatory()), fmt.Sprint(nv.Mandatory()))
+	case Anydata, Anyxml:
+		pd.value("mandatory", fmt.Sprint(o.Mandatory()), fmt.Sprint(n.Mandatory()))
+	}
+
+	if len(pd.changes) > 0 {
+		d.add(DiffModified, p.Path, NodeKind(n), pd.changes)
+	}
+	return true
+}
+
+// typeString describes a type and its restrictions, much as they would be
+// written in YANG, so that any change to them changes the description.
+func typeString(t Type) string {
+	var restrictions []string
+	switch v := t.(type) {
+	case Integer:
+		restrictions = appendRanges(restrictions, "range", v.Rbs())
+	case Uinteger:
+		restrictions = appendRanges(restrictions, "range", v.Rbs())
+	case Decimal64:
+		restrictions = append(restrictions,
+			fmt.Sprintf("fraction-digits %d", v.Fd()))
+		var parts []string
+		for _, rb := range v.Rbs() {
+			start := fmt.Sprintf("%.*f", int(v.Fd()), rb.Start)
+			if rb.Start == rb.End {
+				parts = append(parts, start)
+				continue
+			}
+			parts = append(parts,
+				fmt.Sprintf("%s..%.*f", start, int(v.Fd()), rb.End))
+		}
+		restrictions = append(restrictions, "range "+strings.Join(parts, " | "))
+	case String:
+		if v.Len() != nil {
+			restrictions = appendRanges(restrictions, "length", lengthBoundaries(v.Len()))
+		}
+		for _, pats := range v.Pats() {
+			for _, pat := range pats {
+				if pat.InvertMatch {
+					restrictions = append(restrictions,
+						fmt.Sprintf("pattern %q invert-match", pat.Pattern))
+				} else {
+					restrictions = append(restrictions,
+						fmt.Sprintf("pattern %q", pat.Pattern))
+				}
+			}
+		}
+	case Binary:
+		if v.Length() != nil {
+			restrictions = appendRanges(restrictions, "length", lengthBoundaries(v.Length()))
+		}
+	case Enumeration:
+		for _, e := range v.Enums() {
+			restrictions = append(restrictions,
+				fmt.Sprintf("enum %s %d", e.Val, e.Value))
+		}
+	case Bits:
+		for _, b := range v.Bits() {
+			restrictions = append(restrictions,
+				fmt.Sprintf("bit %s %d", b.Name, b.Pos))
+		}
+	case Identityref:
+		for _, id := range v.Identities() {
+			restrictions = append(restrictions,
+				"identity "+id.Module+":"+id.Value)
+		}
+	case Union:
+		for _, member := range v.Typs() {
+			restrictions = append(restrictions, "type "+typeString(member))
+		}
+	case Leafref:
+		if v.Mach() != nil {
+			restrictions = append(restrictions, "path "+v.Mach().GetExpr())
+		}
+	case InstanceId:
+		restrictions = append(restrictions,
+			fmt.Sprintf("require-instance %t", v.Require()))
+	}
+
+	if len(restrictions) == 0 {
+		return t.Name().Local
+	}
+	return t.Name().Local + " { " + strings.Join(restrictions, "; ") + "; }"
+}
+
+func appendRanges[T fmt.Stringer](restrictions []string, keyword string, rbs []T) []string {
+	if len(rbs) == 0 {
+		return restrictions
+	}
+	var parts []string
+	for _, rb := range rbs {
+		parts = append(parts, rb.String())
+	}
+	return append(restrictions, keyword+" "+strings.Join(parts, " | "))
+}
+
+// Lbs are Stringers by pointer
+func lengthBoundaries(l *Length) []*Lb {
+	var lbs []*Lb
+	for i := range l.Lbs {
+		lbs = append(lbs, &l.Lbs[i])
+	}
+	return lbs
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/sdcio/yang-parser/compile"
+	"github.com/sdcio/yang-parser/schema"
+)
+
+const diffOldModule = `
+module diff {
+	namespace "urn:diff";
+	prefix d;
+
+	container top {
+		leaf name {
+			type string {
+				length "1..32";
+			}
+			description "The name";
+		}
+		leaf speed {
+			type uint32;
+			default 10;
+		}
+		leaf gone {
+			type string;
+		}
+		list entry {
+			key "id";
+			max-elements 10;
+			leaf id {
+				type uint32;
+			}
+		}
+		container state {
+			leaf counter {
+				type uint64;
+			}
+		}
+	}
+
+	rpc reset;
+}`
+
+const diffNewModule = `
+module diff {
+	namespace "urn:diff";
+	prefix d;
+
+	container top {
+		leaf name {
+			type string {
+				length "1..64";
+			}
+			description "The name of the top";
+			mandatory true;
+		}
+		leaf speed {
+			type uint32;
+			units "Mbps";
+			must ". > 0";
+		}
+		leaf extra {
+			type string;
+		}
+		list entry {
+			key "id";
+			ordered-by user;
+			leaf id {
+				type uint32;
+			}
+		}
+		container state {
+			config false;
+			leaf counter {
+				type uint64;
+			}
+		}
+	}
+
+	notification changed;
+}`
+
+func diffSchemas(t *testing.T) schema.SchemaDiff {
+	compileDiffModule := func(module string) schema.ModelSet {
+		ms, err := compile.CompileDir(nil, &compile.Config{
+			Repository: compile.MapRepository(map[string][]byte{
+				"diff.yang": []byte(module),
+			}),
+			Features: compile.FeaturesFromNames(true),
+		})
+		if err != nil {
+			t.Fatalf("Unexpected compilation error: %s", err)
+		}
+		return ms
+	}
+	return schema.DiffModelSets(
+		compileDiffModule(diffOldModule), compileDiffModule(diffNewModule))
+}
+
+const diffText = `modified list /diff:top/entry
+  ordered-by: "system" -> "user"
+  max-elements: "10" -> "unbounded"
+added leaf /diff:top/extra
+removed leaf /diff:top/gone
+modified leaf /diff:top/name
+  description: "The name" -> "The name of the top"
+  type: "string { length 1..32; }" -> "string { length 1..64; }"
+  mandatory: "false" -> "true"
+modified leaf /diff:top/speed
+  must added: ". > 0"
+  default removed: "10"
+  units added: "Mbps"
+modified container /diff:top/state
+  config: "true" -> "false"
+removed rpc /diff:reset
+added notification /diff:changed
+`
+
+// The state container's config change is inherited by its leaf, so is
+// only given for the container.
+func TestDiffModelSetsText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := diffSchemas(t).WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected WriteText error: %s", err)
+	}
+	if act := buf.String(); act != diffText {
+		t.Errorf("Unexpected diff\nexpected:\n%s\nactual:\n%s", diffText, act)
+	}
+}
+
+func TestDiffModelSetsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := diffSchemas(t).WriteJSON(&buf); err != nil {
+		t.Fatalf("Unexpected WriteJSON error: %s", err)
+	}
+
+	var act []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &act); err != nil {
+		t.Fatalf("Unexpected JSON error: %s\n%s", err, buf.String())
+	}
+	if len(act) != 8 {
+		t.Fatalf("Expected 8 nodes, got %d\n%s", len(act), buf.String())
+	}
+	speed := act[4]
+	if speed["kind"] != "modified" || speed["path"] != "/diff:top/speed" ||
+		speed["node"] != "leaf" {
+		t.Errorf("Unexpected node: %v", speed)
+	}
+	changes, _ := speed["changes"].([]interface{})
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %v", speed["changes"])
+	}
+	def, _ := changes[1].(map[string]interface{})
+	if def["property"] != "default" || def["kind"] != "removed" ||
+		def["old"] != "10" || def["new"] != nil {
+		t.Errorf("Unexpected change: %v", def)
+	}
+}
+
+func TestDiffSchemasUnchanged(t *testing.T) {
+	ms := diagramSchema(t)
+	if diff := schema.DiffSchemas(ms, ms); len(diff) != 0 {
+		t.Errorf("Unexpected differences: %v", diff)
+	}
+
+	// A missing schema has no nodes
+	for _, diff := range []schema.SchemaDiff{
+		schema.DiffSchemas(nil, ms), schema.DiffSchemas(ms, nil),
+	} {
+		if len(diff) != len(schema.SchemaChildren(ms)) {
+			t.Errorf("Expected every top-level node, got %v", diff)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := schema.SchemaDiff(nil).WriteJSON(&buf); err != nil {
+		t.Fatalf("Unexpected WriteJSON error: %s", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("Unexpected JSON for no differences: %q", buf.String())
+	}
+}
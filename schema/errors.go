@@ -23,6 +23,8 @@
 package schema
 
 import (
+	"fmt"
+
 	"github.com/danos/mgmterror"
 	"github.com/danos/utils/pathutil"
 )
@@ -73,6 +75,13 @@ func NewMissingValueError(path []string) error {
 	return newInvalidValueError(path, msgMissingValue)
 }
 
+// NewInexactNumberError is returned for a number, such as one decoded from
+// JSON, that has no exact equivalent in its leaf's type.
+func NewInexactNumberError(path []string, number, typ string) error {
+	return newInvalidValueError(path,
+		fmt.Sprintf("%s cannot be represented exactly as %s", number, typ))
+}
+
 func NewEmptyLeafValueError(name string, path []string) error {
 	e := mgmterror.NewUnknownElementApplicationError(name)
 	e.Path = pathutil.Pathstr(path)
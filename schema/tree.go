@@ -187,6 +187,32 @@ func NewRpc(input, output Tree) Rpc {
 func (r *rpc) Input() Tree  { return r.input }
 func (r *rpc) Output() Tree { return r.output }
 
+// Action is a YANG 1.1 action: an operation tied to a container or list
+// node in the data tree, with the same input/output structure as an rpc.
+type Action interface {
+	Input() Tree
+	Output() Tree
+	isAction()
+}
+
+type action struct {
+	input  Tree
+	output Tree
+}
+
+// Ensure that other schema types don't meet the interface
+func (a *action) isAction() {}
+
+// Compile time check that the concrete type meets the interface
+var _ Action = (*action)(nil)
+
+func NewAction(input, output Tree) Action {
+	return &action{input, output}
+}
+
+func (a *action) Input() Tree  { return a.input }
+func (a *action) Output() Tree { return a.output }
+
 type Notification interface {
 	Schema() Tree
 	isNotification()
@@ -347,6 +373,8 @@ type Node interface {
 	Children() []Node
 	OpdChildren() []Node
 	Choices() []Node
+	Actions() map[string]Action
+	NestedNotifications() map[string]Notification
 	Name() string
 	Namespace() string
 	Module() string
@@ -364,6 +392,7 @@ type Node interface {
 	Config() bool
 	String() string
 	Status() Status
+	IfFeatures() []string
 	Description() string
 	Repeatable() bool
 	addParent(*node)
@@ -457,11 +486,14 @@ type node struct {
 	module       string
 	config       bool
 	status       Status
+	ifFeatures   []string
 	parent       *node
 	arguments    []string
 	whenContexts []WhenContext
 	mustContexts []MustContext
 	choices      []Node
+	actions      map[string]Action
+	notifs       map[string]Notification
 }
 
 func (n *node) String() string {
@@ -472,6 +504,26 @@ func (n *node) Status() Status {
 	return n.status
 }
 
+// IfFeatures returns the if-feature expressions of the node, as written.
+// Those of uses and augment statements are included.
+func (n *node) IfFeatures() []string {
+	return n.ifFeatures
+}
+
+// NodeOption sets an optional property of a node as it is made.
+type NodeOption func(*node)
+
+// WithIfFeatures gives the if-feature expressions of a node, as written.
+func WithIfFeatures(exprs []string) NodeOption {
+	return func(n *node) { n.ifFeatures = exprs }
+}
+
+func (n *node) apply(opts []NodeOption) {
+	for _, opt := range opts {
+		opt(n)
+	}
+}
+
 func makenode() *node {
 	n := &node{}
 	n.children = make(map[string]Node)
@@ -728,6 +780,17 @@ func (n *node) Choices() []Node {
 	return n.choices
 }
 
+func (n *node) Actions() map[string]Action {
+	return n.actions
+}
+
+// Notifications defined within this node, keyed by name. This is not
+// called Notifications() as that name is used by Model and ModelSet for
+// the top-level notifications of each module.
+func (n *node) NestedNotifications() map[string]Notification {
+	return n.notifs
+}
+
 func (n *node) Children() []Node {
 	return genChildList(n.children)
 }
@@ -935,7 +998,10 @@ func NewContainer(
 	status Status,
 	whens []WhenContext,
 	musts []MustContext,
+	actions map[string]Action,
+	notifications map[string]Notification,
 	children []Node,
+	opts ...NodeOption,
 ) (Container, error) {
 
 	c := &container{node: makenode()}
@@ -948,8 +1014,11 @@ func NewContainer(
 	c.presence = presence
 	c.config = config
 	c.status = status
+	c.apply(opts)
 	c.whenContexts = whens
 	c.mustContexts = musts
+	c.actions = actions
+	c.notifs = notifications
 
 	if err := c.addChildren(children); err != nil {
 		return nil, err
@@ -1033,7 +1102,10 @@ func NewList(
 	uniques [][][]xml.Name,
 	whens []WhenContext,
 	musts []MustContext,
+	actions map[string]Action,
+	notifications map[string]Notification,
 	children []Node,
+	opts ...NodeOption,
 ) (List, error) {
 
 	if orderedby == "" {
@@ -1049,12 +1121,15 @@ func NewList(
 	l.Ref = ref
 	l.config = config
 	l.status = status
+	l.apply(opts)
 	l.orderedBy = orderedby
 	l.limit = Limit{min, max}
 	l.keys = keys
 	l.uniques = uniques
 	l.whenContexts = whens
 	l.mustContexts = musts
+	l.actions = actions
+	l.notifs = notifications
 
 	l.entry = &listEntry{l.node, l}
 
@@ -1187,6 +1262,7 @@ func (n *listEntry) Validate(ctx ValidateCtx, path []string, p []string) error {
 type Leaf interface {
 	Node
 	Default() (string, bool)
+	Units() string
 	isLeaf()
 }
 
@@ -1213,6 +1289,7 @@ func NewLeaf(
 	status Status,
 	whens []WhenContext,
 	musts []MustContext,
+	opts ...NodeOption,
 ) Leaf {
 	l := &leaf{node: makenode()}
 	l.name.Local = name
@@ -1226,6 +1303,7 @@ func NewLeaf(
 	l.typ = typ
 	l.config = config
 	l.status = status
+	l.apply(opts)
 	l.whenContexts = whens
 	l.mustContexts = musts
 	return l
@@ -1245,6 +1323,10 @@ func (n *leaf) HasDefault() bool {
 func (n *leaf) Type() Type {
 	return n.typ
 }
+func (n *leaf) Units() string {
+	return n.units
+}
+
 func (n *leaf) Default() (string, bool) {
 	if n.Mandatory() {
 		return "", false
@@ -1327,14 +1409,14 @@ func (n *leafValue) Status() Status {
 			}
 		}
 		return Current
-	// TODO: bits and identity not supported yet
-	// case Bits:
-	// for _, b := range t.Bits() {
-	// 	if b.String() == n.name {
-	// 		return b.Status()
-	// 	}
-	// }
-	// 	return Current
+	case Bits:
+		for _, b := range t.Bits() {
+			if b.String() == n.name {
+				return b.Status()
+			}
+		}
+		return Current
+	// TODO: identity not supported yet
 	// case Identity:
 	// 	return Current
 	default:
@@ -1778,12 +1860,14 @@ func (n *opdOptionValue) HasPresence() bool {
 type LeafList interface {
 	Node
 	Limit() Limit
+	Defaults() []string
+	Units() string
 	isLeafList()
 }
 
 type leafList struct {
 	*node
-	def       string
+	defs      []string
 	units     string
 	limit     Limit
 	orderedBy string
@@ -1794,13 +1878,15 @@ func (l *leafList) isLeafList() {}
 
 func NewLeafList(
 	name, namespace, modulename, submodule,
-	desc, ref, def, orderedby, units string,
+	desc, ref, orderedby, units string,
+	defs []string,
 	min, max uint,
 	typ Type,
 	config bool,
 	status Status,
 	whens []WhenContext,
 	musts []MustContext,
+	opts ...NodeOption,
 ) LeafList {
 	if orderedby == "" {
 		orderedby = "system"
@@ -1813,7 +1899,7 @@ func NewLeafList(
 	l.submodule = submodule
 	l.Desc = desc
 	l.Ref = ref
-	l.def = def
+	l.defs = defs
 	l.orderedBy = orderedby
 	l.units = units
 	l.limit.Min = min
@@ -1821,6 +1907,7 @@ func NewLeafList(
 	l.typ = typ
 	l.config = config
 	l.status = status
+	l.apply(opts)
 	l.whenContexts = whens
 	l.mustContexts = musts
 	return l
@@ -1828,24 +1915,36 @@ func NewLeafList(
 
 func (l *leafList) Limit() Limit { return l.limit }
 
+// Defaults returns the default values in the order they were defined
+func (l *leafList) Defaults() []string { return l.defs }
+
 func (n *leafList) Descendant(path []string) Node {
 	return n.descendant(n, path)
 }
 
+func (n *leafList) Units() string {
+	return n.units
+}
+
 func (n *leafList) OrdBy() string {
 	return n.orderedBy
 }
 
 func (n *leafList) DefaultChildNames() []string {
-	return nil
+	return n.defs
 }
 
 func (n *leafList) DefaultChild(name string) Node {
+	for _, def := range n.defs {
+		if def == name {
+			return &leafValue{Node: n, name: name}
+		}
+	}
 	return nil
 }
 
 func (n *leafList) HasDefault() bool {
-	return false
+	return len(n.defs) > 0
 }
 func (n *leafList) Type() Type {
 	return n.typ
@@ -1873,6 +1972,117 @@ func (n *leafList) Child(name string) Node {
 	return &leafValue{Node: n, name: name}
 }
 
+// Anydata represents an 'anydata' node; its content is an unknown
+// set of nodes which is carried as an opaque payload and not validated
+// against the schema.
+type Anydata interface {
+	Node
+	isAnydata()
+}
+
+type anydata struct {
+	*node
+	mandatory bool
+}
+
+// Ensure that other schema types don't meet the interface
+func (*anydata) isAnydata() {}
+
+// Compile time check that the concrete type meets the interface
+var _ Anydata = (*anydata)(nil)
+
+func NewAnydata(
+	name, namespace, modulename, submodule, desc, ref string,
+	mandatory, config bool,
+	status Status,
+	whens []WhenContext,
+	musts []MustContext,
+	opts ...NodeOption,
+) Anydata {
+	a := &anydata{node: makenode()}
+	a.name.Local = name
+	a.name.Space = namespace
+	a.module = modulename
+	a.submodule = submodule
+	a.Desc = desc
+	a.Ref = ref
+	a.mandatory = mandatory
+	a.config = config
+	a.status = status
+	a.apply(opts)
+	a.whenContexts = whens
+	a.mustContexts = musts
+	return a
+}
+
+func (n *anydata) Mandatory() bool { return n.mandatory }
+
+func (n *anydata) Child(name string) Node { return nil }
+
+func (n *anydata) Descendant(path []string) Node {
+	return n.descendant(n, path)
+}
+
+// Content is opaque, so any path below the node is acceptable
+func (n *anydata) Validate(ctx ValidateCtx, path []string, p []string) error {
+	return nil
+}
+
+// Anyxml represents an 'anyxml' node; like anydata its content is
+// carried as an opaque payload and not validated against the schema.
+type Anyxml interface {
+	Node
+	isAnyxml()
+}
+
+type anyxml struct {
+	*node
+	mandatory bool
+}
+
+// Ensure that other schema types don't meet the interface
+func (*anyxml) isAnyxml() {}
+
+// Compile time check that the concrete type meets the interface
+var _ Anyxml = (*anyxml)(nil)
+
+func NewAnyxml(
+	name, namespace, modulename, submodule, desc, ref string,
+	mandatory, config bool,
+	status Status,
+	whens []WhenContext,
+	musts []MustContext,
+	opts ...NodeOption,
+) Anyxml {
+	a := &anyxml{node: makenode()}
+	a.name.Local = name
+	a.name.Space = namespace
+	a.module = modulename
+	a.submodule = submodule
+	a.Desc = desc
+	a.Ref = ref
+	a.mandatory = mandatory
+	a.config = config
+	a.status = status
+	a.apply(opts)
+	a.whenContexts = whens
+	a.mustContexts = musts
+	return a
+}
+
+func (n *anyxml) Mandatory() bool { return n.mandatory }
+
+func (n *anyxml) Child(name string) Node { return nil }
+
+func (n *anyxml) Descendant(path []string) Node {
+	return n.descendant(n, path)
+}
+
+// Content is opaque, so any path below the node is acceptable
+func (n *anyxml) Validate(ctx ValidateCtx, path []string, p []string) error {
+	return nil
+}
+
 type Choice interface {
 	Node
 	DefaultCase() string
@@ -1897,6 +2107,7 @@ func NewChoice(
 	status Status,
 	whens []WhenContext,
 	children []Node,
+	opts ...NodeOption,
 ) (Choice, error) {
 	c := &choice{node: makenode()}
 	c.name.Local = name
@@ -1909,6 +2120,7 @@ func NewChoice(
 	c.mandatory = mandatory
 	c.config = config
 	c.status = status
+	c.apply(opts)
 	c.whenContexts = whens
 
 	if err := c.addChildrenWithActionChain(children,
@@ -1978,6 +2190,7 @@ func NewCase(
 	status Status,
 	whens []WhenContext,
 	children []Node,
+	opts ...NodeOption,
 ) (Case, error) {
 	c := &ycase{node: makenode()}
 	c.name.Local = name
@@ -1988,6 +2201,7 @@ func NewCase(
 	c.Ref = ref
 	c.config = config
 	c.status = status
+	c.apply(opts)
 	c.whenContexts = whens
 
 	if err := c.addChildrenWithActionChain(children,
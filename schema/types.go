@@ -443,6 +443,14 @@ func (e *enumeration) Validate(ctx ValidateCtx, path []string, s string) error {
 	return newInvalidValueError(path, genErrorString(e))
 }
 
+// The allowed values are the enums enabled by the features in use.
+func (e *enumeration) AllowedValues(
+	ctxNode xutils.XpathNode,
+	debug bool,
+) ([]string, error) {
+	return e.errors(), nil
+}
+
 func (e *enumeration) errors() []string {
 	out := make([]string, 0, len(e.enums))
 	for _, enum := range e.enums {
@@ -901,6 +909,13 @@ func (i *identityref) Validate(ctx ValidateCtx, path []string, s string) error {
 	return newInvalidValueError(path, genErrorString(i))
 }
 
+func (i *identityref) AllowedValues(
+	ctxNode xutils.XpathNode,
+	debug bool,
+) ([]string, error) {
+	return i.errors(), nil
+}
+
 func (i *identityref) errors() []string {
 	out := make([]string, 0, len(i.identities))
 	for _, id := range i.identities {
@@ -1024,7 +1039,7 @@ type Bit struct {
 	Desc   string
 	Ref    string
 	status Status
-	Pos    int32
+	Pos    uint32
 }
 
 func (b *Bit) String() string {
@@ -1035,7 +1050,7 @@ func (b *Bit) Status() Status {
 	return b.status
 }
 
-func NewBit(name, desc, ref string, status Status, pos int32) *Bit {
+func NewBit(name, desc, ref string, status Status, pos uint32) *Bit {
 	return &Bit{
 		Name:   name,
 		Desc:   desc,
@@ -1062,10 +1077,43 @@ func (*bits) isBits() {}
 // Compile time check that the concrete type meets the interface
 var _ Bits = (*bits)(nil)
 
+// A bits value is a space separated list of the names of the bits set.
 func (b *bits) Validate(ctx ValidateCtx, path []string, s string) error {
+	for _, name := range strings.Fields(s) {
+		if !b.hasBit(name) {
+			return newInvalidValueError(path, genErrorString(b))
+		}
+	}
 	return nil
 }
 
+func (b *bits) hasBit(name string) bool {
+	for _, bit := range b.Bs {
+		if bit.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *bits) AllowedValues(
+	ctxNode xutils.XpathNode,
+	debug bool,
+) ([]string, error) {
+	return b.errors(), nil
+}
+
+func (b *bits) errors() []string {
+	out := make([]string, 0, len(b.Bs))
+	for _, bit := range b.Bs {
+		if bit.Status() == Obsolete {
+			continue
+		}
+		out = append(out, bit.Name)
+	}
+	return out
+}
+
 func (b *bits) Bits() []*Bit {
 	return b.Bs
 }
@@ -1091,6 +1139,9 @@ type Pattern struct {
 	*regexp.Regexp
 	Msg    string
 	AppTag string
+	// Set by 'modifier invert-match'; values matching the pattern
+	// are then invalid, and all others valid.
+	InvertMatch bool
 }
 
 func (p Pattern) String() string {
@@ -1106,7 +1157,7 @@ func (p Pattern) shortString() string {
 }
 
 func (p Pattern) Validate(s string) error {
-	if p.MatchString(s) {
+	if p.MatchString(s) != p.InvertMatch {
 		return nil
 	}
 	merr := mgmterror.NewInvalidValueApplicationError()
@@ -1127,6 +1178,9 @@ func (p Pattern) Validate(s string) error {
 
 func (p Pattern) message() string {
 	if p.Msg == "" {
+		if p.InvertMatch {
+			return "Matches inverted pattern " + p.String()
+		}
 		return "Does not match pattern " + p.shortString()
 	}
 	return p.Msg
@@ -1526,10 +1580,17 @@ type Identity struct {
 	Value     string
 	Module    string
 	Namespace string
+	bases     []string
+}
+
+// bases are the identities this identity is derived from directly, each
+// as <module>:<identity>. YANG 1.1 allows an identity to have several.
+func NewIdentity(mod, namespace, val, desc, ref string, status Status, value string, bases []string) *Identity {
+	return &Identity{Module: mod, Namespace: namespace, Val: val, Desc: desc, Ref: ref, status: status, Value: value, bases: bases}
 }
 
-func NewIdentity(mod, namespace, val, desc, ref string, status Status, value string) *Identity {
-	return &Identity{Module: mod, Namespace: namespace, Val: val, Desc: desc, Ref: ref, status: status, Value: value}
+func (i *Identity) Bases() []string {
+	return i.bases
 }
 
 func (i *Identity) String() string {
@@ -457,6 +457,10 @@ func hasMandatoryChildren(sn Node, path []string, errs []error) []error {
 			if !v.Presence() {
 				errs = hasMandatoryChildren(csn, path, errs)
 			}
+		case Anydata, Anyxml:
+			if v.Mandatory() {
+				errs = appendMandatoryError(path, v.Name(), errs)
+			}
 		}
 	}
 	for _, cd := range sn.Choices() {
@@ -492,6 +496,8 @@ func checkMandatory(c xnode, valType ValidationType,
 			isMand = v.Limit().Min > 0
 		case Container:
 			isMand = !v.Presence()
+		case Anydata, Anyxml:
+			isMand = v.Mandatory()
 		}
 
 		if isMand {
@@ -578,6 +584,10 @@ func hasCaseMandatoryChildren(cfg map[string]xnode, nd Node, path []string, errs
 			if !v.Presence() {
 				errs = hasMandatoryChildren(csn, path, errs)
 			}
+		case Anydata, Anyxml:
+			if v.Mandatory() {
+				errs = appendMandatoryError(path, v.Name(), errs)
+			}
 		}
 	}
 
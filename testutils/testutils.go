@@ -137,6 +137,27 @@ func GetConfigSchemaSkipUnknown(buf ...[]byte) (schema.ModelSet, error) {
 	return getSchema(false, true, buf...)
 }
 
+// Compile config schema with the given features enabled or disabled.
+func GetConfigSchemaWithFeatures(
+	features compile.FeaturesChecker,
+	bufs ...[]byte,
+) (schema.ModelSet, error) {
+
+	const name = "schema"
+	modules := make(map[string]*parse.Tree)
+	for index, b := range bufs {
+		t, err := parse.Parse(name+strconv.Itoa(index), string(b),
+			nilExtCardinality)
+		if err != nil {
+			return nil, err
+		}
+		modules[t.Root.Argument().String()] = t
+	}
+	return compile.CompileParseTrees(
+		nil, modules, features, false,
+		compile.Include(compile.IsConfig, compile.IncludeState(false)))
+}
+
 func GetFullSchema(buf ...[]byte) (schema.ModelSet, error) {
 	return getSchema(true, false, buf...)
 }
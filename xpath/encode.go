@@ -0,0 +1,237 @@
+// Copyright 2024 Nokia
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file implements encoding of machines. Instructions are closures so
+// can't be encoded themselves. Instead each records the ProgBuilder call
+// that coded it, and decoding makes the same calls on a new ProgBuilder,
+// without parsing the expression again.
+
+package xpath
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/xml"
+	"fmt"
+)
+
+type opCode uint8
+
+const (
+	opNone opCode = iota // Not encodable
+	opFn
+	opNum
+	opBool
+	opNotFound
+	opLiteral
+	opPathSetCurrent
+	opText
+	opCount
+	opDeref
+	opPredicatesStart
+	opPredicatesEnd
+	opPathOper
+	opNameTest
+	opBltin
+	opEvalLocPathExists
+	opPredStart
+	opPredEnd
+)
+
+// instOp is a ProgBuilder call, with its arguments. Fields are exported
+// for encoding/gob.
+type instOp struct {
+	Code  opCode
+	Name  string // Function, literal, or local name for a name test
+	Space string // Namespace for a name test
+	Num   float64
+	Arg   int  // Path operator or number of function arguments
+	Bool  bool // For a function, whether it is a dummy custom function
+}
+
+type encodedMachine struct {
+	Expr     string
+	Location string
+	Name     string
+	Ops      []instOp
+}
+
+// The ProgBuilder methods the grammars pass to CodeFn, by the name they
+// are given.
+func (progBldr *ProgBuilder) namedFn(name string) (instFunc, bool) {
+	switch name {
+	case "store":
+		return progBldr.Store, true
+	case "storePathEval":
+		return progBldr.StorePathEval, true
+	case "evalLocPath", "lrefPredStart":
+		return progBldr.EvalLocPath, true
+	case "filterExprEnd":
+		return progBldr.FilterExprEnd, true
+	case "lrefEquals":
+		return progBldr.LRefEquals, true
+	case "lrefPredEnd":
+		return progBldr.LRefPredEnd, true
+	case "or":
+		return progBldr.Or, true
+	case "and":
+		return progBldr.And, true
+	case "eq":
+		return progBldr.Eq, true
+	case "ne":
+		return progBldr.Ne, true
+	case "lt":
+		return progBldr.Lt, true
+	case "gt":
+		return progBldr.Gt, true
+	case "le":
+		return progBldr.Le, true
+	case "ge":
+		return progBldr.Ge, true
+	case "add":
+		return progBldr.Add, true
+	case "sub":
+		return progBldr.Sub, true
+	case "mul":
+		return progBldr.Mul, true
+	case "div":
+		return progBldr.Div, true
+	case "mod":
+		return progBldr.Mod, true
+	case "negate":
+		return progBldr.Negate, true
+	case "union":
+		return progBldr.Union, true
+	}
+	return nil, false
+}
+
+func (op *instOp) encodable() bool {
+	switch op.Code {
+	case opNone:
+		return false
+	case opFn:
+		_, ok := (*ProgBuilder)(nil).namedFn(op.Name)
+		return ok
+	}
+	return true
+}
+
+func (progBldr *ProgBuilder) replay(op instOp) error {
+	switch op.Code {
+	case opFn:
+		fn, ok := progBldr.namedFn(op.Name)
+		if !ok {
+			return fmt.Errorf("unknown instruction %s", op.Name)
+		}
+		progBldr.CodeFn(fn, op.Name)
+	case opNum:
+		progBldr.CodeNum(op.Num)
+	case opBool:
+		progBldr.PushBool(op.Bool)
+	case opNotFound:
+		progBldr.PushNotFound()
+	case opLiteral:
+		progBldr.CodeLiteral(op.Name)
+	case opPathSetCurrent:
+		progBldr.CodePathSetCurrent()
+	case opText:
+		progBldr.Text()
+	case opCount:
+		progBldr.Count()
+	case opDeref:
+		progBldr.Deref()
+	case opPredicatesStart:
+		progBldr.PredicatesStart()
+	case opPredicatesEnd:
+		progBldr.PredicatesEnd()
+	case opPathOper:
+		progBldr.CodePathOper(op.Arg)
+	case opNameTest:
+		progBldr.CodeNameTest(xml.Name{Space: op.Space, Local: op.Name})
+	case opBltin:
+		sym := NewDummyFnSym(op.Name)
+		if !op.Bool {
+			var ok bool
+			sym, ok = LookupXpathFunction(op.Name, true, nil)
+			if !ok {
+				return fmt.Errorf("unknown function %s()", op.Name)
+			}
+		}
+		progBldr.CodeBltin(sym, op.Arg)
+	case opEvalLocPathExists:
+		progBldr.CodeEvalLocPathExists()
+	case opPredStart:
+		progBldr.CodePredStart()
+	case opPredEnd:
+		progBldr.CodePredEnd()
+	default:
+		return fmt.Errorf("unknown instruction %s", op.Name)
+	}
+	return nil
+}
+
+// MarshalBinary encodes the machine, for instance to cache a compiled
+// schema. Custom functions must be registered again before decoding.
+func (mach *Machine) MarshalBinary() ([]byte, error) {
+	enc := encodedMachine{
+		Expr:     mach.refExpr,
+		Location: mach.location,
+		Name:     mach.name,
+		Ops:      make([]instOp, 0, len(mach.prog)),
+	}
+	for _, inst := range mach.prog {
+		if inst.op == nil {
+			continue
+		}
+		if !inst.op.encodable() {
+			return nil, fmt.Errorf(
+				"machine for '%s': instruction %s cannot be encoded",
+				mach.refExpr, inst.fnName)
+		}
+		enc.Ops = append(enc.Ops, *inst.op)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&enc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (mach *Machine) UnmarshalBinary(data []byte) error {
+	var enc encodedMachine
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&enc); err != nil {
+		return err
+	}
+
+	progBldr := NewProgBuilder(enc.Expr)
+	for _, op := range enc.Ops {
+		if err := progBldr.replay(op); err != nil {
+			return fmt.Errorf("machine for '%s': %s", enc.Expr, err)
+		}
+	}
+	prog, err := progBldr.GetMainProg()
+	if err != nil {
+		return err
+	}
+
+	*mach = Machine{
+		refExpr:  enc.Expr,
+		location: enc.Location,
+		name:     enc.Name,
+		prog:     prog,
+	}
+	return nil
+}
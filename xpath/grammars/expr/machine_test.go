@@ -30,6 +30,8 @@ package expr
 
 import (
 	"testing"
+
+	"github.com/sdcio/yang-parser/xpath"
 )
 
 // Check all valid options in a machine are printed correctly.
@@ -57,3 +59,37 @@ func TestMachinePrint(t *testing.T) {
 			expectedString, machineString)
 	}
 }
+
+// Check that an encoded machine decodes to the same program.
+func TestMachineEncoding(t *testing.T) {
+	for _, expr := range []string{
+		"10 + number(substring('1234', 1, 2))",
+		"count(../interface[name = current()/../name]) > 1 or not(boolean(.))",
+		"../a/b[c = 'x']/d != ../e",
+		"-1 * 2 div 3 mod 4 - 5 <= 6 and 7 >= 8 or 9 < 10 and 11 = 12",
+		"deref(../ref)/../value | /top/items",
+	} {
+		mach, err := NewExprMachine(expr, nil)
+		if err != nil {
+			t.Errorf("Unable to compile '%s': %s", expr, err)
+			continue
+		}
+		data, err := mach.MarshalBinary()
+		if err != nil {
+			t.Errorf("Unable to encode '%s': %s", expr, err)
+			continue
+		}
+		var decoded xpath.Machine
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Errorf("Unable to decode '%s': %s", expr, err)
+			continue
+		}
+		if decoded.GetExpr() != expr {
+			t.Errorf("Expected expression %s, got %s", expr, decoded.GetExpr())
+		}
+		if decoded.PrintMachine() != mach.PrintMachine() {
+			t.Errorf("Expected:\n%s\n---\nGot:\n%s\n---\n",
+				mach.PrintMachine(), decoded.PrintMachine())
+		}
+	}
+}
@@ -34,6 +34,7 @@ type Inst struct {
 	fnName     string // for debug mostly
 	subMachine string // debug string for sub-machine, if present.
 	count      int
+	op         *instOp // ProgBuilder call that coded this instruction
 }
 
 func newInst(fn instFunc, fnName string) Inst {
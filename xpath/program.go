@@ -161,7 +161,15 @@ func (progBldr *ProgBuilder) UnsupportedName(tokenType int, token string) {
 // inserting operations and operands for the operations into a slice.
 
 func (progBldr *ProgBuilder) CodeFn(fn instFunc, fnName string) {
+	progBldr.codeOp(&instOp{Code: opFn, Name: fnName}, fn, fnName)
+}
+
+// codeOp codes an instruction, recording the call that coded it so that
+// an encoded machine can be built again. A call coding more than one
+// instruction records itself on the first, with nil for the others.
+func (progBldr *ProgBuilder) codeOp(op *instOp, fn instFunc, fnName string) {
 	newInstr := newInst(fn, fnName)
+	newInstr.op = op
 	progBldr.progs.Update(newInstr)
 }
 
@@ -170,6 +178,7 @@ func (progBldr *ProgBuilder) CodeSubMachine(
 	fnName, subMachine string,
 ) {
 	newInstr := newInstWithSubMachine(fn, fnName, subMachine)
+	newInstr.op = &instOp{Code: opNone, Name: fnName}
 	progBldr.progs.Update(newInstr)
 }
 
@@ -177,14 +186,16 @@ func (progBldr *ProgBuilder) CodeNum(num float64) {
 	numpush := func(ctx *context) {
 		ctx.pushDatum(NewNumDatum(num))
 	}
-	progBldr.CodeFn(numpush, fmt.Sprintf("numpush\t\t%v", num))
+	progBldr.codeOp(&instOp{Code: opNum, Num: num},
+		numpush, fmt.Sprintf("numpush\t\t%v", num))
 }
 
 func (progBldr *ProgBuilder) PushBool(b bool) {
 	numpush := func(ctx *context) {
 		ctx.pushDatum(NewBoolDatum(b))
 	}
-	progBldr.CodeFn(numpush, fmt.Sprintf("boolpush\t\t%v", b))
+	progBldr.codeOp(&instOp{Code: opBool, Bool: b},
+		numpush, fmt.Sprintf("boolpush\t\t%v", b))
 }
 
 func (progBldr *ProgBuilder) PushNotFound() {
@@ -193,14 +204,16 @@ func (progBldr *ProgBuilder) PushNotFound() {
 		// ctx.pushDatum(NewLiteralDatum("BTnkTEI1y8iFq01rk837"))
 		ctx.pushDatum(NewNodesetDatum([]xutils.XpathNode{}))
 	}
-	progBldr.CodeFn(nsetPush, fmt.Sprintf("nodesetpush\t\t[]"))
+	progBldr.codeOp(&instOp{Code: opNotFound},
+		nsetPush, fmt.Sprintf("nodesetpush\t\t[]"))
 }
 
 func (progBldr *ProgBuilder) CodeLiteral(lit string) {
 	litpush := func(ctx *context) {
 		ctx.pushDatum(NewLiteralDatum(lit))
 	}
-	progBldr.CodeFn(litpush, fmt.Sprintf("litpush\t\t'%s'", lit))
+	progBldr.codeOp(&instOp{Code: opLiteral, Name: lit},
+		litpush, fmt.Sprintf("litpush\t\t'%s'", lit))
 }
 
 func (progBldr *ProgBuilder) CodePathSetCurrent() {
@@ -208,7 +221,7 @@ func (progBldr *ProgBuilder) CodePathSetCurrent() {
 		_ = ctx.actualPathStack.PopPath()
 		ctx.actualPathStack.NewPathFromCurrent()
 	}
-	progBldr.CodeFn(pathSetCurrent,
+	progBldr.codeOp(&instOp{Code: opPathSetCurrent}, pathSetCurrent,
 		fmt.Sprintf("pathsetcurrent"),
 	)
 }
@@ -224,8 +237,9 @@ func (progBldr *ProgBuilder) Text() {
 		ctx.pushDatum(val)
 	}
 
-	progBldr.CodeFn(progBldr.EvalLocPathInternal, "EvalLocPathInternal - text()")
-	progBldr.CodeFn(textFunc, fmt.Sprintf("text()"))
+	progBldr.codeOp(&instOp{Code: opText},
+		progBldr.EvalLocPathInternal, "EvalLocPathInternal - text()")
+	progBldr.codeOp(nil, textFunc, fmt.Sprintf("text()"))
 
 }
 
@@ -243,7 +257,7 @@ func (progBldr *ProgBuilder) Count() {
 		ctx.stack = append(ctx.stack, NewNumDatum(float64(len(entries))))
 	}
 
-	progBldr.CodeFn(countFunc, fmt.Sprintf("count"))
+	progBldr.codeOp(&instOp{Code: opCount}, countFunc, fmt.Sprintf("count"))
 }
 
 func (progBldr *ProgBuilder) Deref() {
@@ -264,7 +278,7 @@ func (progBldr *ProgBuilder) Deref() {
 		ctx.actualPathStack.PushPath(lrefentry.GetSdcpbPath())
 	}
 
-	progBldr.CodeFn(derefFunc, "deref")
+	progBldr.codeOp(&instOp{Code: opDeref}, derefFunc, "deref")
 
 }
 
@@ -272,7 +286,7 @@ func (progBldr *ProgBuilder) PredicatesStart() {
 	pstarts := func(ctx *context) {
 		ctx.predicatePathElemStack.AddEmptyMap()
 	}
-	progBldr.CodeFn(pstarts, "PredicatesStart")
+	progBldr.codeOp(&instOp{Code: opPredicatesStart}, pstarts, "PredicatesStart")
 }
 
 func (progBldr *ProgBuilder) PredicatesEnd() {
@@ -295,7 +309,7 @@ func (progBldr *ProgBuilder) PredicatesEnd() {
 		}
 	}
 
-	progBldr.CodeFn(pends, "PredicatesEnd")
+	progBldr.codeOp(&instOp{Code: opPredicatesEnd}, pends, "PredicatesEnd")
 }
 
 func (progBldr *ProgBuilder) CodePathOper(elem int) {
@@ -324,7 +338,8 @@ func (progBldr *ProgBuilder) CodePathOper(elem int) {
 	}
 
 	if pathOperPush != nil {
-		progBldr.CodeFn(pathOperPush,
+		progBldr.codeOp(&instOp{Code: opPathOper, Arg: elem},
+			pathOperPush,
 			fmt.Sprintf("PathOper-Push\t%s", xutils.GetTokenName(elem)))
 		return
 	}
@@ -341,8 +356,8 @@ func (progBldr *ProgBuilder) CodeNameTest(name xml.Name) {
 			//fmt.Println(utils.ToXPath(ctx.GetActualPath(),false))
 		}
 	}
-	progBldr.CodeFn(nameTestPush,
-		fmt.Sprintf("Name-Push\t%s", name))
+	progBldr.codeOp(&instOp{Code: opNameTest, Name: name.Local, Space: name.Space},
+		nameTestPush, fmt.Sprintf("Name-Push\t%s", name))
 }
 
 func (progBldr *ProgBuilder) CodeBltin(sym *Symbol, numArgs int) {
@@ -382,14 +397,18 @@ func (progBldr *ProgBuilder) CodeBltin(sym *Symbol, numArgs int) {
 	} else {
 		fnType = "bltin"
 	}
-	progBldr.CodeFn(bltinOrCustom, fmt.Sprintf("%s\t\t%s()", fnType, sym.name))
+	progBldr.codeOp(
+		&instOp{Code: opBltin, Name: sym.name, Arg: numArgs,
+			Bool: sym.custom && sym.customFunc == nil},
+		bltinOrCustom, fmt.Sprintf("%s\t\t%s()", fnType, sym.name))
 }
 
 func (progBldr *ProgBuilder) CodeEvalLocPathExists() {
 	if progBldr.ignoreInsidePred > 0 {
 		return
 	}
-	progBldr.CodeFn(progBldr.EvalLocPathExists, "locPathExists")
+	progBldr.codeOp(&instOp{Code: opEvalLocPathExists},
+		progBldr.EvalLocPathExists, "locPathExists")
 }
 
 // Code:
@@ -410,7 +429,7 @@ func (progBldr *ProgBuilder) CodePredStart() {
 		ctx.previousPredicateRequiresELP = false
 	}
 
-	progBldr.CodeFn(instFn, "PREDSTART")
+	progBldr.codeOp(&instOp{Code: opPredStart}, instFn, "PREDSTART")
 
 	//progBldr.CodeFn(progBldr.NewPathStackFromActual(), "PREDSTART - NewPathStackFromActual")
 	// progBldr.CodeFn(progBldr.Store, "PREDSTART")
@@ -483,7 +502,7 @@ func (progBldr *ProgBuilder) CodePredEnd() {
 		ctx.actualPathStack.PopPath()
 	}
 
-	progBldr.CodeFn(cFn, "PREDEND")
+	progBldr.codeOp(&instOp{Code: opPredEnd}, cFn, "PREDEND")
 	// prog := progBldr.progs.Pop()
 	// preds := progBldr.preds
 
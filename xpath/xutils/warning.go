@@ -107,6 +107,11 @@ func (w Warning) GetType() WarnType {
 	return w.warnTyp
 }
 
+func (w Warning) GetStartNode() string { return w.startNode }
+func (w Warning) GetXpathStmt() string { return w.xpathStmt }
+func (w Warning) GetXpathLoc() string  { return w.xpathLoc }
+func (w Warning) GetTestPath() string  { return w.testPath }
+
 func (w Warning) String() string {
 	return fmt.Sprintf(
 		"Node:\t\t%s\n"+